@@ -2,15 +2,28 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pepabo/difync/internal/api"
+	"github.com/pepabo/difync/internal/color"
+	"github.com/pepabo/difync/internal/progress"
 	"github.com/pepabo/difync/internal/syncer"
 )
 
@@ -23,6 +36,28 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return value
 }
 
+// Build information, injected at build time via -ldflags
+var (
+	version = "dev"
+	commit  = "dev"
+	date    = "dev"
+)
+
+// expandPath expands environment variables and a leading ~ to the user's home directory
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	return path, nil
+}
+
 // Command-line flags
 var (
 	difyBaseURL = flag.String("base-url", "", "Dify API base URL (overrides env: DIFY_BASE_URL)")
@@ -30,8 +65,116 @@ var (
 	appMapFile  = flag.String("app-map", "", "Path to app mapping file (overrides env: APP_MAP_FILE, default: app_map.json)")
 	dryRun      = flag.Bool("dry-run", false, "Perform a dry run without making any changes")
 	verbose     = flag.Bool("verbose", false, "Enable verbose output")
+	trace       = flag.Bool("trace", false, "Log every API request and response to stderr, with credentials redacted")
+	summaryOnly = flag.Bool("summary-only", false, "Print only the sync summary, without a line per changed app")
+	quiet       = flag.Bool("quiet", false, "Suppress all output except errors")
+	colorMode   = flag.String("color", "auto", "Colorize output: auto, always or never (auto respects NO_COLOR)")
+
+	passwordFile  = flag.String("password-file", "", "Read the Dify password from this file (overrides env: DIFY_PASSWORD)")
+	passwordStdin = flag.Bool("password-stdin", false, "Read the Dify password from stdin (overrides env: DIFY_PASSWORD)")
+
+	deleteOrphans  = flag.Bool("delete-orphans", false, "Delete local files for apps that no longer exist in Dify")
+	archiveDeleted = flag.String("archive-deleted", "", "Move local files for apps that no longer exist in Dify into this directory")
+	dedupe         = flag.Bool("dedupe", false, "Keep the first of duplicate app map entries instead of failing")
+	pruneMap       = flag.Bool("prune-map", false, "Remove apps that no longer exist in Dify from the app map")
+	noAppMap       = flag.Bool("no-app-map", false, "Sync every app in the workspace under its app name without reading or writing an app map")
+	noLock         = flag.Bool("no-lock", false, "Do not lock the app map against concurrent difync runs")
+	reinit         = flag.Bool("reinit", false, "Rebuild the app map from scratch on init instead of updating it")
+	onlyNew        = flag.Bool("only-new", true, "Only download DSL files that do not exist locally on init")
+	overwrite      = flag.Bool("overwrite", false, "Download every DSL file on init, replacing existing local files (same as --only-new=false)")
+	rateLimit      = flag.Float64("rate-limit", 0, "Maximum API requests per second (0 disables limiting)")
+	maxBandwidth   = flag.Int64("max-bandwidth", 0, "Maximum download rate of DSL files in bytes per second (0 disables throttling)")
+	maxIdleConns   = flag.Int("max-idle-conns", api.DefaultMaxIdleConnsPerHost, "Idle connections to Dify kept open for reuse")
+	idleTimeout    = flag.Duration("idle-conn-timeout", api.DefaultIdleConnTimeout, "How long an idle connection to Dify is kept open")
+	connectTimeout = flag.Duration("connect-timeout", api.DefaultConnectTimeout, "Maximum time to establish a connection to Dify or the proxy")
+	tlsTimeout     = flag.Duration("tls-handshake-timeout", api.DefaultTLSHandshakeTimeout, "Maximum time for the TLS handshake with Dify")
+	dslExtension   = flag.String("dsl-extension", ".yaml", "File extension for new DSL files (e.g. .yaml, .yml, .json)")
+	apiPrefix      = flag.String("api-prefix", "", "Path prefix for a Dify mounted under a subpath (e.g. /dify)")
+	extraHeaders   = headerFlagVar("header", "Add a \"Key: Value\" header to every API request (repeatable)")
+	userAgent      = flag.String("user-agent", "", "User-Agent header of API requests (default: difync/<version>)")
+	basicAuth      = flag.String("basic-auth", "", "HTTP basic auth credentials \"user:pass\" for an API gateway in front of Dify")
+	exportPath     = flag.String("export-path", "", "DSL export endpoint relative to /console/api/apps/{id} (default: /export?include_secret=false, older Dify: /dsl)")
+	errorDumpDir   = flag.String("dump-request-on-error", "", "Write the full request and response of every API error to a timestamped file in this directory")
+	auditLog       = flag.String("audit-log", "", "Append a JSON Lines record of every sync action to this file")
+	verifyWrites   = flag.Bool("verify-writes", false, "Re-read downloaded files to detect truncated writes")
+	skipUnchanged  = flag.Bool("skip-unchanged-content", false, "Leave local files untouched when the downloaded DSL is identical")
+	ignoreKeys     = flag.String("ignore-keys", "", "Comma-separated YAML keys ignored by --skip-unchanged-content (e.g. export_time,app.id)")
+	touchOnSync    = flag.Bool("touch-on-sync", false, "Set the modification time of files in sync to the remote update time")
+	redownload     = flag.Bool("redownload-missing", true, "Download the DSL of app map entries whose local file was deleted")
+	skipForbidden  = flag.Bool("skip-forbidden", false, "Count apps that may not be exported (403) as skipped instead of failed")
+	failFast       = flag.Bool("fail-fast", false, "Stop syncing at the first app that fails")
+	variant        = flag.String("variant", syncer.VariantDraft, "DSL variant to download: draft or published")
+	followSymlinks = flag.Bool("follow-symlinks", false, "Write downloaded DSLs through to the target of symlinked DSL files instead of failing")
+	dslPermissions = flag.String("dsl-permissions", "0644", "Octal permissions of written DSL files (e.g. 0600 for DSLs with secrets)")
+	lineEnding     = flag.String("line-ending", syncer.LineEndingPreserve, "Line endings of downloaded DSL files: lf, crlf or preserve")
+	clockSkew      = flag.Duration("clock-skew-tolerance", 2*time.Second, "Treat remote changes within this window of the local file time as in sync")
+	maxApps        = flag.Int("max-apps", 0, "Abort init if more than this many apps are found (0 means unlimited)")
+	concurrency    = flag.Int("concurrency", 1, "Number of DSL files init downloads at once")
+	reportFile     = flag.String("report", "", "Write a Markdown (or HTML, for .html paths) sync report to this file")
+	metricsFile    = flag.String("metrics-file", "", "Write Prometheus textfile collector metrics to this file after each sync")
+	manifestFile   = flag.String("manifest", "", "Write a JSON manifest with SHA-256 checksums of the synced files to this path")
+	sinceLastRun   = flag.Bool("since-last-run", false, "Only sync apps updated in Dify since the last sync without errors")
+	resume         = flag.Bool("resume", false, "Skip apps completed by an earlier sync that did not finish cleanly")
+	stateFile      = flag.String("state-file", "", "File recording the last sync without errors for --since-last-run (default: .difync-state.json next to the app map)")
+	outputFormat   = flag.String("output", "text", "Output format for the config command: text or json")
+	watchInterval  = flag.Duration("interval", 5*time.Minute, "Time between syncs for the watch command")
+	startupJitter  = flag.Duration("startup-jitter", 0, "Wait a random duration up to this long before syncing (0 disables)")
+	archivePath    = flag.String("archive", "", "Archive file written by the export command (.tar.gz, .tgz or .zip)")
+	showVersion    = flag.Bool("version", false, "Print version information and exit")
+	assumeYes      = flag.Bool("yes", false, "Skip confirmation prompts")
+	exitZeroNoApps = flag.Bool("exit-zero-on-no-apps", false, "Exit with 0 instead of 1 if init finds no apps, e.g. in a fresh workspace")
 )
 
+// headerFlag collects repeated --header "Key: Value" flags
+type headerFlag map[string]string
+
+// headerFlagVar defines a repeatable header flag
+func headerFlagVar(name, usage string) headerFlag {
+	headers := headerFlag{}
+	flag.Var(headers, name, usage)
+	return headers
+}
+
+// String implements flag.Value
+func (h headerFlag) String() string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+": "+h[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// Set implements flag.Value
+func (h headerFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("invalid header %q (use \"Key: Value\")", value)
+	}
+	h[key] = strings.TrimSpace(val)
+	return nil
+}
+
+// stringsFlag collects the values of a repeatable flag
+type stringsFlag []string
+
+// String implements flag.Value
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+// Set implements flag.Value
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 // For testing purposes, we make createSyncer a variable so it can be replaced in tests
 var createSyncer = func(config syncer.Config) syncer.Syncer {
 	return syncer.NewSyncer(config)
@@ -40,6 +183,67 @@ var createSyncer = func(config syncer.Config) syncer.Syncer {
 // For testing purposes
 var osExit = os.Exit
 
+// For testing purposes, confirmation prompts read from this reader
+var stdin io.Reader = os.Stdin
+
+// For testing purposes, startup jitter is drawn from jitterRand and waited for with sleep
+var (
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	sleep      = time.Sleep
+)
+
+// waitStartupJitter sleeps for a random duration in [0, jitter) so that runs scheduled
+// at the same time on many machines do not hit Dify at once
+func waitStartupJitter(jitter time.Duration, quiet bool) {
+	if jitter <= 0 {
+		return
+	}
+
+	delay := time.Duration(jitterRand.Int63n(int64(jitter)))
+	if !quiet {
+		fmt.Printf("Waiting %s before starting (startup jitter)\n", delay.Round(time.Millisecond))
+	}
+	sleep(delay)
+}
+
+// exitAuthFailure is the exit code used when authentication with Dify fails
+const exitAuthFailure = 2
+
+// readPassword returns the password from --password-file or --password-stdin,
+// or from the DIFY_PASSWORD environment variable if neither is given
+func readPassword() (string, error) {
+	if *passwordFile != "" && *passwordStdin {
+		return "", fmt.Errorf("--password-file and --password-stdin cannot be used together")
+	}
+
+	var data []byte
+	switch {
+	case *passwordFile != "":
+		path, err := expandPath(*passwordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to expand password file path: %w", err)
+		}
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+	case *passwordStdin:
+		var err error
+		data, err = io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+	default:
+		return os.Getenv("DIFY_PASSWORD"), nil
+	}
+
+	// Trim a single trailing newline, as written by echo or most editors
+	password := string(data)
+	password = strings.TrimSuffix(password, "\n")
+	password = strings.TrimSuffix(password, "\r")
+	return password, nil
+}
+
 // loadConfigAndValidate loads configuration from flags and environment variables
 // and validates the configuration
 func loadConfigAndValidate() (*syncer.Config, error) {
@@ -49,14 +253,19 @@ func loadConfigAndValidate() (*syncer.Config, error) {
 		baseURL = os.Getenv("DIFY_BASE_URL")
 	}
 
-	// Email and password are only retrieved from environment variables
+	// Email is only retrieved from environment variables
 	email := os.Getenv("DIFY_EMAIL")
-	password := os.Getenv("DIFY_PASSWORD")
 
-	// Get DSL directory from flags or environment with default
+	// Password is read from a file or stdin if requested, falling back to the environment
+	password, err := readPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get DSL directory from flags or environment; the app map or the default is used otherwise
 	dslDirectory := *dslDir
 	if dslDirectory == "" {
-		dslDirectory = getEnvWithDefault("DSL_DIRECTORY", "dsl")
+		dslDirectory = os.Getenv("DSL_DIRECTORY")
 	}
 
 	// Get app map file from flags or environment with default
@@ -69,19 +278,108 @@ func loadConfigAndValidate() (*syncer.Config, error) {
 	if baseURL == "" {
 		return nil, fmt.Errorf("dify base URL is required. Set with --base-url or DIFY_BASE_URL env var")
 	}
+	baseURL, err = expandBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err = normalizeBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
 
 	if email == "" {
 		return nil, fmt.Errorf("dify email is required. Set with DIFY_EMAIL env var")
 	}
 
 	if password == "" {
-		return nil, fmt.Errorf("dify password is required. Set with DIFY_PASSWORD env var")
+		return nil, fmt.Errorf("dify password is required. Set with --password-file, --password-stdin or DIFY_PASSWORD env var")
 	}
 
-	// Resolve DSL directory path
-	dslDirPath, err := filepath.Abs(dslDirectory)
+	if *maxApps < 0 {
+		return nil, fmt.Errorf("--max-apps must not be negative")
+	}
+
+	if *concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	if *maxBandwidth < 0 {
+		return nil, fmt.Errorf("--max-bandwidth must not be negative")
+	}
+
+	if *maxIdleConns < 0 {
+		return nil, fmt.Errorf("--max-idle-conns must not be negative")
+	}
+
+	if *idleTimeout < 0 {
+		return nil, fmt.Errorf("--idle-conn-timeout must not be negative")
+	}
+
+	if *connectTimeout < 0 {
+		return nil, fmt.Errorf("--connect-timeout must not be negative")
+	}
+
+	if *tlsTimeout < 0 {
+		return nil, fmt.Errorf("--tls-handshake-timeout must not be negative")
+	}
+
+	if *watchInterval <= 0 {
+		return nil, fmt.Errorf("--interval must be positive")
+	}
+
+	if *startupJitter < 0 {
+		return nil, fmt.Errorf("--startup-jitter must not be negative")
+	}
+
+	switch *lineEnding {
+	case syncer.LineEndingLF, syncer.LineEndingCRLF, syncer.LineEndingPreserve:
+	default:
+		return nil, fmt.Errorf("invalid --line-ending %q (use lf, crlf or preserve)", *lineEnding)
+	}
+
+	fileMode, err := parseFileMode(*dslPermissions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve DSL directory path: %w", err)
+		return nil, err
+	}
+
+	switch *variant {
+	case syncer.VariantDraft, syncer.VariantPublished:
+	default:
+		return nil, fmt.Errorf("invalid --variant %q (use draft or published)", *variant)
+	}
+
+	if *basicAuth != "" && !strings.Contains(*basicAuth, ":") {
+		return nil, fmt.Errorf("--basic-auth must be in the form user:pass")
+	}
+
+	if *quiet && *verbose {
+		return nil, fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+
+	if *ignoreKeys != "" && !*skipUnchanged {
+		return nil, fmt.Errorf("--ignore-keys requires --skip-unchanged-content")
+	}
+
+	// --only-new is on by default, so only an explicit --only-new conflicts with --overwrite
+	onlyNewSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "only-new" {
+			onlyNewSet = true
+		}
+	})
+	if onlyNewSet && *onlyNew && *overwrite {
+		return nil, fmt.Errorf("--only-new and --overwrite cannot be used together")
+	}
+
+	mode, err := color.ParseMode(*colorMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand environment variables and ~ in paths
+	appMap, err = expandPath(appMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand app map file path: %w", err)
 	}
 
 	// Resolve app map file path
@@ -90,6 +388,123 @@ func loadConfigAndValidate() (*syncer.Config, error) {
 		return nil, fmt.Errorf("failed to resolve app map file path: %w", err)
 	}
 
+	// An app map may set its own DSL directory, relative to the app map file.
+	// Unreadable app maps are reported when they are loaded.
+	if dslDirectory == "" {
+		dslDirectory, _ = syncer.ReadAppMapDSLDirectory(appMapPath)
+	}
+	if dslDirectory == "" {
+		dslDirectory = "dsl"
+	}
+
+	dslDirectory, err = expandPath(dslDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand DSL directory path: %w", err)
+	}
+
+	// Resolve DSL directory path
+	dslDirPath, err := filepath.Abs(dslDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve DSL directory path: %w", err)
+	}
+
+	// Resolve audit log file path if set
+	auditLogPath := *auditLog
+	if auditLogPath != "" {
+		auditLogPath, err = expandPath(auditLogPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand audit log file path: %w", err)
+		}
+		auditLogPath, err = filepath.Abs(auditLogPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve audit log file path: %w", err)
+		}
+	}
+
+	// Resolve the API error dump directory if set
+	dumpDir := *errorDumpDir
+	if dumpDir != "" {
+		dumpDir, err = expandPath(dumpDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand dump directory path: %w", err)
+		}
+		dumpDir, err = filepath.Abs(dumpDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve dump directory path: %w", err)
+		}
+	}
+
+	// Resolve archive directory for deleted apps if set
+	archiveDeletedDir := *archiveDeleted
+	if archiveDeletedDir != "" {
+		archiveDeletedDir, err = expandPath(archiveDeletedDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand archive directory path: %w", err)
+		}
+		archiveDeletedDir, err = filepath.Abs(archiveDeletedDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve archive directory path: %w", err)
+		}
+	}
+
+	// Resolve report file path if set
+	reportPath := *reportFile
+	if reportPath != "" {
+		reportPath, err = expandPath(reportPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand report file path: %w", err)
+		}
+		reportPath, err = filepath.Abs(reportPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve report file path: %w", err)
+		}
+	}
+
+	// Resolve metrics file path if set
+	metricsPath := *metricsFile
+	if metricsPath != "" {
+		metricsPath, err = expandPath(metricsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand metrics file path: %w", err)
+		}
+		metricsPath, err = filepath.Abs(metricsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve metrics file path: %w", err)
+		}
+	}
+
+	// Resolve state file path if set; the syncer defaults to a file next to the app map
+	statePath := *stateFile
+	if statePath != "" {
+		statePath, err = expandPath(statePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand state file path: %w", err)
+		}
+		statePath, err = filepath.Abs(statePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve state file path: %w", err)
+		}
+	}
+
+	// Resolve manifest file path if set
+	manifestPath := *manifestFile
+	if manifestPath != "" {
+		manifestPath, err = expandPath(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand manifest file path: %w", err)
+		}
+		manifestPath, err = filepath.Abs(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve manifest file path: %w", err)
+		}
+	}
+
+	// Identify requests by version unless a User-Agent is given
+	agent := *userAgent
+	if agent == "" {
+		agent = "difync/" + version
+	}
+
 	// Create syncer config
 	config := &syncer.Config{
 		DifyBaseURL:  baseURL,
@@ -99,17 +514,126 @@ func loadConfigAndValidate() (*syncer.Config, error) {
 		AppMapFile:   appMapPath,
 		DryRun:       *dryRun,
 		Verbose:      *verbose,
+		Trace:        *trace,
+		ErrorDumpDir: dumpDir,
+		Quiet:        *quiet,
+		SummaryOnly:  *summaryOnly,
+		Color:        color.Enabled(mode, os.Stdout),
+
+		DeleteOrphans:      *deleteOrphans,
+		ArchiveDeletedDir:  archiveDeletedDir,
+		PruneMap:           *pruneMap,
+		NoAppMap:           *noAppMap,
+		NoLock:             *noLock,
+		Reinit:             *reinit,
+		Overwrite:          *overwrite || !*onlyNew,
+		Dedupe:             *dedupe,
+		RateLimit:          *rateLimit,
+		MaxBandwidth:       *maxBandwidth,
+		MaxIdleConns:       *maxIdleConns,
+		IdleConnTimeout:    *idleTimeout,
+		ConnectTimeout:     *connectTimeout,
+		TLSTimeout:         *tlsTimeout,
+		DSLExtension:       *dslExtension,
+		APIPathPrefix:      *apiPrefix,
+		ExtraHeaders:       extraHeaders,
+		UserAgent:          agent,
+		BasicAuth:          *basicAuth,
+		ExportPath:         *exportPath,
+		AuditLogFile:       auditLogPath,
+		VerifyWrites:       *verifyWrites,
+		SkipUnchanged:      *skipUnchanged,
+		IgnoreKeys:         splitList(*ignoreKeys),
+		SkipForbidden:      *skipForbidden,
+		TouchOnSync:        *touchOnSync,
+		RedownloadMissing:  *redownload,
+		FailFast:           *failFast,
+		Variant:            *variant,
+		FileMode:           fileMode,
+		FollowSymlinks:     *followSymlinks,
+		LineEnding:         *lineEnding,
+		ReportFile:         reportPath,
+		MetricsFile:        metricsPath,
+		ManifestFile:       manifestPath,
+		SinceLastRun:       *sinceLastRun,
+		Resume:             *resume,
+		StateFile:          statePath,
+		MaxApps:            *maxApps,
+		Concurrency:        *concurrency,
+		ClockSkewTolerance: *clockSkew,
 	}
 
 	return config, nil
 }
 
+// expandBaseURL replaces ${VAR} and $VAR in the Dify base URL with environment variables,
+// e.g. https://dify.${ENV}.example.com, so one configuration serves several environments.
+// Unset variables are an error rather than silently left empty.
+func expandBaseURL(raw string) (string, error) {
+	var missing []string
+	expanded := os.Expand(raw, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("invalid Dify base URL %q: environment variable %s is not set", raw, strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// normalizeBaseURL checks that the Dify base URL is an absolute http or https URL
+// and trims trailing slashes, so endpoint paths can be appended to it
+func normalizeBaseURL(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid Dify base URL %q: %w", raw, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("invalid Dify base URL %q: must start with http:// or https://", raw)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid Dify base URL %q: missing host", raw)
+	}
+	return strings.TrimRight(raw, "/"), nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFileMode parses the octal --dsl-permissions value, e.g. "0600" or "644"
+func parseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid --dsl-permissions %q (use octal permissions such as 0644 or 0600)", value)
+	}
+	return os.FileMode(mode), nil
+}
+
 // printInfo prints information about the sync operation
 func printInfo(config *syncer.Config) {
+	if config.Quiet {
+		return
+	}
+
 	fmt.Println("Difync - Dify.AI DSL Synchronizer")
 	fmt.Println("----------------------------")
 	fmt.Printf("DSL Directory: %s\n", config.DSLDirectory)
-	fmt.Printf("App Map File: %s\n", config.AppMapFile)
+	if config.NoAppMap {
+		fmt.Println("App Map File: none (discovering all apps)")
+	} else {
+		fmt.Printf("App Map File: %s\n", config.AppMapFile)
+	}
 	if config.DryRun {
 		fmt.Println("Mode: DRY RUN (no changes will be made)")
 	} else {
@@ -118,29 +642,214 @@ func printInfo(config *syncer.Config) {
 	fmt.Println()
 }
 
+// printVersion prints the build information
+func printVersion(w io.Writer) {
+	fmt.Fprintf(w, "difync %s (commit: %s, built: %s)\n", version, commit, date)
+}
+
+// redactedPassword replaces a password when printing the configuration
+const redactedPassword = "********"
+
+// configEntry is a single resolved configuration value printed by the config command
+type configEntry struct {
+	key   string
+	value interface{}
+}
+
+// printConfig prints the resolved configuration with the password redacted
+func printConfig(w io.Writer, config *syncer.Config, format string) error {
+	password := ""
+	if config.DifyPassword != "" {
+		password = redactedPassword
+	}
+
+	// Header values and basic auth passwords often hold gateway secrets
+	headers := make(map[string]string, len(config.ExtraHeaders))
+	for key := range config.ExtraHeaders {
+		headers[key] = redactedPassword
+	}
+	basicAuthValue := ""
+	if user, _, ok := strings.Cut(config.BasicAuth, ":"); ok {
+		basicAuthValue = user + ":" + redactedPassword
+	}
+
+	entries := []configEntry{
+		{"base_url", config.DifyBaseURL},
+		{"email", config.DifyEmail},
+		{"password", password},
+		{"dsl_directory", config.DSLDirectory},
+		{"app_map_file", config.AppMapFile},
+		{"dry_run", config.DryRun},
+		{"verbose", config.Verbose},
+		{"trace", config.Trace},
+		{"error_dump_dir", config.ErrorDumpDir},
+		{"quiet", config.Quiet},
+		{"summary_only", config.SummaryOnly},
+		{"color", config.Color},
+		{"delete_orphans", config.DeleteOrphans},
+		{"archive_deleted_dir", config.ArchiveDeletedDir},
+		{"prune_map", config.PruneMap},
+		{"no_app_map", config.NoAppMap},
+		{"no_lock", config.NoLock},
+		{"reinit", config.Reinit},
+		{"overwrite", config.Overwrite},
+		{"dedupe", config.Dedupe},
+		{"rate_limit", config.RateLimit},
+		{"max_bandwidth", config.MaxBandwidth},
+		{"max_idle_conns", config.MaxIdleConns},
+		{"idle_conn_timeout", config.IdleConnTimeout.String()},
+		{"connect_timeout", config.ConnectTimeout.String()},
+		{"tls_handshake_timeout", config.TLSTimeout.String()},
+		{"dsl_extension", config.DSLExtension},
+		{"api_path_prefix", config.APIPathPrefix},
+		{"extra_headers", headers},
+		{"user_agent", config.UserAgent},
+		{"basic_auth", basicAuthValue},
+		{"export_path", config.ExportPath},
+		{"audit_log_file", config.AuditLogFile},
+		{"verify_writes", config.VerifyWrites},
+		{"skip_unchanged_content", config.SkipUnchanged},
+		{"ignore_keys", strings.Join(config.IgnoreKeys, ",")},
+		{"skip_forbidden", config.SkipForbidden},
+		{"touch_on_sync", config.TouchOnSync},
+		{"redownload_missing", config.RedownloadMissing},
+		{"fail_fast", config.FailFast},
+		{"variant", config.Variant},
+		{"follow_symlinks", config.FollowSymlinks},
+		{"dsl_permissions", fmt.Sprintf("%#o", config.FileMode)},
+		{"line_ending", config.LineEnding},
+		{"report_file", config.ReportFile},
+		{"metrics_file", config.MetricsFile},
+		{"manifest_file", config.ManifestFile},
+		{"since_last_run", config.SinceLastRun},
+		{"resume", config.Resume},
+		{"state_file", config.StateFile},
+		{"max_apps", config.MaxApps},
+		{"concurrency", config.Concurrency},
+		{"clock_skew_tolerance", config.ClockSkewTolerance.String()},
+	}
+
+	switch format {
+	case "json":
+		values := make(map[string]interface{}, len(entries))
+		for _, entry := range entries {
+			values[entry.key] = entry.value
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(values)
+	case "text", "":
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s: %v\n", entry.key, entry.value)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q (use text or json)", format)
+	}
+}
+
+// maxFailedAppsShown limits the failed apps listed in the summary
+const maxFailedAppsShown = 20
+
 // printStats prints statistics about the sync operation
-func printStats(stats *syncer.SyncStats, duration time.Duration) {
+func printStats(config *syncer.Config, stats *syncer.SyncStats, duration time.Duration) {
+	if config.Quiet {
+		return
+	}
+
+	// Count the errors from the failed apps listed below, so that the two always agree
+	c := color.New(config.Color)
+	failed := stats.Failed()
+	errorsLine := fmt.Sprintf("Errors: %d", len(failed))
+	if len(failed) > 0 {
+		errorsLine = c.Red(errorsLine)
+	}
+
 	fmt.Println("\nSync Summary:")
 	fmt.Printf("Total apps: %d\n", stats.Total)
-	fmt.Printf("Downloads: %d\n", stats.Downloads)
-	fmt.Printf("No action (in sync): %d\n", stats.NoAction)
-	fmt.Printf("Errors: %d\n", stats.Errors)
+	fmt.Println(c.Yellow(fmt.Sprintf("Downloads: %d", stats.Downloads)))
+	fmt.Printf("Renamed: %d\n", stats.Renamed)
+	fmt.Printf("Deleted: %d\n", stats.Deleted)
+	fmt.Printf("Skipped: %d\n", stats.Skipped)
+	fmt.Printf("Downloaded: %s\n", syncer.FormatBytes(stats.BytesDownloaded))
+	fmt.Println(c.Green(fmt.Sprintf("No action (in sync): %d", stats.NoAction)))
+	fmt.Println(errorsLine)
 	fmt.Printf("Duration: %v\n", duration)
+
+	// Name the failed apps so users need not re-run with --verbose
+	if len(failed) > 0 {
+		fmt.Println(c.Red("\nFailed apps:"))
+		for i, result := range failed {
+			if i == maxFailedAppsShown {
+				fmt.Printf("  ... and %d more\n", len(failed)-maxFailedAppsShown)
+				break
+			}
+			fmt.Printf("  %s (app_id: %s): %v\n", result.Filename, result.AppID, result.Error)
+		}
+	}
+
+	// Show what a dry run would have changed on disk and in the app map
+	if len(stats.Planned) > 0 {
+		fmt.Println("\nPlanned changes (dry run):")
+		for _, planned := range stats.Planned {
+			switch planned.Action {
+			case syncer.ActionRename:
+				fmt.Printf("  would rename %s -> %s\n", planned.Filename, planned.NewFilename)
+			case syncer.ActionDelete:
+				fmt.Printf("  would delete %s (app_id: %s)\n", planned.Filename, planned.AppID)
+			}
+		}
+	}
 }
 
 // runInit initializes the app map file
-func runInit(config *syncer.Config) (int, error) {
+func runInit(config *syncer.Config, args []string) (int, error) {
 	// Validate config
 	if config == nil {
 		return 1, fmt.Errorf("configuration is nil")
 	}
+	if config.NoAppMap {
+		return 1, fmt.Errorf("init writes an app map and cannot be used with --no-app-map")
+	}
 
-	fmt.Println("Difync - Dify.AI DSL Synchronizer")
-	fmt.Println("----------------------------")
-	fmt.Println("Initializing app map file...")
+	initFlags := flag.NewFlagSet("init", flag.ContinueOnError)
+	initFlags.SetOutput(io.Discard)
+	var appIDs stringsFlag
+	initFlags.Var(&appIDs, "app-id", "Only add this app to the app map (repeatable)")
+
+	if err := initFlags.Parse(args); err != nil {
+		return 1, fmt.Errorf("invalid init arguments: %w", err)
+	}
+	if initFlags.NArg() > 0 {
+		return 1, fmt.Errorf("unexpected arguments for init: %s", strings.Join(initFlags.Args(), " "))
+	}
+	if len(appIDs) > 0 {
+		config.AppIDs = appIDs
+	}
+
+	if !config.Quiet {
+		fmt.Println("Difync - Dify.AI DSL Synchronizer")
+		fmt.Println("----------------------------")
+		fmt.Println("Initializing app map file...")
+	}
+
+	// Ask before downloading more apps than --max-apps allows
+	if config.MaxApps > 0 && config.ConfirmMaxApps == nil {
+		config.ConfirmMaxApps = func(count int) bool {
+			fmt.Printf("Found %d applications, more than the --max-apps limit of %d\n", count, config.MaxApps)
+			return *assumeYes || confirm(fmt.Sprintf("Download all %d applications?", count))
+		}
+	}
 
 	syncr := createSyncer(*config)
 
+	// Fail early with a clear message if login failed
+	if err := syncr.Validate(); err != nil {
+		return exitAuthFailure, err
+	}
+	defer logoutOnExit(syncr)
+	defer closeOnExit(syncr)
+
 	// Type assertion using duck typing to check for InitializeAppMap method
 	// Use reflection to check if the object has the InitializeAppMap method
 	initMethod := reflect.ValueOf(syncr).MethodByName("InitializeAppMap")
@@ -157,6 +866,16 @@ func runInit(config *syncer.Config) (int, error) {
 	// Check for error
 	errVal := results[1].Interface()
 	if errVal != nil {
+		if err, ok := errVal.(error); ok && errors.Is(err, syncer.ErrNoApps) {
+			// A fresh workspace has no apps yet, which is not a failure for some pipelines
+			if *exitZeroNoApps {
+				if !config.Quiet {
+					fmt.Printf("No apps found in this Dify workspace at %s, nothing to initialize\n", config.DifyBaseURL)
+				}
+				return 0, nil
+			}
+			return 1, fmt.Errorf("no apps found in this Dify workspace at %s, check your base URL and account", config.DifyBaseURL)
+		}
 		return 1, fmt.Errorf("initialization failed: %v", errVal)
 	}
 
@@ -167,9 +886,319 @@ func runInit(config *syncer.Config) (int, error) {
 		return 1, fmt.Errorf("unexpected return type from InitializeAppMap")
 	}
 
-	fmt.Printf("Successfully initialized app map file with %d applications\n", len(appMap.Apps))
-	fmt.Printf("App map file created at: %s\n", config.AppMapFile)
-	fmt.Printf("DSL files downloaded to: %s\n", config.DSLDirectory)
+	if !config.Quiet {
+		fmt.Printf("Successfully initialized app map file with %d applications\n", len(appMap.Apps))
+		fmt.Printf("App map file created at: %s\n", config.AppMapFile)
+		fmt.Printf("DSL files downloaded to: %s\n", config.DSLDirectory)
+	}
+	return 0, nil
+}
+
+// logouter is implemented by syncers that can invalidate their console token
+type logouter interface {
+	Logout() error
+}
+
+// logoutOnExit invalidates the console token of the syncer if supported
+func logoutOnExit(syncr syncer.Syncer) {
+	l, ok := syncr.(logouter)
+	if !ok {
+		return
+	}
+	if err := l.Logout(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to logout from Dify API: %v\n", err)
+	}
+}
+
+// closeOnExit releases the resources of the syncer, such as its audit log, if supported
+func closeOnExit(syncr syncer.Syncer) {
+	c, ok := syncr.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := c.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to close syncer: %v\n", err)
+	}
+}
+
+// orphanPruner is implemented by syncers that can list and remove DSL files missing from the app map
+type orphanPruner interface {
+	FindOrphanedFiles() ([]string, error)
+	RemoveOrphanedFiles(names []string) (int, error)
+}
+
+// fileChecker is implemented by syncers that can check DSL files for problems
+type fileChecker interface {
+	CheckFiles() ([]syncer.FileProblem, error)
+}
+
+// archiveExporter is implemented by syncers that can export all DSL files into an archive
+type archiveExporter interface {
+	ExportArchive(path string) (int, error)
+}
+
+// appDownloader is implemented by syncers that can download a single app's DSL by ID
+type appDownloader interface {
+	GetAppDSL(appID string) ([]byte, error)
+}
+
+// appResolver is implemented by syncers that can look up the app ID of a DSL file
+type appResolver interface {
+	ResolveAppID(ref string) (string, error)
+}
+
+// confirm asks the user a yes/no question and returns true only for an explicit yes
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// runPrune removes local DSL files that are not in the app map
+func runPrune(config *syncer.Config) (int, error) {
+	// Validate config
+	if config == nil {
+		return 1, fmt.Errorf("configuration is nil")
+	}
+
+	if !config.Quiet {
+		fmt.Println("Difync - Dify.AI DSL Synchronizer")
+		fmt.Println("----------------------------")
+		fmt.Println("Looking for DSL files not in the app map...")
+	}
+
+	syncr := createSyncer(*config)
+	defer closeOnExit(syncr)
+
+	pruner, ok := syncr.(orphanPruner)
+	if !ok {
+		return 1, fmt.Errorf("syncer does not support pruning")
+	}
+
+	orphans, err := pruner.FindOrphanedFiles()
+	if err != nil {
+		return 1, fmt.Errorf("failed to find orphaned files: %w", err)
+	}
+
+	if len(orphans) == 0 {
+		if !config.Quiet {
+			fmt.Println("No orphaned DSL files found")
+		}
+		return 0, nil
+	}
+
+	if !config.Quiet {
+		fmt.Printf("Found %d orphaned DSL files:\n", len(orphans))
+		for _, name := range orphans {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	if config.DryRun {
+		if !config.Quiet {
+			fmt.Printf("Dry run: Would remove %d files\n", len(orphans))
+		}
+		return 0, nil
+	}
+
+	if !*assumeYes && !confirm(fmt.Sprintf("Remove %d files?", len(orphans))) {
+		fmt.Println("Aborted")
+		return 0, nil
+	}
+
+	removed, err := pruner.RemoveOrphanedFiles(orphans)
+	if !config.Quiet {
+		fmt.Printf("Removed %d files\n", removed)
+	}
+	if err != nil {
+		return 1, err
+	}
+
+	return 0, nil
+}
+
+// runDoctor reports missing, empty, malformed and unmapped DSL files.
+// It exits with 1 if any problems are found.
+func runDoctor(config *syncer.Config) (int, error) {
+	// Validate config
+	if config == nil {
+		return 1, fmt.Errorf("configuration is nil")
+	}
+
+	if !config.Quiet {
+		fmt.Println("Difync - Dify.AI DSL Synchronizer")
+		fmt.Println("----------------------------")
+		fmt.Println("Checking DSL files...")
+	}
+
+	syncr := createSyncer(*config)
+	defer closeOnExit(syncr)
+
+	checker, ok := syncr.(fileChecker)
+	if !ok {
+		return 1, fmt.Errorf("syncer does not support checking files")
+	}
+
+	problems, err := checker.CheckFiles()
+	if err != nil {
+		return 1, fmt.Errorf("failed to check DSL files: %w", err)
+	}
+
+	if len(problems) == 0 {
+		if !config.Quiet {
+			fmt.Println("No problems found")
+		}
+		return 0, nil
+	}
+
+	fmt.Printf("Found %d problems:\n", len(problems))
+	for _, problem := range problems {
+		if problem.AppID != "" {
+			fmt.Printf("  %s (app_id: %s): %s\n", problem.Filename, problem.AppID, problem.Problem)
+		} else {
+			fmt.Printf("  %s: %s\n", problem.Filename, problem.Problem)
+		}
+	}
+	fmt.Println("Remove broken files and run 'difync init' to download missing files again, or 'difync prune' to remove files not in the app map")
+	return 1, nil
+}
+
+// runExport writes every DSL in the app map into a single archive
+func runExport(config *syncer.Config) (int, error) {
+	// Validate config
+	if config == nil {
+		return 1, fmt.Errorf("configuration is nil")
+	}
+
+	if *archivePath == "" {
+		return 1, fmt.Errorf("--archive is required for export")
+	}
+
+	path, err := expandPath(*archivePath)
+	if err != nil {
+		return 1, fmt.Errorf("failed to expand archive path: %w", err)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return 1, fmt.Errorf("failed to resolve archive path: %w", err)
+	}
+
+	if !config.Quiet {
+		fmt.Println("Difync - Dify.AI DSL Synchronizer")
+		fmt.Println("----------------------------")
+		fmt.Println("Exporting DSL files to archive...")
+	}
+
+	syncr := createSyncer(*config)
+
+	// Fail early with a clear message if login failed
+	if err := syncr.Validate(); err != nil {
+		return exitAuthFailure, err
+	}
+	defer logoutOnExit(syncr)
+	defer closeOnExit(syncr)
+
+	exporter, ok := syncr.(archiveExporter)
+	if !ok {
+		return 1, fmt.Errorf("syncer does not support exporting archives")
+	}
+
+	count, err := exporter.ExportArchive(path)
+	if err != nil {
+		return 1, fmt.Errorf("failed to export archive: %w", err)
+	}
+
+	if !config.Quiet {
+		fmt.Printf("Exported %d DSL files to %s\n", count, path)
+	}
+	return 0, nil
+}
+
+// runGet downloads the DSL of a single app by ID without using the app map.
+// args are the arguments after the subcommand: the app ID plus --out and --include-secret,
+// which may come before or after it.
+func runGet(config *syncer.Config, args []string) (int, error) {
+	// Validate config
+	if config == nil {
+		return 1, fmt.Errorf("configuration is nil")
+	}
+
+	getFlags := flag.NewFlagSet("get", flag.ContinueOnError)
+	getFlags.SetOutput(io.Discard)
+	out := getFlags.String("out", "", "File to write the DSL to, or - for stdout")
+	includeSecret := getFlags.Bool("include-secret", false, "Include secrets such as API keys in the DSL")
+
+	if err := getFlags.Parse(args); err != nil {
+		return 1, fmt.Errorf("invalid get arguments: %w", err)
+	}
+	if getFlags.NArg() == 0 {
+		return 1, fmt.Errorf("usage: difync get <app-id> --out <file|->")
+	}
+	appID := getFlags.Arg(0)
+	if err := getFlags.Parse(getFlags.Args()[1:]); err != nil {
+		return 1, fmt.Errorf("invalid get arguments: %w", err)
+	}
+	if getFlags.NArg() > 0 {
+		return 1, fmt.Errorf("unexpected arguments for get: %s", strings.Join(getFlags.Args(), " "))
+	}
+	if *out == "" {
+		return 1, fmt.Errorf("--out is required for get (use - for stdout)")
+	}
+
+	getConfig := *config
+	if *includeSecret {
+		if getConfig.ExportPath != "" && getConfig.ExportPath != api.DefaultExportPath {
+			return 1, fmt.Errorf("--include-secret cannot be combined with --export-path %s", getConfig.ExportPath)
+		}
+		getConfig.ExportPath = api.SecretExportPath
+	}
+
+	syncr := createSyncer(getConfig)
+
+	// Fail early with a clear message if login failed
+	if err := syncr.Validate(); err != nil {
+		return exitAuthFailure, err
+	}
+	defer logoutOnExit(syncr)
+	defer closeOnExit(syncr)
+
+	downloader, ok := syncr.(appDownloader)
+	if !ok {
+		return 1, fmt.Errorf("syncer does not support downloading single apps")
+	}
+
+	dsl, err := downloader.GetAppDSL(appID)
+	if err != nil {
+		return 1, err
+	}
+
+	// Write to stdout without any other output so the DSL can be piped
+	if *out == "-" {
+		if _, err := os.Stdout.Write(dsl); err != nil {
+			return 1, fmt.Errorf("failed to write DSL to stdout: %w", err)
+		}
+		return 0, nil
+	}
+
+	path, err := expandPath(*out)
+	if err != nil {
+		return 1, fmt.Errorf("failed to expand output path: %w", err)
+	}
+	mode := config.FileMode
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.WriteFile(path, dsl, mode); err != nil {
+		return 1, fmt.Errorf("failed to write DSL file: %w", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return 1, fmt.Errorf("failed to set DSL file permissions: %w", err)
+	}
+
+	if !config.Quiet {
+		fmt.Printf("Downloaded app %s to %s (%s)\n", appID, path, syncer.FormatBytes(int64(len(dsl))))
+	}
 	return 0, nil
 }
 
@@ -180,18 +1209,44 @@ func runSync(config *syncer.Config) (int, error) {
 		return 1, fmt.Errorf("configuration is nil")
 	}
 
+	// Spread out scheduled runs before contacting Dify, including the login
+	waitStartupJitter(*startupJitter, config.Quiet)
+
 	// Create syncer
-	syncr := createSyncer(*config)
+	syncr := createSyncer(withProgress(*config))
+
+	// Fail early with a clear message if login failed
+	if err := syncr.Validate(); err != nil {
+		return exitAuthFailure, err
+	}
+	defer logoutOnExit(syncr)
+	defer closeOnExit(syncr)
 
 	// Print info
 	printInfo(config)
 
+	return syncAndReport(config, syncr)
+}
+
+// withProgress draws a progress bar during syncs if stdout is a terminal.
+// Quiet and JSON output disable it.
+func withProgress(config syncer.Config) syncer.Config {
+	if config.Progress == nil && !config.Quiet && *outputFormat != "json" && progress.Enabled(os.Stdout) {
+		config.Progress = progress.New(os.Stdout).Update
+	}
+	return config
+}
+
+// syncAndReport runs a sync with an authenticated syncer, then prints and writes its results
+func syncAndReport(config *syncer.Config, syncr syncer.Syncer) (int, error) {
 	// Start sync
-	fmt.Println("Starting sync...")
+	if !config.Quiet {
+		fmt.Println("Starting sync...")
+	}
 	startTime := time.Now()
 
 	stats, err := syncr.SyncAll()
-	if err != nil {
+	if err != nil && stats == nil {
 		// Display initialization errors more clearly
 		errMsg := err.Error()
 		appMapNotFoundErr := fmt.Sprintf("app map file not found at %s", config.AppMapFile)
@@ -205,16 +1260,144 @@ func runSync(config *syncer.Config) (int, error) {
 
 	// Print summary
 	duration := time.Since(startTime)
-	printStats(stats, duration)
+	printStats(config, stats, duration)
 
-	// Return non-zero status code if there were errors
+	// Write the report independently of the console output
+	if config.ReportFile != "" {
+		if err := syncer.WriteReport(config.ReportFile, stats); err != nil {
+			return 1, err
+		}
+	}
+
+	if config.MetricsFile != "" {
+		if err := syncer.WriteMetrics(config.MetricsFile, stats); err != nil {
+			return 1, err
+		}
+	}
+
+	// A sync stopped part way, e.g. by --fail-fast, still reports the apps synced so far
+	if err != nil {
+		return 1, fmt.Errorf("error during sync: %w", err)
+	}
+
+	// Return non-zero status code if there were errors. Quiet mode prints no summary,
+	// so the failed apps are returned to be printed to stderr.
 	if stats.Errors > 0 {
+		if config.Quiet {
+			return 1, stats.Err()
+		}
 		return 1, nil
 	}
 
 	return 0, nil
 }
 
+// runCat prints the current remote DSL of a single app to stdout, and nothing else, so it
+// can be piped to other tools. The app is given by the filename of its app map entry or by
+// its app ID. Nothing is written to disk.
+func runCat(config *syncer.Config, args []string) (int, error) {
+	// Validate config
+	if config == nil {
+		return 1, fmt.Errorf("configuration is nil")
+	}
+	if len(args) != 1 {
+		return 1, fmt.Errorf("usage: difync cat <filename|app-id>")
+	}
+
+	syncr := createSyncer(*config)
+
+	// Fail early with a clear message if login failed
+	if err := syncr.Validate(); err != nil {
+		return exitAuthFailure, err
+	}
+	defer logoutOnExit(syncr)
+	defer closeOnExit(syncr)
+
+	resolver, ok := syncr.(appResolver)
+	if !ok {
+		return 1, fmt.Errorf("syncer does not support resolving apps")
+	}
+	downloader, ok := syncr.(appDownloader)
+	if !ok {
+		return 1, fmt.Errorf("syncer does not support downloading single apps")
+	}
+
+	appID, err := resolver.ResolveAppID(args[0])
+	if err != nil {
+		return 1, err
+	}
+
+	dsl, err := downloader.GetAppDSL(appID)
+	if err != nil {
+		return 1, err
+	}
+
+	if _, err := os.Stdout.Write(dsl); err != nil {
+		return 1, fmt.Errorf("failed to write DSL to stdout: %w", err)
+	}
+	return 0, nil
+}
+
+// runWatch syncs every interval until a signal is received on stop.
+// A single syncer, and with it a single authenticated client, is reused for every cycle.
+// A signal received during a sync stops the watch after that sync has finished.
+func runWatch(config *syncer.Config, interval time.Duration, stop <-chan os.Signal) (int, error) {
+	// Validate config
+	if config == nil {
+		return 1, fmt.Errorf("configuration is nil")
+	}
+
+	// Spread out scheduled runs before contacting Dify, including the login
+	waitStartupJitter(*startupJitter, config.Quiet)
+
+	// Create syncer
+	syncr := createSyncer(withProgress(*config))
+
+	// Fail early with a clear message if login failed
+	if err := syncr.Validate(); err != nil {
+		return exitAuthFailure, err
+	}
+	defer logoutOnExit(syncr)
+	defer closeOnExit(syncr)
+
+	// Print info
+	printInfo(config)
+	if !config.Quiet {
+		fmt.Printf("Watching for changes every %s (press Ctrl+C to stop)\n", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Errors of a single cycle are reported and retried on the next one
+		if _, err := syncAndReport(config, syncr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+
+		// Prefer stopping over another cycle if both are due
+		select {
+		case <-stop:
+			return stopWatch(config)
+		default:
+		}
+
+		select {
+		case <-stop:
+			return stopWatch(config)
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopWatch reports that the watch has stopped
+func stopWatch(config *syncer.Config) (int, error) {
+	if !config.Quiet {
+		fmt.Println("Stopping watch")
+	}
+	return 0, nil
+}
+
 func main() {
 	// Load .env file if it exists
 	_ = godotenv.Load()
@@ -228,10 +1411,17 @@ func main() {
 		subCommand = args[0]
 	}
 
+	// Print version without requiring configuration
+	if *showVersion || subCommand == "version" {
+		printVersion(os.Stdout)
+		osExit(0)
+		return
+	}
+
 	// Load and validate configuration
 	config, err := loadConfigAndValidate()
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		osExit(1)
 	}
 
@@ -241,15 +1431,43 @@ func main() {
 	switch subCommand {
 	case "init":
 		// Initialization command
-		exitCode, err = runInit(config)
+		exitCode, err = runInit(config, args[1:])
+	case "prune":
+		// Remove local files not in the app map
+		exitCode, err = runPrune(config)
+	case "doctor":
+		// Check DSL files for problems left by interrupted downloads
+		exitCode, err = runDoctor(config)
+	case "config":
+		// Print the resolved configuration without contacting Dify
+		if err = printConfig(os.Stdout, config, *outputFormat); err != nil {
+			exitCode = 1
+		}
+	case "export":
+		// Write all DSL files into a single archive
+		exitCode, err = runExport(config)
+	case "get":
+		// Download a single app by ID without the app map
+		exitCode, err = runGet(config, args[1:])
+	case "cat":
+		// Print the remote DSL of a single app for scripts
+		exitCode, err = runCat(config, args[1:])
+	case "watch":
+		// Sync on an interval until interrupted
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		exitCode, err = runWatch(config, *watchInterval, stop)
+		signal.Stop(stop)
 	default:
 		// Normal sync command
 		exitCode, err = runSync(config)
 	}
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		osExit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if exitCode == 0 {
+			exitCode = 1
+		}
 	}
 
 	osExit(exitCode)
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +41,65 @@ func TestGetEnvWithDefault(t *testing.T) {
 	}
 }
 
+func TestExpandPath(t *testing.T) {
+	os.Setenv("DIFYNC_TEST_DIR", "/tmp/difync")
+	defer os.Unsetenv("DIFYNC_TEST_DIR")
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("Failed to get home directory: %v", err)
+	}
+
+	testCases := []struct {
+		input    string
+		expected string
+		desc     string
+	}{
+		{
+			input:    "$DIFYNC_TEST_DIR/dsl",
+			expected: "/tmp/difync/dsl",
+			desc:     "Expand $VAR",
+		},
+		{
+			input:    "${DIFYNC_TEST_DIR}/app_map.json",
+			expected: "/tmp/difync/app_map.json",
+			desc:     "Expand ${VAR}",
+		},
+		{
+			input:    "~/dify/dsl",
+			expected: filepath.Join(home, "dify/dsl"),
+			desc:     "Expand leading ~/",
+		},
+		{
+			input:    "~",
+			expected: home,
+			desc:     "Expand bare ~",
+		},
+		{
+			input:    "dsl/~backup",
+			expected: "dsl/~backup",
+			desc:     "Keep ~ that is not leading",
+		},
+		{
+			input:    "dsl",
+			expected: "dsl",
+			desc:     "Keep plain relative path",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := expandPath(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("expandPath(%q) = %q, expected %q", tc.input, result, tc.expected)
+			}
+		})
+	}
+}
+
 // Test flags
 // This is a bit tricky since flags are package level variables
 // We need to reset them after the test
@@ -53,6 +120,8 @@ func TestFlags(t *testing.T) {
 	appMapFile := flag.String("app-map", "", "Path to app mapping file (overrides env: APP_MAP_FILE, default: app_map.json)")
 	dryRun := flag.Bool("dry-run", false, "Perform a dry run without making any changes")
 	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	deleteOrphans := flag.Bool("delete-orphans", false, "Delete local files for apps that no longer exist in Dify")
+	pruneMap := flag.Bool("prune-map", false, "Remove apps that no longer exist in Dify from the app map")
 
 	// Parse test args
 	err := flag.CommandLine.Parse([]string{
@@ -61,6 +130,8 @@ func TestFlags(t *testing.T) {
 		"-app-map", "test-map.json",
 		"-dry-run",
 		"-verbose",
+		"-delete-orphans",
+		"-prune-map",
 	})
 	if err != nil {
 		t.Fatalf("Failed to parse flags: %v", err)
@@ -86,6 +157,108 @@ func TestFlags(t *testing.T) {
 	if !*verbose {
 		t.Errorf("Expected verbose to be true")
 	}
+
+	if !*deleteOrphans {
+		t.Errorf("Expected delete-orphans to be true")
+	}
+
+	if !*pruneMap {
+		t.Errorf("Expected prune-map to be true")
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	// Save the original flag values, stdin and environment
+	originalFile, originalStdinFlag := passwordFile, passwordStdin
+	originalStdin := stdin
+	originalPassword := os.Getenv("DIFY_PASSWORD")
+	defer func() {
+		passwordFile, passwordStdin = originalFile, originalStdinFlag
+		stdin = originalStdin
+		os.Setenv("DIFY_PASSWORD", originalPassword)
+	}()
+
+	tmpDir, err := os.MkdirTemp("", "difync-test-password-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write password file: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		useStdin    bool
+		stdin       string
+		env         string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "file with trailing newline",
+			file:     writeFile("password", "file-secret\n"),
+			env:      "env-secret",
+			expected: "file-secret",
+		},
+		{
+			name:     "file trims only one newline",
+			file:     writeFile("password-blank-line", "file-secret\n\n"),
+			expected: "file-secret\n",
+		},
+		{
+			name:     "piped stdin",
+			useStdin: true,
+			stdin:    "stdin-secret\r\n",
+			env:      "env-secret",
+			expected: "stdin-secret",
+		},
+		{
+			name:     "environment fallback",
+			env:      "env-secret",
+			expected: "env-secret",
+		},
+		{
+			name:        "missing file",
+			file:        filepath.Join(tmpDir, "missing"),
+			expectError: true,
+		},
+		{
+			name:        "file and stdin together",
+			file:        writeFile("password-both", "file-secret"),
+			useStdin:    true,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, useStdin := tt.file, tt.useStdin
+			passwordFile, passwordStdin = &file, &useStdin
+			stdin = strings.NewReader(tt.stdin)
+			os.Setenv("DIFY_PASSWORD", tt.env)
+
+			password, err := readPassword()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if password != tt.expected {
+				t.Errorf("Expected password %q, got %q", tt.expected, password)
+			}
+		})
+	}
 }
 
 func TestLoadConfigAndValidate(t *testing.T) {
@@ -96,9 +269,11 @@ func TestLoadConfigAndValidate(t *testing.T) {
 	oldPassword := os.Getenv("DIFY_PASSWORD")
 	oldDSLDir := os.Getenv("DSL_DIRECTORY")
 	oldAppMapFile := os.Getenv("APP_MAP_FILE")
+	oldVerbose, oldQuiet := verbose, quiet
 
 	defer func() {
 		flag.CommandLine = oldFlagSet
+		verbose, quiet = oldVerbose, oldQuiet
 		os.Setenv("DIFY_BASE_URL", oldBaseURL)
 		os.Setenv("DIFY_EMAIL", oldEmail)
 		os.Setenv("DIFY_PASSWORD", oldPassword)
@@ -212,6 +387,86 @@ func TestLoadConfigAndValidate(t *testing.T) {
 	if config.DifyPassword != "envpassword" {
 		t.Errorf("Expected DifyPassword to be 'envpassword', got '%s'", config.DifyPassword)
 	}
+
+	// Test environment variable expansion in paths
+	os.Setenv("DIFYNC_WORKSPACE", "/tmp/workspace")
+	defer os.Unsetenv("DIFYNC_WORKSPACE")
+	os.Setenv("DSL_DIRECTORY", "$DIFYNC_WORKSPACE/dsl")
+	os.Setenv("APP_MAP_FILE", "${DIFYNC_WORKSPACE}/app_map.json")
+
+	config, err = loadConfigAndValidate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.DSLDirectory != "/tmp/workspace/dsl" {
+		t.Errorf("Expected DSLDirectory to be '/tmp/workspace/dsl', got '%s'", config.DSLDirectory)
+	}
+
+	if config.AppMapFile != "/tmp/workspace/app_map.json" {
+		t.Errorf("Expected AppMapFile to be '/tmp/workspace/app_map.json', got '%s'", config.AppMapFile)
+	}
+
+	// Test that --quiet and --verbose are mutually exclusive
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	difyBaseURL = flag.String("base-url", "", "")
+	dslDir = flag.String("dsl-dir", "", "")
+	appMapFile = flag.String("app-map", "", "")
+	dryRun = flag.Bool("dry-run", false, "")
+	verbose = flag.Bool("verbose", false, "")
+	quiet = flag.Bool("quiet", false, "")
+
+	flag.CommandLine.Parse([]string{"-quiet", "-verbose"})
+
+	_, err = loadConfigAndValidate()
+	if err == nil {
+		t.Error("Expected error when both --quiet and --verbose are set")
+	}
+
+	// Test that --ignore-keys requires --skip-unchanged-content
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	difyBaseURL = flag.String("base-url", "", "")
+	dslDir = flag.String("dsl-dir", "", "")
+	appMapFile = flag.String("app-map", "", "")
+	verbose = flag.Bool("verbose", false, "")
+	quiet = flag.Bool("quiet", false, "")
+	ignoreKeys = flag.String("ignore-keys", "", "")
+	skipUnchanged = flag.Bool("skip-unchanged-content", false, "")
+
+	flag.CommandLine.Parse([]string{"-ignore-keys", "export_time"})
+
+	_, err = loadConfigAndValidate()
+	if err == nil || !strings.Contains(err.Error(), "--skip-unchanged-content") {
+		t.Errorf("Expected error when --ignore-keys is set without --skip-unchanged-content, got %v", err)
+	}
+	*ignoreKeys = ""
+
+	// Test that an explicit --only-new conflicts with --overwrite, while the default does not
+	for _, tt := range []struct {
+		args    []string
+		wantErr bool
+	}{
+		{args: []string{"-only-new", "-overwrite"}, wantErr: true},
+		{args: []string{"-overwrite"}, wantErr: false},
+		{args: []string{"-only-new=false", "-overwrite"}, wantErr: false},
+	} {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		difyBaseURL = flag.String("base-url", "", "")
+		dslDir = flag.String("dsl-dir", "", "")
+		appMapFile = flag.String("app-map", "", "")
+		verbose = flag.Bool("verbose", false, "")
+		quiet = flag.Bool("quiet", false, "")
+		onlyNew = flag.Bool("only-new", true, "")
+		overwrite = flag.Bool("overwrite", false, "")
+
+		flag.CommandLine.Parse(tt.args)
+
+		_, err = loadConfigAndValidate()
+		if gotErr := err != nil && strings.Contains(err.Error(), "--only-new and --overwrite"); gotErr != tt.wantErr {
+			t.Errorf("Args %v: expected error %v, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+	*overwrite = false
 }
 
 func TestPrintInfo(t *testing.T) {
@@ -242,13 +497,282 @@ func TestPrintStats(t *testing.T) {
 	}
 
 	// Should not panic
-	printStats(stats, 1*time.Minute)
+	printStats(&syncer.Config{}, stats, 1*time.Minute)
+}
+
+func TestPrintStatsFailedApps(t *testing.T) {
+	stats := &syncer.SyncStats{Errors: 1}
+	for i := 0; i < maxFailedAppsShown+3; i++ {
+		stats.Results = append(stats.Results, syncer.SyncResult{
+			Filename: fmt.Sprintf("app%02d.yaml", i),
+			AppID:    fmt.Sprintf("app-%d", i),
+			Action:   syncer.ActionError,
+			Error:    errors.New("export failed"),
+		})
+	}
+	stats.Results = append([]syncer.SyncResult{{Filename: "ok.yaml", AppID: "app-ok", Action: syncer.ActionNone, Success: true}}, stats.Results...)
+
+	output := captureStdout(t, func() {
+		printStats(&syncer.Config{}, stats, time.Second)
+	})
+
+	for _, want := range []string{"Failed apps:", "app00.yaml (app_id: app-0): export failed", "app19.yaml", "... and 3 more"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"ok.yaml", "app20.yaml"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("Expected output not to contain %q, got:\n%s", unwanted, output)
+		}
+	}
+
+	// Quiet mode prints nothing
+	output = captureStdout(t, func() {
+		printStats(&syncer.Config{Quiet: true}, stats, time.Second)
+	})
+	if output != "" {
+		t.Errorf("Expected no output in quiet mode, got %q", output)
+	}
+}
+
+func TestPrintStatsPlannedChanges(t *testing.T) {
+	stats := &syncer.SyncStats{
+		Planned: []syncer.SyncResult{
+			{Filename: "old.yaml", AppID: "app-1", Action: syncer.ActionRename, NewFilename: "New.yaml"},
+			{Filename: "gone.yaml", AppID: "app-2", Action: syncer.ActionDelete},
+		},
+	}
+
+	output := captureStdout(t, func() {
+		printStats(&syncer.Config{DryRun: true}, stats, time.Second)
+	})
+
+	for _, want := range []string{"would rename old.yaml -> New.yaml", "would delete gone.yaml (app_id: app-2)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	config := &syncer.Config{
+		DifyBaseURL:  "https://test.example.com",
+		DifyEmail:    "test@example.com",
+		DifyPassword: "supersecret",
+		DSLDirectory: "/path/to/dsl",
+		AppMapFile:   "/path/to/app_map.json",
+		DryRun:       true,
+	}
+
+	// Text output
+	var buf bytes.Buffer
+	if err := printConfig(&buf, config, "text"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "supersecret") {
+		t.Errorf("Expected password to be redacted, got:\n%s", output)
+	}
+	for _, want := range []string{"password: " + redactedPassword, "base_url: https://test.example.com", "dry_run: true"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// JSON output
+	buf.Reset()
+	if err := printConfig(&buf, config, "json"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &values); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if values["password"] != redactedPassword {
+		t.Errorf("Expected password to be redacted, got %v", values["password"])
+	}
+	if values["dsl_directory"] != "/path/to/dsl" {
+		t.Errorf("Expected dsl_directory '/path/to/dsl', got %v", values["dsl_directory"])
+	}
+
+	// Unknown format
+	if err := printConfig(&buf, config, "yaml"); err == nil {
+		t.Error("Expected error for unsupported output format")
+	}
+}
+
+func TestPrintVersion(t *testing.T) {
+	var buf bytes.Buffer
+	printVersion(&buf)
+
+	output := buf.String()
+	if output == "" {
+		t.Fatal("Expected version output to be non-empty")
+	}
+
+	if !strings.Contains(output, version) || !strings.Contains(output, commit) || !strings.Contains(output, date) {
+		t.Errorf("Expected version output to contain version, commit and date, got %q", output)
+	}
+}
+
+func TestHeaderFlag(t *testing.T) {
+	headers := headerFlag{}
+	if err := headers.Set("X-Api-Key: secret"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := headers.Set("X-Trace:  a:b "); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if headers["X-Api-Key"] != "secret" {
+		t.Errorf("Expected X-Api-Key to be secret, got %q", headers["X-Api-Key"])
+	}
+	if headers["X-Trace"] != "a:b" {
+		t.Errorf("Expected X-Trace to be a:b, got %q", headers["X-Trace"])
+	}
+	if got := headers.String(); got != "X-Api-Key: secret, X-Trace: a:b" {
+		t.Errorf("Unexpected string %q", got)
+	}
+
+	for _, invalid := range []string{"X-Api-Key", ": value"} {
+		if err := headers.Set(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestNormalizeBaseURL(t *testing.T) {
+	valid := map[string]string{
+		"https://dify.example.com":       "https://dify.example.com",
+		"https://dify.example.com/":      "https://dify.example.com",
+		"http://localhost:5001//":        "http://localhost:5001",
+		"https://example.com/dify/":      "https://example.com/dify",
+		"https://dify.example.com:8443/": "https://dify.example.com:8443",
+	}
+	for raw, expected := range valid {
+		normalized, err := normalizeBaseURL(raw)
+		if err != nil {
+			t.Errorf("Expected no error for %q, got %v", raw, err)
+		}
+		if normalized != expected {
+			t.Errorf("Expected %q to be normalized to %q, got %q", raw, expected, normalized)
+		}
+	}
+
+	invalid := map[string]string{
+		"api.example.com":        "must start with http:// or https://",
+		"localhost:5001":         "must start with http:// or https://",
+		"ftp://dify.example.com": "must start with http:// or https://",
+		"https://":               "missing host",
+		"https://dify example":   "invalid Dify base URL",
+		"http://[::1":            "invalid Dify base URL",
+	}
+	for raw, expected := range invalid {
+		_, err := normalizeBaseURL(raw)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q for %q, got %v", expected, raw, err)
+		}
+	}
+}
+
+func TestExpandBaseURL(t *testing.T) {
+	t.Setenv("DIFYNC_TEST_ENV", "stage")
+
+	expanded, err := expandBaseURL("https://dify.${DIFYNC_TEST_ENV}.example.com/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expanded != "https://dify.stage.example.com/" {
+		t.Errorf("Expected the placeholder to be expanded, got %q", expanded)
+	}
+	if normalized, err := normalizeBaseURL(expanded); err != nil || normalized != "https://dify.stage.example.com" {
+		t.Errorf("Expected the expanded URL to be valid, got %q: %v", normalized, err)
+	}
+
+	// URLs without placeholders are unchanged
+	if expanded, err := expandBaseURL("https://dify.example.com"); err != nil || expanded != "https://dify.example.com" {
+		t.Errorf("Expected the URL to be unchanged, got %q: %v", expanded, err)
+	}
+
+	// Unset variables are reported instead of leaving a broken URL
+	_, err = expandBaseURL("https://dify.${DIFYNC_TEST_UNSET}.example.com")
+	if err == nil || !strings.Contains(err.Error(), "DIFYNC_TEST_UNSET is not set") {
+		t.Errorf("Expected an error naming the unset variable, got %v", err)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if items := splitList(" export_time, app.id,,"); strings.Join(items, "|") != "export_time|app.id" {
+		t.Errorf("Expected two keys, got %q", items)
+	}
+	if items := splitList(""); items != nil {
+		t.Errorf("Expected no keys, got %q", items)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	for value, expected := range map[string]os.FileMode{"0644": 0644, "0600": 0600, "640": 0640} {
+		mode, err := parseFileMode(value)
+		if err != nil {
+			t.Errorf("Expected no error for %q, got %v", value, err)
+		}
+		if mode != expected {
+			t.Errorf("Expected %q to parse as %o, got %o", value, expected, mode)
+		}
+	}
+
+	for _, invalid := range []string{"", "rw-r--r--", "0899", "01777", "-1"} {
+		if _, err := parseFileMode(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestWaitStartupJitter(t *testing.T) {
+	oldRand, oldSleep := jitterRand, sleep
+	defer func() {
+		jitterRand, sleep = oldRand, oldSleep
+	}()
+
+	var slept []time.Duration
+	sleep = func(d time.Duration) {
+		slept = append(slept, d)
+	}
+
+	// No jitter means no sleep at all
+	waitStartupJitter(0, true)
+	if len(slept) != 0 {
+		t.Fatalf("Expected no sleep without jitter, got %v", slept)
+	}
+
+	// The same seed gives the same delay, always below the jitter
+	jitter := time.Minute
+	for i := 0; i < 2; i++ {
+		jitterRand = rand.New(rand.NewSource(42))
+		waitStartupJitter(jitter, true)
+	}
+	if len(slept) != 2 {
+		t.Fatalf("Expected 2 sleeps, got %d", len(slept))
+	}
+	if slept[0] != slept[1] {
+		t.Errorf("Expected deterministic delays for the same seed, got %v and %v", slept[0], slept[1])
+	}
+	if slept[0] < 0 || slept[0] >= jitter {
+		t.Errorf("Expected delay in [0, %v), got %v", jitter, slept[0])
+	}
 }
 
 // MockSyncer implements the syncer.Syncer interface for testing
 type MockSyncer struct {
-	stats *syncer.SyncStats
-	err   error
+	stats       *syncer.SyncStats
+	err         error
+	validateErr error
+}
+
+// Validate implements the syncer.Syncer interface
+func (m *MockSyncer) Validate() error {
+	return m.validateErr
 }
 
 // LoadAppMap implements the syncer.Syncer interface
@@ -282,6 +806,16 @@ func (m *MockSyncer) SyncApp(app syncer.AppMapping) syncer.SyncResult {
 	}
 }
 
+// Plan implements the syncer.Syncer interface
+func (m *MockSyncer) Plan() ([]syncer.SyncResult, error) {
+	return nil, m.err
+}
+
+// FetchAll implements the syncer.Syncer interface
+func (m *MockSyncer) FetchAll() (map[string][]byte, error) {
+	return nil, m.err
+}
+
 func TestRunSync(t *testing.T) {
 	// Save the original factory function
 	originalFactory := createSyncer
@@ -358,33 +892,433 @@ func TestRunSync(t *testing.T) {
 	if exitCode != 1 {
 		t.Errorf("Expected exit code 1, got %d", exitCode)
 	}
+
+	// Test sync with authentication failure
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return &MockSyncer{
+			validateErr: fmt.Errorf("%w: invalid credentials", syncer.ErrAuthentication),
+		}
+	}
+
+	exitCode, err = runSync(config)
+	if err == nil || !errors.Is(err, syncer.ErrAuthentication) {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+	if exitCode != exitAuthFailure {
+		t.Errorf("Expected exit code %d, got %d", exitAuthFailure, exitCode)
+	}
 }
 
-// MockSyncerWithInit implements both Syncer and has InitializeAppMap method
-type MockSyncerWithInit struct {
+// captureStdout returns everything fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	originalStdout := os.Stdout
+	defer func() {
+		os.Stdout = originalStdout
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read captured stdout: %v", err)
+	}
+	return string(output)
+}
+
+// countingSyncer counts SyncAll calls and calls onSync after each of them
+type countingSyncer struct {
 	*MockSyncer
-	appMap  *syncer.AppMap
-	initErr error
+	syncs  int
+	onSync func(syncs int)
 }
 
-// InitializeAppMap mocks the DefaultSyncer.InitializeAppMap method
-func (m *MockSyncerWithInit) InitializeAppMap() (*syncer.AppMap, error) {
-	return m.appMap, m.initErr
+// SyncAll implements the syncer.Syncer interface
+func (c *countingSyncer) SyncAll() (*syncer.SyncStats, error) {
+	c.syncs++
+	c.onSync(c.syncs)
+	return c.MockSyncer.SyncAll()
 }
 
-func TestRunInit(t *testing.T) {
-	// Save the original factory function
+func TestRunWatch(t *testing.T) {
 	originalFactory := createSyncer
 	defer func() {
 		createSyncer = originalFactory
 	}()
 
-	// Create a temporary directory for testing
-	tmpDir, err := os.MkdirTemp("", "difync-test-runInit-")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+	stop := make(chan os.Signal, 1)
+	created := 0
+	counter := &countingSyncer{
+		MockSyncer: &MockSyncer{
+			stats: &syncer.SyncStats{Total: 1, NoAction: 1},
+		},
+		// Interrupt during the second cycle, which must still finish
+		onSync: func(syncs int) {
+			if syncs == 2 {
+				stop <- os.Interrupt
+			}
+		},
+	}
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		created++
+		return counter
 	}
-	defer os.RemoveAll(tmpDir)
+
+	config := &syncer.Config{
+		DifyBaseURL:  "https://test.example.com",
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: "/path/to/dsl",
+		AppMapFile:   "/path/to/app_map.json",
+		Quiet:        true,
+	}
+
+	exitCode, err := runWatch(config, time.Millisecond, stop)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if counter.syncs != 2 {
+		t.Errorf("Expected 2 sync cycles, got %d", counter.syncs)
+	}
+	if created != 1 {
+		t.Errorf("Expected one syncer to be reused across cycles, got %d", created)
+	}
+
+	// A failed login stops the watch before the first cycle
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return &MockSyncer{validateErr: fmt.Errorf("invalid credentials")}
+	}
+	exitCode, err = runWatch(config, time.Millisecond, stop)
+	if err == nil {
+		t.Error("Expected error for failed login")
+	}
+	if exitCode != exitAuthFailure {
+		t.Errorf("Expected exit code %d, got %d", exitAuthFailure, exitCode)
+	}
+}
+
+func TestRunSyncStoppedEarly(t *testing.T) {
+	originalFactory := createSyncer
+	defer func() {
+		createSyncer = originalFactory
+	}()
+
+	// A sync stopped by --fail-fast returns its stats together with the error
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return &MockSyncer{
+			stats: &syncer.SyncStats{Total: 2, Errors: 1},
+			err:   errors.New("stopped after a.yaml (app_id: app-a) failed"),
+		}
+	}
+
+	config := &syncer.Config{
+		DifyBaseURL:  "https://test.example.com",
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: "/path/to/dsl",
+		AppMapFile:   "/path/to/app_map.json",
+		FailFast:     true,
+	}
+
+	var exitCode int
+	var err error
+	output := captureStdout(t, func() {
+		exitCode, err = runSync(config)
+	})
+
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(output, "Sync Summary:") {
+		t.Errorf("Expected the summary to be printed, got %q", output)
+	}
+}
+
+func TestSyncAndReportForbiddenExport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "A"}]}`))
+		case "/console/api/apps/app-a":
+			w.Write([]byte(`{"data": {"id": "app-a", "name": "A", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		case "/console/api/apps/app-a/export":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code": "forbidden", "message": "no permission"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "A.yaml", "app_id": "app-a"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+	config := &syncer.Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: tmpDir,
+		AppMapFile:   appMapPath,
+		Quiet:        true,
+		LogOutput:    io.Discard,
+	}
+
+	// An app that may not be exported fails the sync. Quiet mode prints nothing on stdout,
+	// so the failed app is returned as the error that main prints to stderr.
+	var exitCode int
+	var err error
+	output := captureStdout(t, func() {
+		exitCode, err = syncAndReport(config, syncer.NewSyncer(*config))
+	})
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if err == nil || !strings.Contains(err.Error(), "A.yaml (app_id: app-a)") {
+		t.Errorf("Expected an error naming the failed app, got %v", err)
+	}
+	if output != "" {
+		t.Errorf("Expected no output on stdout in quiet mode, got %q", output)
+	}
+}
+
+func TestSyncAndReportFailedDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "A"}]}`))
+		case "/console/api/apps/app-a":
+			w.Write([]byte(`{"data": {"id": "app-a", "name": "A", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": "bad_request", "message": "export failed"}`))
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "A.yaml", "app_id": "app-a"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+	config := &syncer.Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: tmpDir,
+		AppMapFile:   appMapPath,
+		LogOutput:    io.Discard,
+	}
+
+	// The download fails, and the summary counts it as the error it lists
+	var exitCode int
+	output := captureStdout(t, func() {
+		exitCode, _ = syncAndReport(config, syncer.NewSyncer(*config))
+	})
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	for _, want := range []string{"Downloads: 0", "Errors: 1", "Failed apps:", "A.yaml (app_id: app-a)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestRunSyncQuiet(t *testing.T) {
+	// Save the original factory function
+	originalFactory := createSyncer
+	defer func() {
+		createSyncer = originalFactory
+	}()
+
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return &MockSyncer{
+			stats: &syncer.SyncStats{
+				Total:     2,
+				Downloads: 1,
+				NoAction:  1,
+				StartTime: time.Now().Add(-1 * time.Second),
+				EndTime:   time.Now(),
+			},
+		}
+	}
+
+	config := &syncer.Config{
+		DifyBaseURL:  "https://test.example.com",
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: "/path/to/dsl",
+		AppMapFile:   "/path/to/app_map.json",
+		Quiet:        true,
+	}
+
+	var exitCode int
+	var err error
+	output := captureStdout(t, func() {
+		exitCode, err = runSync(config)
+	})
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if len(output) != 0 {
+		t.Errorf("Expected no output in quiet mode, got %q", output)
+	}
+}
+
+func TestLoadConfigDSLDirectoryFromAppMap(t *testing.T) {
+	// Save the original flag values
+	originalAppMapFile := appMapFile
+	originalDSLDir := dslDir
+	defer func() {
+		appMapFile = originalAppMapFile
+		dslDir = originalDSLDir
+	}()
+
+	t.Setenv("DIFY_BASE_URL", "https://test.example.com")
+	t.Setenv("DIFY_EMAIL", "test@example.com")
+	t.Setenv("DIFY_PASSWORD", "testpassword")
+	t.Setenv("DSL_DIRECTORY", "")
+
+	// The app map is committed next to its DSL files
+	repoDir := t.TempDir()
+	appMapPath := filepath.Join(repoDir, "difync", "app_map.json")
+	if err := os.MkdirAll(filepath.Dir(appMapPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"version": 1, "dsl_directory": "../dsl", "apps": []}`
+	if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appMapFile = &appMapPath
+
+	// Without --dsl-dir, the directory comes from the app map
+	empty := ""
+	dslDir = &empty
+	config, err := loadConfigAndValidate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := filepath.Join(repoDir, "dsl"); config.DSLDirectory != expected {
+		t.Errorf("Expected DSL directory %s from the app map, got %s", expected, config.DSLDirectory)
+	}
+
+	// The flag overrides the app map
+	flagDir := filepath.Join(repoDir, "other")
+	dslDir = &flagDir
+	config, err = loadConfigAndValidate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.DSLDirectory != flagDir {
+		t.Errorf("Expected DSL directory %s from the flag, got %s", flagDir, config.DSLDirectory)
+	}
+}
+
+func TestPrintStatsColor(t *testing.T) {
+	// Save the original flag value
+	originalColorMode := colorMode
+	defer func() {
+		colorMode = originalColorMode
+	}()
+
+	t.Setenv("DIFY_BASE_URL", "https://test.example.com")
+	t.Setenv("DIFY_EMAIL", "test@example.com")
+	t.Setenv("DIFY_PASSWORD", "testpassword")
+
+	stats := &syncer.SyncStats{
+		Total:     3,
+		Downloads: 1,
+		NoAction:  1,
+		Errors:    1,
+	}
+
+	tests := []struct {
+		mode        string
+		expectColor bool
+	}{
+		{mode: "never", expectColor: false},
+		{mode: "always", expectColor: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			mode := tt.mode
+			colorMode = &mode
+
+			config, err := loadConfigAndValidate()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			output := captureStdout(t, func() {
+				printStats(config, stats, time.Second)
+			})
+
+			if hasColor := strings.Contains(output, "\x1b["); hasColor != tt.expectColor {
+				t.Errorf("Expected escape codes: %v, got output %q", tt.expectColor, output)
+			}
+		})
+	}
+
+	// Invalid modes are rejected
+	invalid := "sometimes"
+	colorMode = &invalid
+	if _, err := loadConfigAndValidate(); err == nil {
+		t.Error("Expected error for invalid --color value")
+	}
+}
+
+// MockSyncerWithInit implements both Syncer and has InitializeAppMap method
+type MockSyncerWithInit struct {
+	*MockSyncer
+	appMap  *syncer.AppMap
+	initErr error
+}
+
+// InitializeAppMap mocks the DefaultSyncer.InitializeAppMap method
+func (m *MockSyncerWithInit) InitializeAppMap() (*syncer.AppMap, error) {
+	return m.appMap, m.initErr
+}
+
+func TestRunInit(t *testing.T) {
+	// Save the original factory function
+	originalFactory := createSyncer
+	defer func() {
+		createSyncer = originalFactory
+	}()
+
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-runInit-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
 
 	// Create a DSL directory
 	dslDir := filepath.Join(tmpDir, "dsl")
@@ -424,7 +1358,7 @@ func TestRunInit(t *testing.T) {
 		AppMapFile:   appMapPath,
 	}
 
-	exitCode, err := runInit(config)
+	exitCode, err := runInit(config, nil)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -435,7 +1369,7 @@ func TestRunInit(t *testing.T) {
 	// Test initialization with error
 	mockSyncer.initErr = fmt.Errorf("mock initialization error")
 
-	exitCode, err = runInit(config)
+	exitCode, err = runInit(config, nil)
 	if err == nil {
 		t.Errorf("Expected error, got none")
 	}
@@ -443,12 +1377,72 @@ func TestRunInit(t *testing.T) {
 		t.Errorf("Expected exit code 1, got %d", exitCode)
 	}
 
+	// Test that an empty workspace gets a hint instead of a generic error
+	mockSyncer.initErr = syncer.ErrNoApps
+
+	exitCode, err = runInit(config, nil)
+	if err == nil || !strings.Contains(err.Error(), "no apps found in this Dify workspace at https://test.example.com, check your base URL and account") {
+		t.Errorf("Expected a hint about the base URL and account, got %v", err)
+	}
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+
+	// Test that --exit-zero-on-no-apps treats an empty workspace as success
+	*exitZeroNoApps = true
+	output := captureStdout(t, func() {
+		exitCode, err = runInit(config, nil)
+	})
+	*exitZeroNoApps = false
+	if err != nil {
+		t.Errorf("Expected no error with --exit-zero-on-no-apps, got %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if !strings.Contains(output, "No apps found in this Dify workspace at https://test.example.com") {
+		t.Errorf("Expected an informational message, got %q", output)
+	}
+
+	// Test initialization with authentication failure
+	mockSyncer.initErr = nil
+	mockSyncer.validateErr = fmt.Errorf("%w: invalid credentials", syncer.ErrAuthentication)
+
+	exitCode, err = runInit(config, nil)
+	if err == nil || !errors.Is(err, syncer.ErrAuthentication) {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+	if exitCode != exitAuthFailure {
+		t.Errorf("Expected exit code %d, got %d", exitAuthFailure, exitCode)
+	}
+
+	// Test that --app-id limits init to the given apps
+	mockSyncer.validateErr = nil
+	var gotConfig syncer.Config
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		gotConfig = config
+		return mockSyncer
+	}
+
+	exitCode, err = runInit(config, []string{"--app-id", "app-1", "--app-id", "app-2"})
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected success, got exit code %d, %v", exitCode, err)
+	}
+	if strings.Join(gotConfig.AppIDs, ",") != "app-1,app-2" {
+		t.Errorf("Expected app IDs app-1,app-2, got %v", gotConfig.AppIDs)
+	}
+
+	exitCode, err = runInit(config, []string{"app-1"})
+	if err == nil || exitCode != 1 {
+		t.Errorf("Expected error for a positional argument, got exit code %d, %v", exitCode, err)
+	}
+
 	// Test when syncer is not DefaultSyncer
 	createSyncer = func(config syncer.Config) syncer.Syncer {
 		return &MockSyncer{} // This doesn't implement InitializeAppMap
 	}
 
-	exitCode, err = runInit(config)
+	exitCode, err = runInit(config, nil)
 	if err == nil {
 		t.Errorf("Expected error about failed conversion, got none")
 	}
@@ -457,7 +1451,373 @@ func TestRunInit(t *testing.T) {
 	}
 }
 
+// MockSyncerWithPrune implements Syncer and has FindOrphanedFiles method
+type MockSyncerWithPrune struct {
+	*MockSyncer
+	dir     string
+	orphans []string
+	findErr error
+}
+
+// FindOrphanedFiles mocks the DefaultSyncer.FindOrphanedFiles method
+func (m *MockSyncerWithPrune) FindOrphanedFiles() ([]string, error) {
+	return m.orphans, m.findErr
+}
+
+// RemoveOrphanedFiles mocks the DefaultSyncer.RemoveOrphanedFiles method, removing the files from dir
+func (m *MockSyncerWithPrune) RemoveOrphanedFiles(names []string) (int, error) {
+	removed := 0
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(m.dir, name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// MockSyncerWithDoctor implements Syncer and has CheckFiles method
+type MockSyncerWithDoctor struct {
+	*MockSyncer
+	problems []syncer.FileProblem
+	checkErr error
+}
+
+// CheckFiles mocks the DefaultSyncer.CheckFiles method
+func (m *MockSyncerWithDoctor) CheckFiles() ([]syncer.FileProblem, error) {
+	return m.problems, m.checkErr
+}
+
+func TestRunDoctor(t *testing.T) {
+	// Save the original factory function
+	originalFactory := createSyncer
+	defer func() {
+		createSyncer = originalFactory
+	}()
+
+	mockSyncer := &MockSyncerWithDoctor{MockSyncer: &MockSyncer{}}
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return mockSyncer
+	}
+	config := &syncer.Config{}
+
+	// No problems
+	var exitCode int
+	var err error
+	output := captureStdout(t, func() {
+		exitCode, err = runDoctor(config)
+	})
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d, %v", exitCode, err)
+	}
+	if !strings.Contains(output, "No problems found") {
+		t.Errorf("Expected no problems to be reported, got %q", output)
+	}
+
+	// Problems are listed and make the command fail
+	mockSyncer.problems = []syncer.FileProblem{
+		{Filename: "missing.yaml", AppID: "app-1", Problem: "file is missing"},
+		{Filename: "extra.yaml", Problem: "not in the app map"},
+	}
+	output = captureStdout(t, func() {
+		exitCode, err = runDoctor(config)
+	})
+	if err != nil || exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d, %v", exitCode, err)
+	}
+	for _, want := range []string{"Found 2 problems", "missing.yaml (app_id: app-1): file is missing", "extra.yaml: not in the app map"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, output)
+		}
+	}
+
+	// Errors while checking are returned
+	mockSyncer.checkErr = errors.New("app map not found")
+	exitCode, err = runDoctor(config)
+	if err == nil || exitCode != 1 {
+		t.Errorf("Expected an error, got exit code %d, %v", exitCode, err)
+	}
+}
+
+func TestRunPrune(t *testing.T) {
+	// Save the original factory function, stdin and flag value
+	originalFactory := createSyncer
+	originalStdin := stdin
+	originalAssumeYes := *assumeYes
+	defer func() {
+		createSyncer = originalFactory
+		stdin = originalStdin
+		*assumeYes = originalAssumeYes
+	}()
+
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-runPrune-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	stalePath := filepath.Join(tmpDir, "stale.yaml")
+	writeStale := func() {
+		if err := os.WriteFile(stalePath, []byte("test content"), 0644); err != nil {
+			t.Fatalf("Failed to create stale file: %v", err)
+		}
+	}
+	writeStale()
+
+	mockSyncer := &MockSyncerWithPrune{
+		MockSyncer: &MockSyncer{},
+		dir:        tmpDir,
+		orphans:    []string{"stale.yaml"},
+	}
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return mockSyncer
+	}
+
+	config := &syncer.Config{
+		DSLDirectory: tmpDir,
+		AppMapFile:   filepath.Join(tmpDir, "app_map.json"),
+	}
+
+	// Test dry run only lists files
+	config.DryRun = true
+	exitCode, err := runPrune(config)
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected dry run to succeed, got exit code %d, error %v", exitCode, err)
+	}
+	if _, err := os.Stat(stalePath); err != nil {
+		t.Errorf("Expected stale file to be kept in dry run: %v", err)
+	}
+	config.DryRun = false
+
+	// Test declining the confirmation prompt
+	stdin = strings.NewReader("n\n")
+	exitCode, err = runPrune(config)
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected declined prune to succeed, got exit code %d, error %v", exitCode, err)
+	}
+	if _, err := os.Stat(stalePath); err != nil {
+		t.Errorf("Expected stale file to be kept when declined: %v", err)
+	}
+
+	// Test accepting the confirmation prompt
+	stdin = strings.NewReader("yes\n")
+	exitCode, err = runPrune(config)
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected prune to succeed, got exit code %d, error %v", exitCode, err)
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Error("Expected stale file to be removed")
+	}
+
+	// Test --yes skips the prompt
+	writeStale()
+	stdin = strings.NewReader("")
+	*assumeYes = true
+	exitCode, err = runPrune(config)
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected prune with --yes to succeed, got exit code %d, error %v", exitCode, err)
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Error("Expected stale file to be removed with --yes")
+	}
+
+	// Test quiet mode prints nothing
+	writeStale()
+	config.Quiet = true
+	output := captureStdout(t, func() {
+		exitCode, err = runPrune(config)
+	})
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected quiet prune to succeed, got exit code %d, error %v", exitCode, err)
+	}
+	if output != "" {
+		t.Errorf("Expected no output in quiet mode, got %q", output)
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Error("Expected stale file to be removed in quiet mode")
+	}
+
+	// Test nothing to prune
+	mockSyncer.orphans = nil
+	output = captureStdout(t, func() {
+		exitCode, err = runPrune(config)
+	})
+	if err != nil || exitCode != 0 {
+		t.Errorf("Expected exit code 0 when nothing to prune, got %d, error %v", exitCode, err)
+	}
+	if output != "" {
+		t.Errorf("Expected no output in quiet mode, got %q", output)
+	}
+	config.Quiet = false
+
+	// Test error finding orphans
+	mockSyncer.findErr = fmt.Errorf("mock error")
+	exitCode, err = runPrune(config)
+	if err == nil || exitCode != 1 {
+		t.Errorf("Expected error, got exit code %d, error %v", exitCode, err)
+	}
+
+	// Test syncer without prune support
+	createSyncer = func(config syncer.Config) syncer.Syncer {
+		return &MockSyncer{}
+	}
+	exitCode, err = runPrune(config)
+	if err == nil || exitCode != 1 {
+		t.Errorf("Expected error for unsupported syncer, got exit code %d, error %v", exitCode, err)
+	}
+}
+
 // TestMainFunction tests the main function with various commands
+func TestRunGet(t *testing.T) {
+	var exportQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps/app-1":
+			w.Write([]byte(`{"data": {"id": "app-1", "name": "App 1"}}`))
+		case "/console/api/apps/app-1/export":
+			exportQuery = r.URL.RawQuery
+			w.Write([]byte(`{"data": "name: App 1\n"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	config := &syncer.Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "password",
+		LogOutput:    io.Discard,
+	}
+
+	// Write to a file, with the flag after the app ID
+	outPath := filepath.Join(tmpDir, "app.yaml")
+	var exitCode int
+	var err error
+	captureStdout(t, func() {
+		exitCode, err = runGet(config, []string{"app-1", "--out", outPath})
+	})
+	if err != nil || exitCode != 0 {
+		t.Fatalf("Expected success, got exit code %d: %v", exitCode, err)
+	}
+	if data, err := os.ReadFile(outPath); err != nil || string(data) != "name: App 1\n" {
+		t.Errorf("Unexpected file content %q: %v", data, err)
+	}
+	if exportQuery != "include_secret=false" {
+		t.Errorf("Expected secrets to be excluded, got query %q", exportQuery)
+	}
+
+	// Write to stdout with secrets included
+	output := captureStdout(t, func() {
+		exitCode, err = runGet(config, []string{"--include-secret", "app-1", "--out", "-"})
+	})
+	if err != nil || exitCode != 0 {
+		t.Fatalf("Expected success, got exit code %d: %v", exitCode, err)
+	}
+	if output != "name: App 1\n" {
+		t.Errorf("Expected only the DSL on stdout, got %q", output)
+	}
+	if exportQuery != "include_secret=true" {
+		t.Errorf("Expected secrets to be included, got query %q", exportQuery)
+	}
+
+	// Errors
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing app", []string{"missing-app", "--out", "-"}, "does not exist"},
+		{"missing app ID", []string{"--out", "-"}, "usage"},
+		{"missing out", []string{"app-1"}, "--out is required"},
+		{"extra arguments", []string{"app-1", "app-2", "--out", "-"}, "unexpected arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode, err := runGet(config, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+			if exitCode == 0 {
+				t.Error("Expected a non-zero exit code")
+			}
+		})
+	}
+}
+
+func TestRunCat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps/app-1":
+			w.Write([]byte(`{"data": {"id": "app-1", "name": "App 1"}}`))
+		case "/console/api/apps/app-1/export":
+			w.Write([]byte(`{"data": "name: App 1 (remote)\n"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	localPath := filepath.Join(dslDir, "app_1.yaml")
+	if err := os.WriteFile(localPath, []byte("name: App 1 (local)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "app_1.yaml", "app_id": "app-1"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &syncer.Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "password",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+		LogOutput:    io.Discard,
+	}
+
+	// The app is found by filename or by app ID, and only the remote DSL is printed
+	for _, ref := range []string{"app_1.yaml", "app-1"} {
+		var exitCode int
+		var err error
+		output := captureStdout(t, func() {
+			exitCode, err = runCat(config, []string{ref})
+		})
+		if err != nil || exitCode != 0 {
+			t.Fatalf("Expected success for %s, got exit code %d: %v", ref, exitCode, err)
+		}
+		if output != "name: App 1 (remote)\n" {
+			t.Errorf("Expected the remote DSL for %s, got %q", ref, output)
+		}
+	}
+
+	// The local file is left alone
+	if data, err := os.ReadFile(localPath); err != nil || string(data) != "name: App 1 (local)\n" {
+		t.Errorf("Expected the local file to be unchanged, got %q: %v", data, err)
+	}
+
+	// Errors
+	if _, err := runCat(config, nil); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+	if _, err := runCat(config, []string{"missing.yaml"}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected an error for an unknown app, got %v", err)
+	}
+}
+
 func TestMainFunction(t *testing.T) {
 	// Save original functions and os.Args
 	origArgs := os.Args
@@ -563,6 +1923,30 @@ func TestMainFunction(t *testing.T) {
 			expectedCode:  0,
 			shouldRecover: false,
 		},
+		{
+			name: "authentication_failure",
+			args: []string{"difync"},
+			envVars: map[string]string{
+				"DIFY_BASE_URL": "https://test.example.com",
+				"DIFY_EMAIL":    "test@example.com",
+				"DIFY_PASSWORD": "wrongpassword",
+				"DSL_DIRECTORY": dslDir,
+				"APP_MAP_FILE":  appMapPath,
+			},
+			mockSyncer: &MockSyncer{
+				validateErr: fmt.Errorf("%w: invalid credentials", syncer.ErrAuthentication),
+			},
+			expectedCode:  exitAuthFailure,
+			shouldRecover: false,
+		},
+		{
+			name:          "version",
+			args:          []string{"difync", "version"},
+			envVars:       map[string]string{},
+			mockSyncer:    nil, // Won't be used since no configuration is needed
+			expectedCode:  0,
+			shouldRecover: false,
+		},
 		{
 			name: "invalid_config",
 			args: []string{"difync"},
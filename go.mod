@@ -2,4 +2,8 @@ module github.com/pepabo/difync
 
 go 1.24
 
-require github.com/joho/godotenv v1.5.1 // indirect
+require (
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/time v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
+)
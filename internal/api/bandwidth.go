@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// SetMaxBandwidth limits how fast DSL downloads are read, in bytes per second.
+// Like the request rate limit, the limit is shared by all downloads made through
+// the client. A value of 0 or less disables throttling.
+func (c *Client) SetMaxBandwidth(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		c.bandwidth = nil
+		return
+	}
+	c.bandwidth = rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond))
+}
+
+// throttle wraps a download body so it is read no faster than the bandwidth limit
+func (c *Client) throttle(ctx context.Context, r io.Reader) io.Reader {
+	if c.bandwidth == nil {
+		return r
+	}
+	return &rateLimitedReader{ctx: ctx, r: r, limiter: c.bandwidth}
+}
+
+// rateLimitedReader is an io.Reader that takes a token from a token bucket for every byte read
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Never read more than the bucket can hold, so WaitN can succeed
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
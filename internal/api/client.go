@@ -3,18 +3,75 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // Client represents a Dify API client
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
-	token      string // Changed token to private field
+	token      string // Token obtained by Login, or a static token when no TokenProvider is set
+
+	// TokenProvider supplies the access token for each request. Login sets it to a password login;
+	// set it directly to use another flow, such as SSO with refresh tokens.
+	TokenProvider TokenProvider
+
+	// APIPathPrefix is prepended to every endpoint path (e.g. "/dify" for a reverse-proxied Dify)
+	APIPathPrefix string
+
+	// ExportPath is appended to /console/api/apps/{id} to export a DSL.
+	// Defaults to DefaultExportPath; older Dify versions use LegacyExportPath.
+	ExportPath string
+
+	// ExtraHeaders are added to every request, e.g. an API key for a gateway in front of Dify
+	ExtraHeaders map[string]string
+
+	// UserAgent is sent with every request (default: DefaultUserAgent)
+	UserAgent string
+
+	// BasicAuthUser and BasicAuthPassword are HTTP basic auth credentials for a gateway in front of Dify.
+	// They are sent in the Proxy-Authorization header because Authorization carries the Dify token.
+	BasicAuthUser     string
+	BasicAuthPassword string
+
+	// Credentials used at login, kept to log in again when the token expires
+	email    string
+	password string
+
+	// Refresh token and expiry of the token obtained by Login, if the login response included them
+	refreshToken string
+	tokenExpiry  time.Time
+
+	// ExportPollInterval is the initial wait between polls of an asynchronous export job,
+	// doubled after each poll up to maxExportPollInterval (default: 1s)
+	ExportPollInterval time.Duration
+	// ExportTimeout limits how long to wait for an asynchronous export job (default: 5m)
+	ExportTimeout time.Duration
+
+	// TraceOutput receives each request and response with credentials redacted; nil disables tracing
+	TraceOutput io.Writer
+
+	// ErrorDumpDir receives a file with the full request and response of every API error; "" disables dumps
+	ErrorDumpDir string
+
+	// dialer connects the transport created by NewClient
+	dialer *net.Dialer
+
+	// limiter paces outbound requests; nil means no limit
+	limiter *rate.Limiter
+	// bandwidth limits the bytes per second read from DSL downloads; nil means no limit
+	bandwidth *rate.Limiter
 }
 
 // AppInfo represents the basic information about a Dify application
@@ -22,6 +79,19 @@ type AppInfo struct {
 	ID        string      `json:"id"`
 	Name      string      `json:"name"`
 	UpdatedAt interface{} `json:"updated_at"` // Changed to interface{} to handle both string and numeric types
+	// Mode is the app type, e.g. chat, workflow, advanced-chat, agent-chat or completion
+	Mode string `json:"mode"`
+}
+
+// appMode reads the app type from an app object, which older Dify versions call app_type
+func appMode(appData map[string]interface{}) string {
+	if mode, ok := appData["mode"].(string); ok && mode != "" {
+		return mode
+	}
+	if appType, ok := appData["app_type"].(string); ok {
+		return appType
+	}
+	return ""
 }
 
 // AppPublishInfo represents the publish information about a Dify application
@@ -36,20 +106,138 @@ type LoginResponse struct {
 	Status string `json:"status"`
 	Data   struct {
 		AccessToken string `json:"access_token"`
+		// RefreshToken and ExpiresIn (seconds) are only returned by some Dify versions
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
 	} `json:"data"`
 }
 
+// DefaultUserAgent identifies requests of clients without a UserAgent
+const DefaultUserAgent = "difync"
+
 // NewClient creates a new Dify API client
 func NewClient(baseURL string) *Client {
+	dialer := newDialer()
 	return &Client{
 		BaseURL:    baseURL,
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: &http.Client{Timeout: 30 * time.Second, Transport: newTransport(dialer)},
+		dialer:     dialer,
+	}
+}
+
+// url builds the full URL for an API endpoint path such as "/console/api/apps"
+func (c *Client) url(path string) string {
+	prefix := strings.Trim(c.APIPathPrefix, "/")
+	if prefix != "" {
+		prefix = "/" + prefix
+	}
+	return strings.TrimRight(c.BaseURL, "/") + prefix + path
+}
+
+// Known export endpoint paths, relative to /console/api/apps/{id}
+const (
+	// DefaultExportPath is the export endpoint used by current Dify versions
+	DefaultExportPath = "/export?include_secret=false"
+	// LegacyExportPath is the export endpoint used by older Dify versions
+	LegacyExportPath = "/dsl"
+	// SecretExportPath is the current export endpoint with secrets such as API keys included
+	SecretExportPath = "/export?include_secret=true"
+)
+
+// exportURL returns the URL used to export the DSL of the given app
+func (c *Client) exportURL(appID string) string {
+	exportPath := c.ExportPath
+	if exportPath == "" {
+		exportPath = DefaultExportPath
+	}
+	if !strings.HasPrefix(exportPath, "/") {
+		exportPath = "/" + exportPath
+	}
+	return c.url(fmt.Sprintf("/console/api/apps/%s%s", appID, exportPath))
+}
+
+// SetRateLimit limits outbound requests to the given number per second.
+// The limiter is shared by all requests made through the client, so concurrent
+// callers collectively respect the rate. A value of 0 or less disables limiting.
+func (c *Client) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+		return
+	}
+	c.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+}
+
+// newRequest creates a request with the headers configured for every request
+func (c *Client) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	for key, value := range c.ExtraHeaders {
+		req.Header.Set(key, value)
+	}
+
+	if c.BasicAuthUser != "" || c.BasicAuthPassword != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(c.BasicAuthUser + ":" + c.BasicAuthPassword))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	return req, nil
+}
+
+// send executes a request, waiting for the rate limiter first if one is set.
+// With TraceOutput set, the request and response are written to it.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, fmt.Errorf("rate limiter wait failed: %w", err)
+		}
+	}
+
+	if c.TraceOutput == nil {
+		return c.HTTPClient.Do(req)
 	}
+
+	c.traceRequest(req)
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(c.TraceOutput, "< error: %v\n", err)
+		return nil, err
+	}
+	c.traceResponse(resp, time.Since(start))
+	return resp, nil
 }
 
-// Login authenticates with Dify API using email and password
+// Login authenticates with Dify API using email and password.
+// On success, the password login becomes the client's TokenProvider and is used
+// to log in again when the token expires.
 func (c *Client) Login(email, password string) error {
-	url := fmt.Sprintf("%s/console/api/login", c.BaseURL)
+	c.email = email
+	c.password = password
+
+	provider := NewCachedTokenProvider(func(ctx context.Context) (string, time.Time, error) {
+		token, err := c.login(ctx, email, password)
+		return token, c.tokenExpiry, err
+	})
+	if _, err := provider.Token(context.Background()); err != nil {
+		return err
+	}
+
+	c.TokenProvider = provider
+	return nil
+}
+
+// login requests an access token with email and password and stores it in the client
+func (c *Client) login(ctx context.Context, email, password string) (string, error) {
+	url := c.url("/console/api/login")
 
 	// Create login payload
 	loginData := map[string]string{
@@ -59,62 +247,162 @@ func (c *Client) Login(email, password string) error {
 
 	payload, err := json.Marshal(loginData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal login data: %w", err)
+		return "", fmt.Errorf("failed to marshal login data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	req, err := c.newRequest("POST", url, bytes.NewBuffer(payload))
 	if err != nil {
-		return fmt.Errorf("failed to create login request: %w", err)
+		return "", fmt.Errorf("failed to create login request: %w", err)
 	}
 
+	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
-		return fmt.Errorf("failed to execute login request: %w", err)
+		return "", fmt.Errorf("failed to execute login request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("login API returned error: status=%d, body=%s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("login API returned error: status=%d, body=%s", resp.StatusCode, string(body))
 	}
 
 	var loginResp LoginResponse
 	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
-		return fmt.Errorf("failed to decode login response: %w", err)
+		return "", fmt.Errorf("failed to decode login response: %w", err)
 	}
 
-	// Store the access token
+	// Store the access token and, if known, when it expires
 	c.token = loginResp.Data.AccessToken
+	c.refreshToken = loginResp.Data.RefreshToken
+	c.tokenExpiry = time.Time{}
+	if loginResp.Data.ExpiresIn > 0 {
+		c.tokenExpiry = time.Now().Add(time.Duration(loginResp.Data.ExpiresIn) * time.Second)
+	}
+	return c.token, nil
+}
+
+// RefreshToken returns the refresh token obtained by Login.
+// It is empty if the login response did not include one.
+func (c *Client) RefreshToken() string {
+	return c.refreshToken
+}
+
+// TokenExpiry returns when the token obtained by Login expires.
+// It is the zero time if the login response did not include a lifetime.
+func (c *Client) TokenExpiry() time.Time {
+	return c.tokenExpiry
+}
+
+// Logout invalidates the access token obtained by Login and clears the credentials.
+// It is a no-op when the client was not authenticated with a password login.
+// A missing logout endpoint (404) is not treated as an error.
+func (c *Client) Logout() error {
+	if c.token == "" || c.email == "" {
+		return nil
+	}
+
+	url := c.url("/console/api/logout")
+
+	req, err := c.newRequest("POST", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create logout request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+
+	// Clear the in-memory credentials regardless of the outcome
+	c.token = ""
+	c.TokenProvider = nil
+	c.refreshToken = ""
+	c.tokenExpiry = time.Time{}
+	c.email = ""
+	c.password = ""
+
+	resp, err := c.send(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute logout request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return c.apiError(resp, url)
+	}
+
 	return nil
 }
 
+// authenticated reports whether the client has a token or a way to obtain one
+func (c *Client) authenticated() bool {
+	return c.token != "" || c.TokenProvider != nil
+}
+
+// accessToken returns the token to send, asking the TokenProvider if one is set
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	if c.TokenProvider == nil {
+		return c.token, nil
+	}
+	return c.TokenProvider.Token(ctx)
+}
+
+// doAuthenticated executes a request with the access token.
+// If the token has expired (401) and the TokenProvider can invalidate it, a new token
+// is obtained once and the request retried. When that fails, the original 401 response is returned.
+func (c *Client) doAuthenticated(req *http.Request) (*http.Response, error) {
+	token, err := c.accessToken(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidator, ok := c.TokenProvider.(TokenInvalidator)
+	if resp.StatusCode != http.StatusUnauthorized || !ok {
+		return resp, nil
+	}
+
+	invalidator.Invalidate()
+	token, err = c.accessToken(req.Context())
+	if err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	// Retry only once with the new token to avoid looping on persistent 401s
+	retry := req.Clone(req.Context())
+	retry.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return c.send(retry)
+}
+
 // GetAppInfo fetches application information from Dify
 func (c *Client) GetAppInfo(appID string) (*AppInfo, error) {
-	if c.token == "" {
+	if !c.authenticated() {
 		return nil, fmt.Errorf("not authenticated, call Login() first")
 	}
 
-	url := fmt.Sprintf("%s/console/api/apps/%s", c.BaseURL, appID)
+	url := c.url(fmt.Sprintf("/console/api/apps/%s", appID))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doAuthenticated(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned error: status=%d, body=%s", resp.StatusCode, string(body))
+		return nil, c.apiError(resp, url)
 	}
 
 	// Save response body
@@ -123,9 +411,6 @@ func (c *Client) GetAppInfo(appID string) (*AppInfo, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Debug output
-	fmt.Printf("Debug - Raw API Response: %s\n", string(body))
-
 	// Decode JSON directly to map to avoid mapping issues
 	var rawData map[string]interface{}
 	if err := json.Unmarshal(body, &rawData); err != nil {
@@ -145,14 +430,11 @@ func (c *Client) GetAppInfo(appID string) (*AppInfo, error) {
 			if name, ok := appData["name"].(string); ok {
 				appInfo.Name = name
 			}
+			appInfo.Mode = appMode(appData)
 			// Get and set updated_at directly
 			if updatedAt, exists := appData["updated_at"]; exists {
 				appInfo.UpdatedAt = updatedAt
-				fmt.Printf("Debug - Found updated_at in data: %v (type: %T)\n", updatedAt, updatedAt)
-			} else {
-				fmt.Printf("Debug - updated_at field not found in data\n")
 			}
-			fmt.Printf("Debug - Constructed AppInfo from data: %+v\n", appInfo)
 			return appInfo, nil
 		}
 	}
@@ -167,44 +449,50 @@ func (c *Client) GetAppInfo(appID string) (*AppInfo, error) {
 	if name, ok := rawData["name"].(string); ok {
 		appInfo.Name = name
 	}
+	appInfo.Mode = appMode(rawData)
 
 	// Get and set updated_at directly from top-level
 	if updatedAt, exists := rawData["updated_at"]; exists {
 		appInfo.UpdatedAt = updatedAt
-		fmt.Printf("Debug - Found updated_at in raw response: %v (type: %T)\n", updatedAt, updatedAt)
-	} else {
-		fmt.Printf("Debug - updated_at field not found in response\n")
 	}
 
-	fmt.Printf("Debug - Constructed AppInfo: %+v\n", appInfo)
 	return appInfo, nil
 }
 
+// FindApp fetches application information from Dify like GetAppInfo, but reports a
+// missing app by returning nil without an error. It answers both DoesDSLExist and
+// GetAppInfo with a single request.
+func (c *Client) FindApp(appID string) (*AppInfo, error) {
+	appInfo, err := c.GetAppInfo(appID)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return appInfo, err
+}
+
 // GetAppPublish fetches application publish information from Dify
 func (c *Client) GetAppPublish(appID string) (*AppPublishInfo, error) {
-	if c.token == "" {
+	if !c.authenticated() {
 		return nil, fmt.Errorf("not authenticated, call Login() first")
 	}
 
-	url := fmt.Sprintf("%s/console/api/apps/%s/workflows/publish", c.BaseURL, appID)
+	url := c.url(fmt.Sprintf("/console/api/apps/%s/workflows/publish", appID))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doAuthenticated(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned error: status=%d, body=%s", resp.StatusCode, string(body))
+		return nil, c.apiError(resp, url)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -212,8 +500,6 @@ func (c *Client) GetAppPublish(appID string) (*AppPublishInfo, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	fmt.Printf("Debug - GetAppPublish Raw API Response: %s\n", string(body))
-
 	var rawData map[string]interface{}
 	if err := json.Unmarshal(body, &rawData); err != nil {
 		return nil, fmt.Errorf("failed to decode JSON to map: %w", err)
@@ -230,66 +516,118 @@ func (c *Client) GetAppPublish(appID string) (*AppPublishInfo, error) {
 		appPublishInfo.UpdatedAt = updatedAt
 	}
 
-	fmt.Printf("Debug - Constructed AppPublishInfo: %+v\n", appPublishInfo)
 	return appPublishInfo, nil
 }
 
 // GetDSL fetches the DSL for a specific app from Dify
 func (c *Client) GetDSL(appID string) ([]byte, error) {
-	if c.token == "" {
-		return nil, fmt.Errorf("not authenticated, call Login() first")
-	}
+	return c.exportDSL(appID, c.exportURL(appID))
+}
 
-	url := fmt.Sprintf("%s/console/api/apps/%s/export?include_secret=false", c.BaseURL, appID)
+// GetPublishedDSL fetches the DSL of a published workflow version of an app,
+// identified by the workflow ID returned by GetAppPublish
+func (c *Client) GetPublishedDSL(appID, workflowID string) ([]byte, error) {
+	url := c.exportURL(appID)
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	return c.exportDSL(appID, url+separator+"workflow_id="+neturl.QueryEscape(workflowID))
+}
 
-	fmt.Printf("Debug - Using export URL: %s\n", url)
+// exportDSL fetches a DSL from an export URL of an app
+func (c *Client) exportDSL(appID, url string) ([]byte, error) {
+	if !c.authenticated() {
+		return nil, fmt.Errorf("not authenticated, call Login() first")
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
-
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doAuthenticated(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	// Newer Dify exports large apps asynchronously and returns a job to poll
+	if resp.StatusCode == http.StatusAccepted {
+		jobID, err := decodeExportJobID(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return c.waitForExportJob(appID, jobID)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned error: status=%d, url=%s, body=%s", resp.StatusCode, url, string(body))
+		return nil, c.apiError(resp, url)
 	}
 
+	return decodeDSLResponse(c.throttle(req.Context(), resp.Body))
+}
+
+// decodeDSLResponse decodes the DSL from an export response body
+func decodeDSLResponse(body io.Reader) ([]byte, error) {
 	var result struct {
-		Data string `json:"data"`
+		Data json.RawMessage `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return []byte(result.Data), nil
+	dsl, err := decodeDSLData(result.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(dsl) == "" {
+		return nil, ErrEmptyDSL
+	}
+
+	return []byte(dsl), nil
+}
+
+// decodeDSLData extracts the DSL from the export response's data field,
+// which is either the DSL string itself or an object with a yaml_content field
+func decodeDSLData(data json.RawMessage) (string, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return "", nil
+	}
+
+	var dsl string
+	if err := json.Unmarshal(data, &dsl); err == nil {
+		return dsl, nil
+	}
+
+	var wrapper struct {
+		YAMLContent string `json:"yaml_content"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return "", fmt.Errorf("failed to decode DSL data: %w", err)
+	}
+
+	return wrapper.YAMLContent, nil
 }
 
 // DoesDSLExist checks if a DSL exists in Dify for the given app ID
 func (c *Client) DoesDSLExist(appID string) (bool, error) {
-	if c.token == "" {
+	if !c.authenticated() {
 		return false, fmt.Errorf("not authenticated, call Login() first")
 	}
 
-	url := fmt.Sprintf("%s/console/api/apps/%s", c.BaseURL, appID)
+	url := c.url(fmt.Sprintf("/console/api/apps/%s", appID))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doAuthenticated(req)
 	if err != nil {
 		return false, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -302,49 +640,36 @@ func (c *Client) DoesDSLExist(appID string) (bool, error) {
 
 	// If status is not 200 or 404, there was an error
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("API returned error: status=%d, url=%s, body=%s", resp.StatusCode, url, string(body))
+		return false, c.apiError(resp, url)
 	}
 
 	// App exists
 	return true, nil
 }
 
-// Helper function for min
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // GetAppList fetches all applications from Dify
 func (c *Client) GetAppList() ([]AppInfo, error) {
-	if c.token == "" {
+	if !c.authenticated() {
 		return nil, fmt.Errorf("not authenticated, call Login() first")
 	}
 
-	url := fmt.Sprintf("%s/console/api/apps", c.BaseURL)
-
-	fmt.Printf("Debug - Using app list URL: %s\n", url)
+	url := c.url("/console/api/apps")
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doAuthenticated(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned error: status=%d, url=%s, body=%s", resp.StatusCode, url, string(body))
+		return nil, c.apiError(resp, url)
 	}
 
 	// Save response body
@@ -353,24 +678,9 @@ func (c *Client) GetAppList() ([]AppInfo, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Debug output
-	fmt.Printf("Debug - GetAppList Raw API Response: %s\n", string(body))
-
-	// New implementation: use map for more flexible parsing
-	var rawData map[string]interface{}
-	if err := json.Unmarshal(body, &rawData); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON to map: %w", err)
-	}
-
-	// Get data array
-	dataInterface, hasData := rawData["data"]
-	if !hasData {
-		return nil, fmt.Errorf("API response does not contain 'data' field")
-	}
-
-	dataArray, isArray := dataInterface.([]interface{})
-	if !isArray {
-		return nil, fmt.Errorf("API response 'data' is not an array")
+	dataArray, err := appListItems(body)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create app info slice
@@ -393,6 +703,7 @@ func (c *Client) GetAppList() ([]AppInfo, error) {
 		if name, ok := appData["name"].(string); ok {
 			app.Name = name
 		}
+		app.Mode = appMode(appData)
 
 		// Get updated_at directly
 		if updatedAt, exists := appData["updated_at"]; exists {
@@ -402,6 +713,31 @@ func (c *Client) GetAppList() ([]AppInfo, error) {
 		apps = append(apps, app)
 	}
 
-	fmt.Printf("Debug - Parsed %d apps from response\n", len(apps))
 	return apps, nil
 }
+
+// appListItems returns the apps of an app list response. Most deployments wrap them
+// in a "data" field, some return a bare JSON array.
+func appListItems(body []byte) ([]interface{}, error) {
+	var rawData interface{}
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	switch data := rawData.(type) {
+	case map[string]interface{}:
+		dataInterface, hasData := data["data"]
+		if !hasData {
+			return nil, fmt.Errorf("API response does not contain 'data' field")
+		}
+		dataArray, isArray := dataInterface.([]interface{})
+		if !isArray {
+			return nil, fmt.Errorf("API response 'data' is not an array")
+		}
+		return dataArray, nil
+	case []interface{}:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("API response is neither an object nor an array")
+	}
+}
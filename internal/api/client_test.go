@@ -1,9 +1,12 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -73,6 +76,41 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLoginTokenExpiry(t *testing.T) {
+	response := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	// A login response with a lifetime and refresh token
+	response = `{"status": "success", "data": {"access_token": "test-token", "refresh_token": "test-refresh", "expires_in": 3600}}`
+	client := NewClient(server.URL)
+	before := time.Now()
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expiry := client.TokenExpiry()
+	if expiry.Before(before.Add(time.Hour)) || expiry.After(time.Now().Add(time.Hour)) {
+		t.Errorf("Expected expiry about an hour from now, got %v", expiry)
+	}
+	if client.RefreshToken() != "test-refresh" {
+		t.Errorf("Expected refresh token 'test-refresh', got %q", client.RefreshToken())
+	}
+
+	// Without expires_in the expiry is unknown
+	response = `{"status": "success", "data": {"access_token": "test-token"}}`
+	client = NewClient(server.URL)
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !client.TokenExpiry().IsZero() || client.RefreshToken() != "" {
+		t.Errorf("Expected no expiry or refresh token, got %v and %q", client.TokenExpiry(), client.RefreshToken())
+	}
+}
+
 func TestLoginErrors(t *testing.T) {
 	// Test HTTP client error
 	client := NewClient("invalid-url")
@@ -272,6 +310,165 @@ func TestGetDSL(t *testing.T) {
 	}
 }
 
+func TestGetPublishedDSL(t *testing.T) {
+	tests := []struct {
+		name        string
+		exportPath  string
+		expectedRaw string
+	}{
+		{"default export path", "", "include_secret=false&workflow_id=workflow-id-1"},
+		{"legacy export path", LegacyExportPath, "workflow_id=workflow-id-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRaw string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRaw = r.URL.RawQuery
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"data": "name: Published App"}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.token = "test-token"
+			client.ExportPath = tt.exportPath
+
+			dsl, err := client.GetPublishedDSL("test-app-id", "workflow-id-1")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(dsl) != "name: Published App" {
+				t.Errorf("Expected the published DSL, got %q", dsl)
+			}
+			if gotRaw != tt.expectedRaw {
+				t.Errorf("Expected query %q, got %q", tt.expectedRaw, gotRaw)
+			}
+		})
+	}
+}
+
+func TestGetDSLResponseShapes(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "string data",
+			body:     `{"data": "name: Test App"}`,
+			expected: "name: Test App",
+		},
+		{
+			name:     "nested yaml_content",
+			body:     `{"data": {"yaml_content": "name: Nested App"}}`,
+			expected: "name: Nested App",
+		},
+		{
+			name:        "empty string data",
+			body:        `{"data": ""}`,
+			expectedErr: ErrEmptyDSL,
+		},
+		{
+			name:        "missing data",
+			body:        `{}`,
+			expectedErr: ErrEmptyDSL,
+		},
+		{
+			name:        "object without yaml_content",
+			body:        `{"data": {"other": "value"}}`,
+			expectedErr: ErrEmptyDSL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.token = "test-token" // Set token directly for testing
+
+			dsl, err := client.GetDSL("test-app-id")
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(dsl) != tt.expected {
+				t.Errorf("Expected DSL to be '%s', got '%s'", tt.expected, string(dsl))
+			}
+		})
+	}
+}
+
+func TestGetDSLAsyncExportJob(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/apps/test-app-id/export":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"data": {"job_id": "job-1"}}`))
+		case "/console/api/apps/test-app-id/export/job-1":
+			polls++
+			if polls < 2 {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(`{"status": "processing"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": "name: Large App"}`))
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token" // Set token directly for testing
+	client.ExportPollInterval = time.Millisecond
+
+	dsl, err := client.GetDSL("test-app-id")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(dsl) != "name: Large App" {
+		t.Errorf("Expected DSL 'name: Large App', got '%s'", string(dsl))
+	}
+	if polls != 2 {
+		t.Errorf("Expected 2 polls, got %d", polls)
+	}
+}
+
+func TestGetDSLAsyncExportJobTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"task_id": "job-1"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token" // Set token directly for testing
+	client.ExportPollInterval = time.Millisecond
+	client.ExportTimeout = 20 * time.Millisecond
+
+	if _, err := client.GetDSL("test-app-id"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
+
 func TestGetDSLErrors(t *testing.T) {
 	// Test not authenticated error
 	client := NewClient("https://api.example.com")
@@ -360,6 +557,46 @@ func TestDoesDSLExist(t *testing.T) {
 	}
 }
 
+func TestFindApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/apps/existing-app":
+			w.Write([]byte(`{"id": "existing-app", "name": "Existing App", "updated_at": "2023-01-01T12:00:00Z"}`))
+		case "/console/api/apps/deleted-app":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token"
+
+	// An existing app is returned with its info
+	app, err := client.FindApp("existing-app")
+	if err != nil {
+		t.Fatalf("Expected no error for existing app, got %v", err)
+	}
+	if app == nil || app.Name != "Existing App" || app.UpdatedAt != "2023-01-01T12:00:00Z" {
+		t.Errorf("Expected the existing app's info, got %+v", app)
+	}
+
+	// A deleted app is nil without an error
+	app, err = client.FindApp("deleted-app")
+	if err != nil {
+		t.Fatalf("Expected no error for deleted app, got %v", err)
+	}
+	if app != nil {
+		t.Errorf("Expected nil for deleted app, got %+v", app)
+	}
+
+	// Other errors are returned
+	if _, err := client.FindApp("error-app"); err == nil {
+		t.Error("Expected error for server error")
+	}
+}
+
 func TestDoesDSLExistErrors(t *testing.T) {
 	// Test not authenticated error
 	client := NewClient("https://api.example.com")
@@ -405,11 +642,13 @@ func TestGetAppList(t *testing.T) {
 				{
 					"id": "app-id-1",
 					"name": "App 1",
+					"mode": "workflow",
 					"updated_at": "2023-01-01T12:00:00Z"
 				},
 				{
 					"id": "app-id-2",
 					"name": "App 2",
+					"app_type": "chat",
 					"updated_at": "2023-01-02T12:00:00Z"
 				}
 			]
@@ -463,27 +702,610 @@ func TestGetAppList(t *testing.T) {
 		t.Errorf("Expected second app UpdatedAt to be string type with value %v, got %T: %v",
 			expectedTime2, apps[1].UpdatedAt, apps[1].UpdatedAt)
 	}
+
+	// Mode is read from mode, or from app_type on older Dify versions
+	if apps[0].Mode != "workflow" {
+		t.Errorf("Expected first app mode to be workflow, got %q", apps[0].Mode)
+	}
+	if apps[1].Mode != "chat" {
+		t.Errorf("Expected second app mode to be chat, got %q", apps[1].Mode)
+	}
+}
+
+func TestGetAppListBareArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Some deployments return the apps without a "data" wrapper
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[
+			{"id": "app-id-1", "name": "App 1", "mode": "workflow", "updated_at": "2023-01-01T12:00:00Z"},
+			{"id": "app-id-2", "name": "App 2", "mode": "chat", "updated_at": "2023-01-02T12:00:00Z"}
+		]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token"
+
+	apps, err := client.GetAppList()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(apps) != 2 {
+		t.Fatalf("Expected 2 apps, got %d", len(apps))
+	}
+	if apps[0].ID != "app-id-1" || apps[0].Name != "App 1" || apps[0].Mode != "workflow" {
+		t.Errorf("Expected first app to be App 1, got %+v", apps[0])
+	}
+	if apps[1].ID != "app-id-2" || apps[1].Name != "App 2" || apps[1].UpdatedAt != "2023-01-02T12:00:00Z" {
+		t.Errorf("Expected second app to be App 2, got %+v", apps[1])
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/apps/unauthorized-app", "/console/api/apps/unauthorized-app/export":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Unauthorized"}`))
+		case "/console/api/apps/missing-app", "/console/api/apps/missing-app/export":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Not found"}`))
+		case "/console/api/apps":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Unauthorized"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token" // Set token directly for testing
+
+	// Test 401 responses
+	_, err := client.GetAppInfo("unauthorized-app")
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected GetAppInfo to return unauthorized error, got %v", err)
+	}
+
+	_, err = client.GetDSL("unauthorized-app")
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected GetDSL to return unauthorized error, got %v", err)
+	}
+
+	_, err = client.GetAppList()
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected GetAppList to return unauthorized error, got %v", err)
+	}
+
+	_, err = client.DoesDSLExist("unauthorized-app")
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected DoesDSLExist to return unauthorized error, got %v", err)
+	}
+	if IsNotFound(err) {
+		t.Error("Expected unauthorized error not to be reported as not found")
+	}
+
+	// Test 404 responses
+	_, err = client.GetAppInfo("missing-app")
+	if !IsNotFound(err) {
+		t.Errorf("Expected GetAppInfo to return not found error, got %v", err)
+	}
+
+	_, err = client.GetDSL("missing-app")
+	if !IsNotFound(err) {
+		t.Errorf("Expected GetDSL to return not found error, got %v", err)
+	}
+
+	// Test error details
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected error to be *APIError, got %T", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected StatusCode to be 404, got %d", apiErr.StatusCode)
+	}
+	if !strings.HasSuffix(apiErr.URL, "/console/api/apps/missing-app/export?include_secret=false") {
+		t.Errorf("Expected URL to point to the export endpoint, got %s", apiErr.URL)
+	}
+	if apiErr.Body != `{"error": "Not found"}` {
+		t.Errorf("Expected Body to be the response body, got %s", apiErr.Body)
+	}
+	if !strings.Contains(apiErr.Error(), "status=404") {
+		t.Errorf("Expected error message to contain status, got %s", apiErr.Error())
+	}
+
+	// Test non-API errors
+	if IsNotFound(fmt.Errorf("plain error")) || IsUnauthorized(nil) {
+		t.Error("Expected non-API errors not to match status helpers")
+	}
+}
+
+func TestReloginOnUnauthorized(t *testing.T) {
+	loginCount := 0
+	loginFails := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/login":
+			loginCount++
+			if loginFails {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(fmt.Sprintf(`{"status": "success", "data": {"access_token": "token-%d"}}`, loginCount)))
+		case "/console/api/apps":
+			// Only the token issued by the second login is accepted
+			if r.Header.Get("Authorization") != "Bearer token-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": "Token expired"}`))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "app-id-1", "name": "App 1"}]}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error on login, got %v", err)
+	}
+
+	// Test transparent re-login and retry
+	apps, err := client.GetAppList()
+	if err != nil {
+		t.Fatalf("Expected request to succeed after re-login, got %v", err)
+	}
+	if len(apps) != 1 {
+		t.Errorf("Expected 1 app, got %d", len(apps))
+	}
+	if loginCount != 2 {
+		t.Errorf("Expected 2 logins, got %d", loginCount)
+	}
+
+	// Test that a persistent 401 is retried only once
+	_, err = client.GetAppInfo("app-id-1")
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+	if loginCount != 3 {
+		t.Errorf("Expected a single re-login for a persistent 401, got %d logins", loginCount)
+	}
+
+	// Test that the original 401 is returned when re-login fails
+	loginFails = true
+	_, err = client.GetDSL("app-id-1")
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected original unauthorized error, got %v", err)
+	}
+	if loginCount != 4 {
+		t.Errorf("Expected 4 logins, got %d", loginCount)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token" // Set token directly for testing
+
+	// Test that requests are paced at 20 requests per second
+	client.SetRateLimit(20)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.DoesDSLExist("test-app-id"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	// The first request fires immediately, the remaining four wait 50ms each
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("Expected requests to be paced, took only %v", elapsed)
+	}
+
+	// Test that a zero rate disables limiting
+	client.SetRateLimit(0)
+	if client.limiter != nil {
+		t.Error("Expected limiter to be nil when rate limit is 0")
+	}
+}
+
+func TestMaxBandwidth(t *testing.T) {
+	// A DSL of 30000 bytes, a little more with the JSON wrapper
+	dsl := strings.Repeat("a", 30000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": "` + dsl + `"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token" // Set token directly for testing
+
+	// At 20000 bytes per second, the first 20000 bytes are read at once and the rest takes 0.5s
+	client.SetMaxBandwidth(20000)
+	start := time.Now()
+	got, err := client.GetDSL("test-app-id")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if string(got) != dsl {
+		t.Errorf("Expected the full DSL to be read, got %d bytes", len(got))
+	}
+	if elapsed < 450*time.Millisecond {
+		t.Errorf("Expected the download to be throttled, took only %v", elapsed)
+	}
+
+	// Test that zero disables throttling
+	client.SetMaxBandwidth(0)
+	if client.bandwidth != nil {
+		t.Error("Expected bandwidth limiter to be nil when the bandwidth is 0")
+	}
+}
+
+func TestExportPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		exportPath   string
+		expectedPath string
+		expectedRaw  string
+	}{
+		{
+			name:         "default",
+			exportPath:   "",
+			expectedPath: "/console/api/apps/test-app-id/export",
+			expectedRaw:  "include_secret=false",
+		},
+		{
+			name:         "legacy",
+			exportPath:   LegacyExportPath,
+			expectedPath: "/console/api/apps/test-app-id/dsl",
+		},
+		{
+			name:         "custom without leading slash",
+			exportPath:   "export?include_secret=true",
+			expectedPath: "/console/api/apps/test-app-id/export",
+			expectedRaw:  "include_secret=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotRaw string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotRaw = r.URL.RawQuery
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"data": "name: Test App"}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.token = "test-token" // Set token directly for testing
+			client.ExportPath = tt.exportPath
+
+			if _, err := client.GetDSL("test-app-id"); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if gotPath != tt.expectedPath {
+				t.Errorf("Expected request path %s, got %s", tt.expectedPath, gotPath)
+			}
+			if gotRaw != tt.expectedRaw {
+				t.Errorf("Expected query %q, got %q", tt.expectedRaw, gotRaw)
+			}
+		})
+	}
+}
+
+func TestGatewayHeaders(t *testing.T) {
+	// Create a test server that behaves like a gateway checking its own credentials
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Api-Key") != "gateway-key" {
+			t.Errorf("Expected X-Api-Key header on %s, got %q", r.URL.Path, r.Header.Get("X-Api-Key"))
+		}
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			t.Errorf("Expected basic auth credentials on %s, got %q", r.URL.Path, r.Header.Get("Proxy-Authorization"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			// The Dify token is still sent alongside the gateway credentials
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				t.Errorf("Expected Dify token, got %q", r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`{"data": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.ExtraHeaders = map[string]string{"X-Api-Key": "gateway-key"}
+	client.BasicAuthUser = "user"
+	client.BasicAuthPassword = "pass"
+
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.GetAppList(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		default:
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer server.Close()
+
+	// Without a UserAgent, the default is sent
+	client := NewClient(server.URL)
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	client.UserAgent = "difync/1.2.3"
+	if _, err := client.GetAppList(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{DefaultUserAgent, "difync/1.2.3"}
+	if strings.Join(userAgents, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected User-Agent headers %v, got %v", expected, userAgents)
+	}
 }
 
-func TestMin(t *testing.T) {
+func TestAPIPathPrefix(t *testing.T) {
+	// Create a test server that serves Dify under /dify
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dify/console/api/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+	})
+	mux.HandleFunc("/dify/console/api/apps", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": [{"id": "app-id-1", "name": "App 1"}]}`))
+	})
+	mux.HandleFunc("/dify/console/api/apps/app-id-1/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": "name: App 1"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.APIPathPrefix = "/dify/"
+
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected login under prefix to succeed, got %v", err)
+	}
+
+	apps, err := client.GetAppList()
+	if err != nil {
+		t.Fatalf("Expected app list under prefix to succeed, got %v", err)
+	}
+	if len(apps) != 1 {
+		t.Errorf("Expected 1 app, got %d", len(apps))
+	}
+
+	dsl, err := client.GetDSL("app-id-1")
+	if err != nil {
+		t.Fatalf("Expected export under prefix to succeed, got %v", err)
+	}
+	if string(dsl) != "name: App 1" {
+		t.Errorf("Expected DSL to be 'name: App 1', got '%s'", string(dsl))
+	}
+
+	// Test URL construction with different prefix forms
 	testCases := []struct {
-		a, b     int
-		expected int
+		baseURL  string
+		prefix   string
+		expected string
 	}{
-		{5, 10, 5},
-		{10, 5, 5},
-		{0, 0, 0},
-		{-5, 5, -5},
-		{5, -5, -5},
-		{-10, -5, -10},
+		{"https://host", "", "https://host/console/api/apps"},
+		{"https://host", "dify", "https://host/dify/console/api/apps"},
+		{"https://host/", "/dify/", "https://host/dify/console/api/apps"},
 	}
 
 	for _, tc := range testCases {
-		t.Run(fmt.Sprintf("min(%d,%d)", tc.a, tc.b), func(t *testing.T) {
-			result := min(tc.a, tc.b)
-			if result != tc.expected {
-				t.Errorf("Expected min(%d, %d) = %d, got %d", tc.a, tc.b, tc.expected, result)
+		c := NewClient(tc.baseURL)
+		c.APIPathPrefix = tc.prefix
+		if got := c.url("/console/api/apps"); got != tc.expected {
+			t.Errorf("url() with base %q and prefix %q = %q, expected %q", tc.baseURL, tc.prefix, got, tc.expected)
+		}
+	}
+}
+
+func TestLogout(t *testing.T) {
+	logoutCalled := false
+	hasLogoutEndpoint := true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/logout":
+			if !hasLogoutEndpoint {
+				w.WriteHeader(http.StatusNotFound)
+				return
 			}
-		})
+			if r.Method != "POST" {
+				t.Errorf("Expected request method to be POST, got %s", r.Method)
+			}
+			if auth := r.Header.Get("Authorization"); auth != "Bearer test-token" {
+				t.Errorf("Expected Authorization header to be 'Bearer test-token', got '%s'", auth)
+			}
+			logoutCalled = true
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Test logout after password login
+	client := NewClient(server.URL)
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error on login, got %v", err)
+	}
+
+	if err := client.Logout(); err != nil {
+		t.Fatalf("Expected no error on logout, got %v", err)
+	}
+	if !logoutCalled {
+		t.Error("Expected logout endpoint to be called")
+	}
+	if client.token != "" || client.email != "" || client.password != "" {
+		t.Error("Expected token and credentials to be cleared")
+	}
+
+	// Test missing logout endpoint
+	hasLogoutEndpoint = false
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error on login, got %v", err)
+	}
+	if err := client.Logout(); err != nil {
+		t.Errorf("Expected missing logout endpoint to be ignored, got %v", err)
+	}
+	if client.token != "" {
+		t.Error("Expected token to be cleared")
+	}
+
+	// Test no-op without password login
+	logoutCalled = false
+	hasLogoutEndpoint = true
+	client = NewClient(server.URL)
+	client.token = "test-token" // Set token directly for testing
+	if err := client.Logout(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if logoutCalled {
+		t.Error("Expected logout endpoint not to be called without password login")
+	}
+	if client.token != "test-token" {
+		t.Error("Expected token to be kept without password login")
+	}
+}
+
+// fakeTokenProvider hands out numbered tokens and counts how often it is asked
+type fakeTokenProvider struct {
+	calls       int
+	invalidated int
+	current     int
+	err         error
+}
+
+func (p *fakeTokenProvider) Token(ctx context.Context) (string, error) {
+	p.calls++
+	if p.err != nil {
+		return "", p.err
+	}
+	if p.current == 0 {
+		p.current = 1
+	}
+	return fmt.Sprintf("sso-token-%d", p.current), nil
+}
+
+func (p *fakeTokenProvider) Invalidate() {
+	p.invalidated++
+	p.current++
+}
+
+func TestTokenProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/apps":
+			// Only the refreshed token is accepted
+			if r.Header.Get("Authorization") != "Bearer sso-token-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "app-id-1", "name": "App 1"}]}`))
+		case "/console/api/apps/app-id-1":
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// The provider is asked for a token before each request, without calling Login
+	provider := &fakeTokenProvider{}
+	client := NewClient(server.URL)
+	client.TokenProvider = provider
+
+	apps, err := client.GetAppList()
+	if err != nil {
+		t.Fatalf("Expected request to succeed after refreshing the token, got %v", err)
+	}
+	if len(apps) != 1 {
+		t.Errorf("Expected 1 app, got %d", len(apps))
+	}
+	if provider.invalidated != 1 {
+		t.Errorf("Expected the rejected token to be invalidated once, got %d", provider.invalidated)
+	}
+	if provider.calls != 2 {
+		t.Errorf("Expected 2 token requests, got %d", provider.calls)
+	}
+
+	exists, err := client.DoesDSLExist("app-id-1")
+	if err != nil || !exists {
+		t.Errorf("Expected app to exist, got %v, %v", exists, err)
+	}
+	if provider.calls != 3 {
+		t.Errorf("Expected a token request per API request, got %d", provider.calls)
+	}
+
+	// Provider errors are returned without sending the request
+	provider.err = errors.New("refresh token revoked")
+	_, err = client.GetAppList()
+	if err == nil || !strings.Contains(err.Error(), "refresh token revoked") {
+		t.Errorf("Expected provider error, got %v", err)
 	}
 }
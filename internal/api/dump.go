@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// apiError creates an APIError from an HTTP response, consuming its body, and
+// writes it to ErrorDumpDir if set
+func (c *Client) apiError(resp *http.Response, url string) *APIError {
+	apiErr := newAPIError(resp, url)
+	if c.ErrorDumpDir != "" {
+		if err := c.dumpAPIError(resp, apiErr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to write API error dump: %v\n", err)
+		}
+	}
+	return apiErr
+}
+
+// dumpAPIError writes the request method and URL, the response status and the full
+// response body of a failed request to a new timestamped file in ErrorDumpDir
+func (c *Client) dumpAPIError(resp *http.Response, apiErr *APIError) error {
+	if err := os.MkdirAll(c.ErrorDumpDir, 0755); err != nil {
+		return fmt.Errorf("failed to create dump directory: %w", err)
+	}
+
+	now := time.Now()
+	pattern := fmt.Sprintf("difync-error-%s-%d-*.txt", now.UTC().Format("20060102T150405"), apiErr.StatusCode)
+	file, err := os.CreateTemp(c.ErrorDumpDir, pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create dump file: %w", err)
+	}
+	defer file.Close()
+
+	method := ""
+	if resp.Request != nil {
+		method = resp.Request.Method
+	}
+	if _, err := fmt.Fprintf(file, "Time: %s\nMethod: %s\nURL: %s\nStatus: %s\n\n%s",
+		now.Format(time.RFC3339), method, apiErr.URL, resp.Status, apiErr.Body); err != nil {
+		return fmt.Errorf("failed to write dump file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message": "export worker crashed", "trace": "` + strings.Repeat("x", 4096) + `"}`))
+	}))
+	defer server.Close()
+
+	dumpDir := filepath.Join(t.TempDir(), "dumps")
+	client := NewClient(server.URL)
+	client.token = "test-token"
+	client.ErrorDumpDir = dumpDir
+
+	if _, err := client.GetDSL("test-app-id"); err == nil {
+		t.Fatal("Expected an error for a 500 response")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dumpDir, "difync-error-*-500-*.txt"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 dump file, got %v (%v)", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read dump file: %v", err)
+	}
+
+	// The dump holds the request and the full, untruncated response body
+	for _, expected := range []string{
+		"Method: GET",
+		"URL: " + server.URL + "/console/api/apps/test-app-id/export?include_secret=false",
+		"Status: 500 Internal Server Error",
+		strings.Repeat("x", 4096),
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected dump to contain %.80q, got %.200q", expected, content)
+		}
+	}
+
+	// Without ErrorDumpDir nothing is written
+	client.ErrorDumpDir = ""
+	client.GetDSL("test-app-id")
+	if files, _ := filepath.Glob(filepath.Join(dumpDir, "*")); len(files) != 1 {
+		t.Errorf("Expected no more dump files, got %v", files)
+	}
+}
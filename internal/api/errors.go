@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrEmptyDSL indicates that an export succeeded but contained no DSL
+var ErrEmptyDSL = errors.New("export returned empty DSL")
+
+// APIError represents a non-successful response returned by the Dify API
+type APIError struct {
+	StatusCode int
+	URL        string
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned error: status=%d, url=%s, body=%s", e.StatusCode, e.URL, e.Body)
+}
+
+// newAPIError creates an APIError from an HTTP response, consuming its body
+func newAPIError(resp *http.Response, url string) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		URL:        url,
+		Body:       string(body),
+	}
+}
+
+// hasStatus checks if err is an APIError with the given status code
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// IsNotFound checks if err is an APIError caused by a 404 response
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsForbidden checks if err is an APIError caused by a 403 response
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsUnauthorized checks if err is an APIError caused by a 401 response
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Defaults for polling asynchronous export jobs
+const (
+	defaultExportPollInterval = 1 * time.Second
+	maxExportPollInterval     = 10 * time.Second
+	defaultExportTimeout      = 5 * time.Minute
+)
+
+// decodeExportJobID extracts the job ID from a 202 export response.
+// Dify returns it as job_id or task_id, either at the top level or under data.
+func decodeExportJobID(body io.Reader) (string, error) {
+	type jobFields struct {
+		JobID  string `json:"job_id"`
+		TaskID string `json:"task_id"`
+	}
+
+	var result struct {
+		jobFields
+		Data jobFields `json:"data"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode export job response: %w", err)
+	}
+
+	for _, id := range []string{result.JobID, result.TaskID, result.Data.JobID, result.Data.TaskID} {
+		if id != "" {
+			return id, nil
+		}
+	}
+
+	return "", fmt.Errorf("export job response does not contain a job ID")
+}
+
+// waitForExportJob polls an asynchronous export job with backoff until the DSL is ready
+func (c *Client) waitForExportJob(appID, jobID string) ([]byte, error) {
+	url := c.url(fmt.Sprintf("/console/api/apps/%s/export/%s", appID, jobID))
+
+	interval := c.ExportPollInterval
+	if interval <= 0 {
+		interval = defaultExportPollInterval
+	}
+	timeout := c.ExportTimeout
+	if timeout <= 0 {
+		timeout = defaultExportTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("timed out after %v waiting for export job %s", timeout, jobID)
+		}
+		time.Sleep(interval)
+
+		dsl, done, err := c.pollExportJob(url)
+		if err != nil || done {
+			return dsl, err
+		}
+
+		interval *= 2
+		if interval > maxExportPollInterval {
+			interval = maxExportPollInterval
+		}
+	}
+}
+
+// pollExportJob checks an export job once, reporting whether it has finished
+func (c *Client) pollExportJob(url string) ([]byte, bool, error) {
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.doAuthenticated(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		// Still running
+		return nil, false, nil
+	case http.StatusOK:
+		dsl, err := decodeDSLResponse(c.throttle(req.Context(), resp.Body))
+		return dsl, true, err
+	default:
+		return nil, true, c.apiError(resp, url)
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenProvider supplies the access token sent with each authenticated request.
+// Implementations may cache the token; the client calls Token before every request.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenInvalidator is implemented by token providers that can discard a cached token.
+// When the API rejects a token (401), the client invalidates it and retries once with a new one.
+type TokenInvalidator interface {
+	Invalidate()
+}
+
+// TokenFunc fetches a new access token and the time it expires.
+// A zero expiry means the token is used until it is invalidated.
+type TokenFunc func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// tokenExpiryMargin is how long before its expiry a cached token is refreshed
+const tokenExpiryMargin = 30 * time.Second
+
+// CachedTokenProvider calls a TokenFunc for a new token and reuses it until shortly before it expires.
+// It can be used to plug in an OAuth or SSO refresh-token flow.
+type CachedTokenProvider struct {
+	fetch TokenFunc
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	now    func() time.Time
+}
+
+// NewCachedTokenProvider creates a token provider that caches the tokens returned by fetch
+func NewCachedTokenProvider(fetch TokenFunc) *CachedTokenProvider {
+	return &CachedTokenProvider{fetch: fetch, now: time.Now}
+}
+
+// Token returns the cached token, fetching a new one if none is cached or it is about to expire
+func (p *CachedTokenProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && (p.expiry.IsZero() || p.now().Add(tokenExpiryMargin).Before(p.expiry)) {
+		return p.token, nil
+	}
+
+	token, expiry, err := p.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token = token
+	p.expiry = expiry
+	return token, nil
+}
+
+// Invalidate discards the cached token so the next call to Token fetches a new one
+func (p *CachedTokenProvider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = ""
+	p.expiry = time.Time{}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCachedTokenProvider(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fetches := 0
+	var fetchErr error
+
+	provider := NewCachedTokenProvider(func(ctx context.Context) (string, time.Time, error) {
+		if fetchErr != nil {
+			return "", time.Time{}, fetchErr
+		}
+		fetches++
+		return fmt.Sprintf("token-%d", fetches), now.Add(5 * time.Minute), nil
+	})
+	provider.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	token, err := provider.Token(ctx)
+	if err != nil || token != "token-1" {
+		t.Fatalf("Expected token-1, got %q, %v", token, err)
+	}
+
+	// The cached token is reused while it is valid
+	now = now.Add(4 * time.Minute)
+	if token, _ := provider.Token(ctx); token != "token-1" {
+		t.Errorf("Expected cached token-1, got %q", token)
+	}
+
+	// A token about to expire is refreshed
+	now = now.Add(45 * time.Second)
+	if token, _ := provider.Token(ctx); token != "token-2" {
+		t.Errorf("Expected refreshed token-2, got %q", token)
+	}
+
+	// An invalidated token is refreshed even if it has not expired
+	provider.Invalidate()
+	if token, _ := provider.Token(ctx); token != "token-3" {
+		t.Errorf("Expected token-3 after invalidation, got %q", token)
+	}
+
+	// Fetch errors are returned and nothing is cached
+	provider.Invalidate()
+	fetchErr = errors.New("refresh failed")
+	if _, err := provider.Token(ctx); !errors.Is(err, fetchErr) {
+		t.Errorf("Expected fetch error, got %v", err)
+	}
+	fetchErr = nil
+	if token, _ := provider.Token(ctx); token != "token-4" {
+		t.Errorf("Expected token-4 after a failed fetch, got %q", token)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTraceBody is the number of body bytes written to the trace
+const maxTraceBody = 2048
+
+// redacted replaces secrets in the trace
+const redacted = "[REDACTED]"
+
+// redactedHeaders are headers whose values are never written to the trace
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// redactedFields are JSON body fields whose values are never written to the trace
+var redactedFields = map[string]bool{
+	"password":      true,
+	"access_token":  true,
+	"refresh_token": true,
+}
+
+// traceRequest writes the method, URL, headers and body of a request to TraceOutput
+func (c *Client) traceRequest(req *http.Request) {
+	var body []byte
+	if req.GetBody != nil {
+		if reader, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(reader)
+			reader.Close()
+		}
+	}
+
+	fmt.Fprintf(c.TraceOutput, "> %s %s\n", req.Method, req.URL)
+	c.traceHeaders(">", req.Header)
+	traceBody(c.TraceOutput, ">", body)
+}
+
+// traceResponse writes the status, headers and body of a response to TraceOutput.
+// The body is read and replaced so that callers can still read it.
+func (c *Client) traceResponse(resp *http.Response, elapsed time.Duration) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fmt.Fprintf(c.TraceOutput, "< %s (%v)\n", resp.Status, elapsed.Round(time.Millisecond))
+	c.traceHeaders("<", resp.Header)
+	if err != nil {
+		fmt.Fprintf(c.TraceOutput, "< failed to read body: %v\n", err)
+	}
+	traceBody(c.TraceOutput, "<", body)
+}
+
+// traceHeaders writes headers in a stable order, redacting credentials and gateway headers
+func (c *Client) traceHeaders(prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		if c.isSecretHeader(key) {
+			value = redacted
+		}
+		fmt.Fprintf(c.TraceOutput, "%s %s: %s\n", prefix, key, value)
+	}
+}
+
+// isSecretHeader reports whether a header may carry credentials.
+// Extra headers are treated as secret since they usually hold gateway API keys.
+func (c *Client) isSecretHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	if redactedHeaders[key] {
+		return true
+	}
+	for extra := range c.ExtraHeaders {
+		if http.CanonicalHeaderKey(extra) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// traceBody writes a body with secret JSON fields redacted, truncated to maxTraceBody bytes
+func traceBody(w io.Writer, prefix string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	body = redactJSON(body)
+	truncated := ""
+	if len(body) > maxTraceBody {
+		truncated = fmt.Sprintf(" ... (%d bytes truncated)", len(body)-maxTraceBody)
+		body = body[:maxTraceBody]
+	}
+	fmt.Fprintf(w, "%s %s%s\n", prefix, body, truncated)
+}
+
+// redactJSON replaces the values of secret fields in a JSON body.
+// Bodies that are not JSON are returned unchanged.
+func redactJSON(body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	if !redactValue(value) {
+		return body
+	}
+
+	redactedBody, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return redactedBody
+}
+
+// redactValue replaces secret fields in decoded JSON in place and reports whether any were found
+func redactValue(value interface{}) bool {
+	found := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[key] {
+				v[key] = redacted
+				found = true
+			} else if redactValue(field) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactValue(item) {
+				found = true
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "secret-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-id-1", "name": "App 1"}]}`))
+		case "/console/api/apps/app-id-1/export":
+			w.Write([]byte(`{"data": "` + strings.Repeat("x", maxTraceBody+100) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	client := NewClient(server.URL)
+	client.TraceOutput = &trace
+	client.ExtraHeaders = map[string]string{"X-Api-Key": "gateway-key"}
+
+	if err := client.Login("test@example.com", "secret-password"); err != nil {
+		t.Fatalf("Expected no error on login, got %v", err)
+	}
+
+	// Traced response bodies can still be read by the client
+	apps, err := client.GetAppList()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(apps) != 1 {
+		t.Errorf("Expected 1 app, got %d", len(apps))
+	}
+	if _, err := client.GetDSL("app-id-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := trace.String()
+	for _, secret := range []string{"secret-token", "secret-password", "gateway-key"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", secret, output)
+		}
+	}
+	for _, want := range []string{
+		"> POST " + server.URL + "/console/api/login",
+		"> GET " + server.URL + "/console/api/apps",
+		"> Authorization: [REDACTED]",
+		"> X-Api-Key: [REDACTED]",
+		`"password":"[REDACTED]"`,
+		"< 200 OK",
+		`"name": "App 1"`,
+		"bytes truncated)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected trace to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestTraceDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+	}))
+	defer server.Close()
+
+	// Without TraceOutput nothing is traced and requests work as before
+	client := NewClient(server.URL)
+	if err := client.Login("test@example.com", "password"); err != nil {
+		t.Fatalf("Expected no error on login, got %v", err)
+	}
+	if client.token != "test-token" {
+		t.Errorf("Expected token to be 'test-token', got %q", client.token)
+	}
+}
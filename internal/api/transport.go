@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Connection pool defaults, sized for a few dozen concurrent requests to Dify
+const (
+	// DefaultMaxIdleConnsPerHost is the number of idle connections kept open to Dify
+	DefaultMaxIdleConnsPerHost = 32
+	// DefaultIdleConnTimeout is how long an idle connection is kept open
+	DefaultIdleConnTimeout = 90 * time.Second
+	// DefaultConnectTimeout limits how long establishing a TCP connection may take
+	DefaultConnectTimeout = 10 * time.Second
+	// DefaultTLSHandshakeTimeout limits how long the TLS handshake may take
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// newTransport creates the transport shared by all requests of a client. It keeps
+// connections alive between requests and negotiates HTTP/2 where Dify supports it.
+// Connections are made through dialer, and through the proxy configured in the
+// environment (HTTPS_PROXY, NO_PROXY) if any.
+func newTransport(dialer *net.Dialer) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConns = DefaultMaxIdleConnsPerHost
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	return transport
+}
+
+// newDialer creates the dialer for connections to Dify or the proxy
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   DefaultConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// SetTimeouts limits how long connecting and the TLS handshake may take, separately from
+// the overall request timeout, to tell a slow network from a slow Dify. Values of 0 or
+// less keep the defaults. Like SetConnectionPool, it needs the transport of NewClient.
+func (c *Client) SetTimeouts(connectTimeout, tlsHandshakeTimeout time.Duration) {
+	if connectTimeout > 0 && c.dialer != nil {
+		c.dialer.Timeout = connectTimeout
+	}
+	if transport, ok := c.HTTPClient.Transport.(*http.Transport); ok && tlsHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+	}
+}
+
+// SetConnectionPool tunes how many idle connections to Dify are kept open and for how long.
+// Values of 0 or less keep the defaults. It has no effect if HTTPClient was replaced
+// with a client that does not use an *http.Transport.
+func (c *Client) SetConnectionPool(maxIdleConnsPerHost int, idleConnTimeout time.Duration) {
+	transport, ok := c.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	if maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConns = maxIdleConnsPerHost
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	}
+	if idleConnTimeout > 0 {
+		transport.IdleConnTimeout = idleConnTimeout
+	}
+}
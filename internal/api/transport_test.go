@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectionPool(t *testing.T) {
+	client := NewClient("https://dify.example.com")
+
+	transport, ok := client.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected an *http.Transport, got %T", client.HTTPClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("Expected default pool settings, got %d idle conns per host and %v timeout",
+			transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if !transport.ForceAttemptHTTP2 {
+		t.Error("Expected HTTP/2 to be attempted")
+	}
+
+	client.SetConnectionPool(64, time.Minute)
+	if transport.MaxIdleConns != 64 || transport.MaxIdleConnsPerHost != 64 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("Expected the pool settings to be applied, got %d/%d idle conns and %v timeout",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+
+	// Zero values keep the current settings
+	client.SetConnectionPool(0, 0)
+	if transport.MaxIdleConnsPerHost != 64 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("Expected zero values to keep the settings, got %d idle conns and %v timeout",
+			transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": []}`))
+	}))
+
+	// Count new connections to the server
+	var connections int32
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.token = "test-token"
+	for i := 0; i < 5; i++ {
+		if _, err := client.GetAppList(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if count := atomic.LoadInt32(&connections); count != 1 {
+		t.Errorf("Expected sequential requests to share 1 connection, got %d", count)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	client := NewClient("https://dify.example.com")
+	transport := client.HTTPClient.Transport.(*http.Transport)
+
+	if client.dialer.Timeout != DefaultConnectTimeout || transport.TLSHandshakeTimeout != DefaultTLSHandshakeTimeout {
+		t.Errorf("Expected default timeouts, got %v connect and %v TLS handshake",
+			client.dialer.Timeout, transport.TLSHandshakeTimeout)
+	}
+	if transport.Proxy == nil || transport.DialContext == nil {
+		t.Error("Expected the transport to dial through the proxy from the environment")
+	}
+
+	client.SetTimeouts(3*time.Second, 5*time.Second)
+	if client.dialer.Timeout != 3*time.Second || transport.TLSHandshakeTimeout != 5*time.Second {
+		t.Errorf("Expected the timeouts to be applied, got %v connect and %v TLS handshake",
+			client.dialer.Timeout, transport.TLSHandshakeTimeout)
+	}
+
+	// Zero values keep the current settings
+	client.SetTimeouts(0, 0)
+	if client.dialer.Timeout != 3*time.Second || transport.TLSHandshakeTimeout != 5*time.Second {
+		t.Errorf("Expected zero values to keep the timeouts, got %v connect and %v TLS handshake",
+			client.dialer.Timeout, transport.TLSHandshakeTimeout)
+	}
+}
@@ -0,0 +1,91 @@
+// Package color provides minimal ANSI coloring for terminal output
+package color
+
+import (
+	"fmt"
+	"os"
+)
+
+// ANSI escape codes
+const (
+	reset  = "\x1b[0m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+)
+
+// Mode controls when output is colorized
+type Mode string
+
+const (
+	// Auto colorizes output only for terminals and when NO_COLOR is not set
+	Auto Mode = "auto"
+	// Always colorizes output
+	Always Mode = "always"
+	// Never disables colorized output
+	Never Mode = "never"
+)
+
+// ParseMode parses a --color value
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case Auto, Always, Never:
+		return Mode(s), nil
+	default:
+		return "", fmt.Errorf("invalid color mode %q (use auto, always or never)", s)
+	}
+}
+
+// Enabled reports whether output written to f should be colorized in the given mode
+func Enabled(mode Mode, f *os.File) bool {
+	switch mode {
+	case Always:
+		return true
+	case Never:
+		return false
+	}
+
+	// See https://no-color.org
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Colorizer wraps text in ANSI colors when enabled
+type Colorizer struct {
+	enabled bool
+}
+
+// New creates a Colorizer; a disabled Colorizer returns text unchanged
+func New(enabled bool) Colorizer {
+	return Colorizer{enabled: enabled}
+}
+
+// Red colors text red
+func (c Colorizer) Red(s string) string {
+	return c.wrap(red, s)
+}
+
+// Green colors text green
+func (c Colorizer) Green(s string) string {
+	return c.wrap(green, s)
+}
+
+// Yellow colors text yellow
+func (c Colorizer) Yellow(s string) string {
+	return c.wrap(yellow, s)
+}
+
+// wrap surrounds text with the given color code and a reset
+func (c Colorizer) wrap(code, s string) string {
+	if !c.enabled {
+		return s
+	}
+	return code + s + reset
+}
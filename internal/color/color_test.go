@@ -0,0 +1,64 @@
+package color
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, valid := range []string{"auto", "always", "never"} {
+		if _, err := ParseMode(valid); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", valid, err)
+		}
+	}
+
+	if _, err := ParseMode("sometimes"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	// A regular file is never a terminal
+	file, err := os.CreateTemp("", "difync-test-color-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if !Enabled(Always, file) {
+		t.Error("Expected always to enable color")
+	}
+	if Enabled(Never, file) {
+		t.Error("Expected never to disable color")
+	}
+	if Enabled(Auto, file) {
+		t.Error("Expected auto to disable color for a non-terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if Enabled(Auto, os.Stdout) {
+		t.Error("Expected auto to disable color when NO_COLOR is set")
+	}
+}
+
+func TestColorizer(t *testing.T) {
+	enabled := New(true)
+	if got := enabled.Red("error"); got != "\x1b[31merror\x1b[0m" {
+		t.Errorf("Expected red escape codes, got %q", got)
+	}
+	if got := enabled.Green("ok"); got != "\x1b[32mok\x1b[0m" {
+		t.Errorf("Expected green escape codes, got %q", got)
+	}
+	if got := enabled.Yellow("download"); got != "\x1b[33mdownload\x1b[0m" {
+		t.Errorf("Expected yellow escape codes, got %q", got)
+	}
+
+	disabled := New(false)
+	for _, got := range []string{disabled.Red("error"), disabled.Green("ok"), disabled.Yellow("download")} {
+		if strings.Contains(got, "\x1b") {
+			t.Errorf("Expected no escape codes, got %q", got)
+		}
+	}
+}
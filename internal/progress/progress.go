@@ -0,0 +1,71 @@
+// Package progress renders a single-line progress bar for terminal output
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// clearLine returns the cursor to the start of the line and erases it
+const clearLine = "\r\x1b[K"
+
+// width is the number of cells of the bar
+const width = 20
+
+// Enabled reports whether a progress bar should be drawn on f, which is only the case for terminals
+func Enabled(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Bar draws the progress of a sync in place on a single line.
+// It is safe for concurrent use; updates are serialized.
+type Bar struct {
+	mu sync.Mutex
+	w  io.Writer
+	// drawn is set while the bar is shown on the current line
+	drawn bool
+}
+
+// New creates a progress bar writing to w
+func New(w io.Writer) *Bar {
+	return &Bar{w: w}
+}
+
+// Update shows that done of total items are finished and name is being processed.
+// The cursor is left at the start of the line, so other output replaces the bar
+// until the next update. The bar is cleared once done reaches total.
+func (b *Bar) Update(done, total int, name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if total <= 0 || done >= total {
+		b.clear()
+		return
+	}
+
+	filled := done * width / total
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", width-filled)
+	fmt.Fprintf(b.w, "%s[%s] %d/%d %s\r", clearLine, bar, done, total, name)
+	b.drawn = true
+}
+
+// Clear removes the bar from the line
+func (b *Bar) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+}
+
+func (b *Bar) clear() {
+	if b.drawn {
+		fmt.Fprint(b.w, clearLine)
+		b.drawn = false
+	}
+}
@@ -0,0 +1,99 @@
+package progress
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	// Output redirected to a file is not a terminal
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if Enabled(f) {
+		t.Error("Expected the progress bar to be disabled for a regular file")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if Enabled(w) {
+		t.Error("Expected the progress bar to be disabled for a pipe")
+	}
+}
+
+func TestBarUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	bar := New(&buf)
+
+	bar.Update(1, 4, "app.yaml")
+	if got, want := buf.String(), "\r\x1b[K[#####---------------] 1/4 app.yaml\r"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Finishing clears the line
+	buf.Reset()
+	bar.Update(4, 4, "")
+	if got := buf.String(); got != "\r\x1b[K" {
+		t.Errorf("Expected the bar to be cleared, got %q", got)
+	}
+
+	// Nothing is cleared if the bar is not shown
+	buf.Reset()
+	bar.Clear()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output, got %q", buf.String())
+	}
+}
+
+// lockedBuffer fails the test if it is written to concurrently
+type lockedBuffer struct {
+	mu      sync.Mutex
+	t       *testing.T
+	writing bool
+	buf     bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.writing {
+		b.t.Error("Expected writes to be serialized")
+	}
+	b.writing = true
+	b.mu.Unlock()
+
+	n, err := b.buf.Write(p)
+
+	b.mu.Lock()
+	b.writing = false
+	b.mu.Unlock()
+	return n, err
+}
+
+func TestBarConcurrentUpdates(t *testing.T) {
+	out := &lockedBuffer{t: t}
+	bar := New(out)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bar.Update(i, 50, "app.yaml")
+		}(i)
+	}
+	wg.Wait()
+
+	if n := strings.Count(out.buf.String(), "app.yaml"); n != 50 {
+		t.Errorf("Expected 50 updates, got %d", n)
+	}
+}
@@ -0,0 +1,113 @@
+package syncer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isYAMLAppMap reports whether an app map path refers to YAML rather than JSON
+func isYAMLAppMap(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// marshalAppMap encodes an app map as YAML for .yaml and .yml paths and as indented JSON otherwise
+func marshalAppMap(path string, appMap *AppMap) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if isYAMLAppMap(path) {
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(appMap); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(appMap); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalAppMap decodes an app map in the format implied by its path
+func unmarshalAppMap(path string, data []byte, appMap *AppMap) error {
+	if isYAMLAppMap(path) {
+		return yaml.Unmarshal(data, appMap)
+	}
+	return json.NewDecoder(bytes.NewReader(data)).Decode(appMap)
+}
+
+// writeAppMap writes the app map to the app map file
+func (s *DefaultSyncer) writeAppMap(appMap *AppMap) error {
+	data, err := marshalAppMap(s.config.AppMapFile, appMap)
+	if err != nil {
+		return err
+	}
+	return s.fileStore().WriteFile(s.config.AppMapFile, data, 0644)
+}
+
+// discoveryAppMap returns the in-memory app map used with Config.NoAppMap:
+// a single pattern entry that matches every app in the workspace
+func discoveryAppMap() *AppMap {
+	return &AppMap{
+		Version: AppMapVersion,
+		Apps:    []AppMapping{{Match: &AppMatch{ID: ".*"}}},
+	}
+}
+
+// defaultDSLDirectory is used when neither the config nor the app map sets a DSL directory
+const defaultDSLDirectory = "dsl"
+
+// resolveDSLDirectory sets an unset DSL directory from the app map's dsl_directory,
+// resolved relative to the app map file, or to defaultDSLDirectory
+func (s *DefaultSyncer) resolveDSLDirectory(appMap *AppMap) {
+	if s.config.DSLDirectory != "" {
+		return
+	}
+	if appMap == nil || appMap.DSLDirectory == "" {
+		s.config.DSLDirectory = defaultDSLDirectory
+		return
+	}
+	s.config.DSLDirectory = appMapRelativePath(s.config.AppMapFile, appMap.DSLDirectory)
+}
+
+// appMapRelativePath resolves a path relative to the directory of the app map file
+func appMapRelativePath(appMapFile, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(appMapFile), path)
+}
+
+// ReadAppMapDSLDirectory returns the dsl_directory of the app map file, resolved relative to
+// the file. It returns "" if the file does not exist or does not set a DSL directory.
+func ReadAppMapDSLDirectory(appMapFile string) (string, error) {
+	data, err := os.ReadFile(appMapFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open app map file: %w", err)
+	}
+
+	var appMap AppMap
+	if err := unmarshalAppMap(appMapFile, data, &appMap); err != nil {
+		return "", fmt.Errorf("failed to decode app map: %w", err)
+	}
+	if appMap.DSLDirectory == "" {
+		return "", nil
+	}
+	return appMapRelativePath(appMapFile, appMap.DSLDirectory), nil
+}
@@ -0,0 +1,185 @@
+package syncer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppMapYAMLRoundTrip(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	syncedAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	appMap := &AppMap{
+		Version: AppMapVersion,
+		Apps: []AppMapping{
+			{Match: &AppMatch{Name: "Generated *"}, Skip: true},
+			{Filename: "alpha.yaml", AppID: "app-a", LastSyncedAt: &syncedAt, LastRemoteUpdatedAt: &syncedAt},
+			{Filename: "beta.yaml", AppID: "app-b", ReadOnly: true},
+		},
+	}
+
+	for _, name := range []string{"app_map.yaml", "app_map.yml", "app_map.json"} {
+		t.Run(name, func(t *testing.T) {
+			syncer := &DefaultSyncer{config: Config{AppMapFile: filepath.Join(tmpDir, name)}}
+			if err := syncer.writeAppMap(appMap); err != nil {
+				t.Fatalf("Failed to write app map: %v", err)
+			}
+
+			data, err := os.ReadFile(syncer.config.AppMapFile)
+			if err != nil {
+				t.Fatalf("Failed to read app map file: %v", err)
+			}
+			isJSON := strings.HasPrefix(string(data), "{")
+			if isJSON != (filepath.Ext(name) == ".json") {
+				t.Errorf("Expected format to follow the extension of %s, got:\n%s", name, data)
+			}
+
+			loaded, err := syncer.LoadAppMap()
+			if err != nil {
+				t.Fatalf("Failed to load app map: %v", err)
+			}
+			if !reflect.DeepEqual(loaded, appMap) {
+				t.Errorf("Expected %+v, got %+v", appMap, loaded)
+			}
+		})
+	}
+}
+
+func TestLoadAppMapYAML(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	appMapPath := filepath.Join(tmpDir, "app_map.yaml")
+	content := `apps:
+  - filename: test.yaml
+    app_id: test-app-id
+    read_only: true
+`
+	if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	syncer := &DefaultSyncer{config: Config{AppMapFile: appMapPath}}
+	appMap, err := syncer.LoadAppMap()
+	if err != nil {
+		t.Fatalf("Failed to load app map: %v", err)
+	}
+
+	expected := []AppMapping{{Filename: "test.yaml", AppID: "test-app-id", ReadOnly: true}}
+	if !reflect.DeepEqual(appMap.Apps, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, appMap.Apps)
+	}
+	if appMap.Version != AppMapVersion {
+		t.Errorf("Expected version %d, got %d", AppMapVersion, appMap.Version)
+	}
+}
+
+func TestSyncAllDSLDirectoryFromAppMap(t *testing.T) {
+	syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// The app map names its DSL directory relative to itself, and no directory is configured
+	content := `{"version": 1, "dsl_directory": "embedded", "apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`
+	if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+	defaultSyncer := syncer.(*DefaultSyncer)
+	defaultSyncer.config.DSLDirectory = ""
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if stats.Downloads != 1 || stats.Errors != 0 {
+		t.Errorf("Expected 1 download and no errors, got %+v", stats)
+	}
+
+	embeddedDir := filepath.Join(filepath.Dir(appMapPath), "embedded")
+	if defaultSyncer.config.DSLDirectory != embeddedDir {
+		t.Errorf("Expected DSL directory %s, got %s", embeddedDir, defaultSyncer.config.DSLDirectory)
+	}
+	if _, err := os.Stat(filepath.Join(embeddedDir, "Test_App.yaml")); err != nil {
+		t.Errorf("Expected DSL file in the app map's DSL directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dslDir, "Test_App.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written to the default DSL directory")
+	}
+
+	// The rewritten app map keeps its DSL directory
+	dir, err := ReadAppMapDSLDirectory(appMapPath)
+	if err != nil {
+		t.Fatalf("ReadAppMapDSLDirectory failed: %v", err)
+	}
+	if dir != embeddedDir {
+		t.Errorf("Expected the app map to keep dsl_directory, got %q", dir)
+	}
+}
+
+func TestSyncAllNoAppMap(t *testing.T) {
+	tmpDir := t.TempDir()
+	dslDir := filepath.Join(tmpDir, "dsl")
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-id-1", "name": "First Bot"}, {"id": "app-id-2", "name": "Second Bot"}]}`))
+		case "/console/api/apps/app-id-1/export":
+			w.Write([]byte(`{"data": "name: First Bot"}`))
+		case "/console/api/apps/app-id-2/export":
+			w.Write([]byte(`{"data": "name: Second Bot"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+		NoAppMap:     true,
+	})
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if stats.Downloads != 2 {
+		t.Errorf("Expected 2 downloads, got %d", stats.Downloads)
+	}
+
+	// Every app is downloaded under its sanitized name
+	for filename, expected := range map[string]string{"First_Bot.yaml": "name: First Bot", "Second_Bot.yaml": "name: Second Bot"} {
+		content, err := os.ReadFile(filepath.Join(dslDir, filename))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", filename, err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %s to contain %q, got %q", filename, expected, content)
+		}
+	}
+
+	// No app map is written
+	if _, err := os.Stat(appMapPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no app map file, got %v", err)
+	}
+}
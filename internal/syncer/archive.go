@@ -0,0 +1,186 @@
+package syncer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveWriter adds files to an archive being written
+type archiveWriter interface {
+	Add(name string, data []byte, modTime time.Time) error
+	Close() error
+}
+
+// ExportArchive downloads the DSL of every app in the app map and writes them,
+// together with the app map itself, into a single archive at path.
+// The format is zip for .zip paths and gzipped tar for .tar.gz or .tgz paths.
+// Nothing is written to the DSL directory. It returns the number of DSL files archived.
+func (s *DefaultSyncer) ExportArchive(path string) (int, error) {
+	format, err := archiveFormat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return 0, err
+	}
+
+	patterns, apps := splitPatternEntries(appMap.Apps)
+	if len(patterns) > 0 || hasNamedEntries(apps) {
+		remoteAppList, err := s.client.GetAppList()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get app list from API: %w", err)
+		}
+		apps, err = s.resolveNamedEntries(apps, remoteAppList)
+		if err != nil {
+			return 0, err
+		}
+		expandedApps, err := s.expandPatterns(patterns, apps, remoteAppList)
+		if err != nil {
+			return 0, fmt.Errorf("failed to expand app map patterns: %w", err)
+		}
+		apps = append(apps, expandedApps...)
+	}
+	sortAppMappings(apps)
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, s.fileMode())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer file.Close()
+	if err := file.Chmod(s.fileMode()); err != nil {
+		return 0, fmt.Errorf("failed to set archive file permissions: %w", err)
+	}
+
+	archive := newArchiveWriter(file, format)
+	now := s.now()
+
+	appMapData, err := marshalAppMap(s.config.AppMapFile, appMap)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal app map: %w", err)
+	}
+	if err := archive.Add(filepath.Base(s.config.AppMapFile), appMapData, now); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, app := range apps {
+		dsl, err := s.exportDSL(app.AppID)
+		if err != nil {
+			return count, fmt.Errorf("failed to get DSL for %s (app_id: %s): %w", app.Filename, app.AppID, err)
+		}
+
+		if err := archive.Add(filepath.ToSlash(filepath.Join("dsl", app.Filename)), dsl, now); err != nil {
+			return count, err
+		}
+		count++
+
+		if s.config.Verbose {
+			fmt.Printf("Archived %s (app_id: %s)\n", app.Filename, app.AppID)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return count, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return count, fmt.Errorf("failed to close archive file: %w", err)
+	}
+
+	return count, nil
+}
+
+// Supported archive formats
+const (
+	archiveFormatZip   = "zip"
+	archiveFormatTarGz = "tar.gz"
+)
+
+// archiveFormat returns the archive format implied by the path's extension
+func archiveFormat(path string) (string, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveFormatZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveFormatTarGz, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format for %s (use .tar.gz, .tgz or .zip)", path)
+	}
+}
+
+// newArchiveWriter creates an archive writer for the given format
+func newArchiveWriter(w io.Writer, format string) archiveWriter {
+	if format == archiveFormatZip {
+		return &zipArchive{zw: zip.NewWriter(w)}
+	}
+	gw := gzip.NewWriter(w)
+	return &tarGzArchive{gw: gw, tw: tar.NewWriter(gw)}
+}
+
+// tarGzArchive writes a gzipped tar archive
+type tarGzArchive struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+// Add adds a file to the tar archive
+func (a *tarGzArchive) Add(name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := a.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	if _, err := a.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close flushes the tar and gzip streams
+func (a *tarGzArchive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gw.Close()
+}
+
+// zipArchive writes a zip archive
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+// Add adds a file to the zip archive
+func (a *zipArchive) Add(name string, data []byte, modTime time.Time) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+	w, err := a.zw.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close writes the zip central directory
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
@@ -0,0 +1,128 @@
+package syncer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readArchive returns the contents of every file in a .tar.gz or .zip archive by name
+func readArchive(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	entries := make(map[string]string)
+
+	if strings.HasSuffix(path, ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatalf("Failed to open zip archive: %v", err)
+		}
+		defer zr.Close()
+
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("Failed to open zip entry %s: %v", f.Name, err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("Failed to read zip entry %s: %v", f.Name, err)
+			}
+			entries[f.Name] = string(data)
+		}
+		return entries
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Failed to open gzip stream: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read tar entry: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("Failed to read tar entry %s: %v", header.Name, err)
+		}
+		entries[header.Name] = string(data)
+	}
+	return entries
+}
+
+func TestExportArchive(t *testing.T) {
+	for _, name := range []string{"backup.tar.gz", "backup.tgz", "backup.zip"} {
+		t.Run(name, func(t *testing.T) {
+			s, server, dslDir, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+			defer server.Close()
+			defer cleanup()
+
+			// Remove the local file to confirm the export never writes to the DSL directory
+			if err := os.Remove(dslPath); err != nil {
+				t.Fatalf("Failed to remove DSL file: %v", err)
+			}
+
+			archivePath := filepath.Join(filepath.Dir(dslDir), name)
+			count, err := s.(*DefaultSyncer).ExportArchive(archivePath)
+			if err != nil {
+				t.Fatalf("ExportArchive returned error: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("Expected 1 DSL file archived, got %d", count)
+			}
+
+			entries := readArchive(t, archivePath)
+
+			if dsl, ok := entries["dsl/test.yaml"]; !ok {
+				t.Error("Expected archive to contain dsl/test.yaml")
+			} else if dsl != "name: Test App\nversion: 1.0.0" {
+				t.Errorf("Unexpected DSL content: %q", dsl)
+			}
+
+			if appMap, ok := entries["app_map.json"]; !ok {
+				t.Error("Expected archive to contain app_map.json")
+			} else if !strings.Contains(appMap, `"app_id": "test-app-id"`) {
+				t.Errorf("Expected archived app map to contain the app, got %s", appMap)
+			}
+
+			files, err := os.ReadDir(dslDir)
+			if err != nil {
+				t.Fatalf("Failed to read DSL directory: %v", err)
+			}
+			if len(files) != 0 {
+				t.Errorf("Expected DSL directory to stay empty, got %d files", len(files))
+			}
+		})
+	}
+}
+
+func TestExportArchiveUnsupportedFormat(t *testing.T) {
+	s, server, dslDir, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer server.Close()
+	defer cleanup()
+
+	archivePath := filepath.Join(filepath.Dir(dslDir), "backup.rar")
+	if _, err := s.(*DefaultSyncer).ExportArchive(archivePath); err == nil {
+		t.Error("Expected error for unsupported archive format")
+	}
+	if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+		t.Error("Expected no archive file to be created")
+	}
+}
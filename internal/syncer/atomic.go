@@ -0,0 +1,25 @@
+package syncer
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces the file at path in store with data so that readers never see
+// a partially written file: the data is written to a temporary file in the same
+// directory, which is then renamed over path.
+func writeFileAtomic(store FileStore, path string, data []byte, perm os.FileMode) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.tmp-%d", filepath.Base(path), rand.Uint64()))
+	if err := store.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := store.Rename(tmp, path); err != nil {
+		store.Remove(tmp)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditLogger appends one JSON object per sync result to a JSON Lines file
+type AuditLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// auditEntry represents a single line in the audit log
+type auditEntry struct {
+	Timestamp    time.Time  `json:"timestamp"`
+	AppID        string     `json:"app_id"`
+	Filename     string     `json:"filename"`
+	Action       SyncAction `json:"action"`
+	Success      bool       `json:"success"`
+	Error        string     `json:"error,omitempty"`
+	NewFilename  string     `json:"new_filename,omitempty"`
+	BytesWritten int64      `json:"bytes_written,omitempty"`
+}
+
+// NewAuditLogger opens the audit log file for appending, creating it if necessary
+func NewAuditLogger(path string) (*AuditLogger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	return &AuditLogger{file: file}, nil
+}
+
+// Log writes a sync result as a single line.
+// Each line is written unbuffered so prior entries survive a crash.
+func (l *AuditLogger) Log(result SyncResult) error {
+	entry := auditEntry{
+		Timestamp:    result.Timestamp,
+		AppID:        result.AppID,
+		Filename:     result.Filename,
+		Action:       result.Action,
+		Success:      result.Success,
+		NewFilename:  result.NewFilename,
+		BytesWritten: result.BytesWritten,
+	}
+	if result.Error != nil {
+		entry.Error = result.Error.Error()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the audit log file
+func (l *AuditLogger) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,190 @@
+package syncer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLogger(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-audit-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logPath := filepath.Join(tmpDir, "audit.jsonl")
+
+	logger, err := NewAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+
+	timestamp := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	results := []SyncResult{
+		{Filename: "app1.yaml", AppID: "app-id-1", Action: ActionDownload, Success: true, Timestamp: timestamp},
+		{Filename: "app2.yaml", AppID: "app-id-2", Action: ActionError, Error: fmt.Errorf("boom"), Timestamp: timestamp},
+	}
+	for _, result := range results {
+		if err := logger.Log(result); err != nil {
+			t.Fatalf("Failed to log result: %v", err)
+		}
+	}
+	logger.Close()
+
+	// Reopening must append instead of truncating
+	logger, err = NewAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen audit logger: %v", err)
+	}
+	if err := logger.Log(SyncResult{Filename: "app3.yaml", AppID: "app-id-3", Action: ActionNone, Success: true}); err != nil {
+		t.Fatalf("Failed to log result: %v", err)
+	}
+	logger.Close()
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to parse audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", len(entries))
+	}
+
+	if entries[0]["app_id"] != "app-id-1" || entries[0]["action"] != "download" || entries[0]["success"] != true {
+		t.Errorf("Unexpected first entry: %v", entries[0])
+	}
+
+	if entries[0]["timestamp"] != "2023-01-01T12:00:00Z" {
+		t.Errorf("Expected timestamp to be 2023-01-01T12:00:00Z, got %v", entries[0]["timestamp"])
+	}
+
+	if _, ok := entries[0]["error"]; ok {
+		t.Errorf("Expected no error field for successful result, got %v", entries[0]["error"])
+	}
+
+	if entries[1]["error"] != "boom" || entries[1]["success"] != false {
+		t.Errorf("Unexpected second entry: %v", entries[1])
+	}
+
+	if entries[2]["filename"] != "app3.yaml" {
+		t.Errorf("Expected appended entry for app3.yaml, got %v", entries[2])
+	}
+}
+
+func TestSyncAllWithAuditLog(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Use the filename matching the remote app name so the app is synced rather than renamed
+	if err := os.Rename(dslPath, filepath.Join(dslDir, "Test_App.yaml")); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	appMapData := `{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	logPath := filepath.Join(filepath.Dir(appMapPath), "audit.jsonl")
+	config := syncer.(*DefaultSyncer).config
+	config.AuditLogFile = logPath
+	syncer = NewSyncer(config)
+	defer syncer.(*DefaultSyncer).auditLogger.Close()
+
+	if _, err := syncer.SyncAll(); err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+
+	var entry auditEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Expected a single audit entry, got %q: %v", data, err)
+	}
+
+	if entry.AppID != "test-app-id" || entry.Filename != "Test_App.yaml" {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+}
+
+func TestSyncAllAuditsRenamesDeletionsAndSkips(t *testing.T) {
+	syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// test.yaml is renamed, gone.yaml and kept.yaml were deleted remotely but kept.yaml is
+	// read-only, and skipped.yaml is skipped
+	for _, name := range []string{"gone.yaml", "kept.yaml"} {
+		if err := os.WriteFile(filepath.Join(dslDir, name), []byte("name: Gone"), 0644); err != nil {
+			t.Fatalf("Failed to write DSL file: %v", err)
+		}
+	}
+	appMapData := `{"apps": [
+		{"filename": "test.yaml", "app_id": "test-app-id"},
+		{"filename": "gone.yaml", "app_id": "gone-app-id"},
+		{"filename": "kept.yaml", "app_id": "kept-app-id", "read_only": true},
+		{"filename": "skipped.yaml", "app_id": "skipped-app-id", "skip": true}
+	]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	logPath := filepath.Join(filepath.Dir(appMapPath), "audit.jsonl")
+	config := syncer.(*DefaultSyncer).config
+	config.AuditLogFile = logPath
+	config.DeleteOrphans = true
+	syncer = NewSyncer(config)
+
+	if _, err := syncer.SyncAll(); err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if err := syncer.(*DefaultSyncer).Close(); err != nil {
+		t.Fatalf("Failed to close syncer: %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+
+	actions := make(map[string]auditEntry)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse audit entry %q: %v", line, err)
+		}
+		actions[entry.Filename] = entry
+	}
+
+	if entry := actions["test.yaml"]; entry.Action != ActionRename || entry.NewFilename != "Test_App.yaml" {
+		t.Errorf("Expected the rename to be audited, got %+v", entry)
+	}
+	if entry := actions["gone.yaml"]; entry.Action != ActionDelete || !entry.Success {
+		t.Errorf("Expected the deletion to be audited, got %+v", entry)
+	}
+	if entry, ok := actions["kept.yaml"]; !ok || entry.Action != ActionNone || entry.AppID != "kept-app-id" {
+		t.Errorf("Expected the kept app to be audited, got %+v", entry)
+	}
+	if entry := actions["skipped.yaml"]; entry.Action != ActionSkip || entry.AppID != "skipped-app-id" {
+		t.Errorf("Expected the skipped app to be audited, got %+v", entry)
+	}
+}
@@ -0,0 +1,21 @@
+package syncer
+
+import "time"
+
+// Clock tells the syncer the current time. It allows tests to use a fixed time.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock backed by the system time
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// now returns the current time of the configured Clock, defaulting to the system time
+func (s *DefaultSyncer) now() time.Time {
+	if s.config.Clock == nil {
+		return realClock{}.Now()
+	}
+	return s.config.Clock.Now()
+}
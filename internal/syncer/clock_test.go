@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that returns a fixed time until it is advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestSyncAppWithFakeClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	// Dify reports the app as updated at remoteUpdatedAt
+	var remoteUpdatedAt time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps/test-app-id":
+			w.Write([]byte(`{"id": "test-app-id", "name": "Test App", "updated_at": "` + remoteUpdatedAt.Format(time.RFC3339) + `"}`))
+		case "/console/api/apps/test-app-id/export":
+			w.Write([]byte(`{"data": "name: Test App"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Local files get their modification time from the same clock
+	dslDir := filepath.Join("/mem", "dsl")
+	store := newMemFileStore()
+	store.clock = clock
+	if err := store.MkdirAll(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+	if err := store.WriteFile(filepath.Join(dslDir, "test.yaml"), []byte("name: Old"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		FileStore:    store,
+		Clock:        clock,
+	})
+	app := AppMapping{Filename: "test.yaml", AppID: "test-app-id"}
+
+	// Local newer than remote: nothing to do
+	remoteUpdatedAt = clock.now.Add(-time.Hour)
+	result := syncer.SyncApp(app)
+	if result.Action != ActionNone || !result.Success {
+		t.Errorf("Expected no action for a newer local file, got %s (%v)", result.Action, result.Error)
+	}
+	if !result.Timestamp.Equal(clock.now) {
+		t.Errorf("Expected the result timestamp %v, got %v", clock.now, result.Timestamp)
+	}
+
+	// Remote newer than local: download
+	clock.now = clock.now.Add(time.Minute)
+	remoteUpdatedAt = clock.now.Add(time.Hour)
+	result = syncer.SyncApp(app)
+	if result.Action != ActionDownload || !result.Success {
+		t.Errorf("Expected a download for a newer remote app, got %s (%v)", result.Action, result.Error)
+	}
+	if !result.Timestamp.Equal(clock.now) {
+		t.Errorf("Expected the result timestamp %v, got %v", clock.now, result.Timestamp)
+	}
+}
+
+func TestWarnTokenExpiryWithFakeClock(t *testing.T) {
+	// The token lives for an hour
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/console/api/login" {
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token", "expires_in": 3600}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	var logs bytes.Buffer
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		Clock:        clock,
+		LogOutput:    &logs,
+	}).(*DefaultSyncer)
+
+	// At one request per second, 2000 apps take longer than the token lives
+	syncer.config.RateLimit = 1
+	syncer.warnTokenExpiry(2000)
+	if !strings.Contains(logs.String(), "The access token expires in 1h0m0s") {
+		t.Errorf("Expected a token expiry warning, got %q", logs.String())
+	}
+
+	// The remaining lifetime is measured with the syncer's clock
+	logs.Reset()
+	clock.now = clock.now.Add(30 * time.Minute)
+	syncer.warnTokenExpiry(2000)
+	if !strings.Contains(logs.String(), "The access token expires in 30m0s") {
+		t.Errorf("Expected the remaining lifetime from the fake clock, got %q", logs.String())
+	}
+}
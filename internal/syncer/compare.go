@@ -0,0 +1,55 @@
+package syncer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sameContent reports whether a local and a downloaded DSL are identical, apart from the
+// keys in IgnoreKeys, such as timestamps that Dify changes on every export. Documents
+// that cannot be parsed as YAML are compared byte by byte.
+func (s *DefaultSyncer) sameContent(local, remote []byte) bool {
+	if len(s.config.IgnoreKeys) == 0 {
+		return bytes.Equal(local, remote)
+	}
+
+	localCanonical, err := canonicalDSL(local, s.config.IgnoreKeys)
+	if err != nil {
+		return bytes.Equal(local, remote)
+	}
+	remoteCanonical, err := canonicalDSL(remote, s.config.IgnoreKeys)
+	if err != nil {
+		return bytes.Equal(local, remote)
+	}
+	return sha256.Sum256(localCanonical) == sha256.Sum256(remoteCanonical)
+}
+
+// canonicalDSL removes the given keys from a YAML document and marshals it again with
+// sorted keys, so that documents differing only in those keys or in formatting are equal.
+// Keys are dot-separated paths from the top level, e.g. "export_time" or "app.id".
+func canonicalDSL(data []byte, ignoreKeys []string) ([]byte, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	for _, key := range ignoreKeys {
+		deleteKeyPath(document, strings.Split(key, "."))
+	}
+	return yaml.Marshal(document)
+}
+
+// deleteKeyPath removes the value at path from a YAML mapping, if it exists
+func deleteKeyPath(document map[string]interface{}, path []string) {
+	for len(path) > 1 {
+		child, ok := document[path[0]].(map[string]interface{})
+		if !ok {
+			return
+		}
+		document, path = child, path[1:]
+	}
+	delete(document, path[0])
+}
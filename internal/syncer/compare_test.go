@@ -0,0 +1,74 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCanonicalDSL(t *testing.T) {
+	a := []byte("export_time: 1\napp:\n  id: one\n  name: Bot\nversion: 1\n")
+	b := []byte("version: 1\napp:\n  name: Bot\n  id: two\nexport_time: 2\n")
+
+	canonicalA, err := canonicalDSL(a, []string{"export_time", "app.id"})
+	if err != nil {
+		t.Fatalf("canonicalDSL failed: %v", err)
+	}
+	canonicalB, err := canonicalDSL(b, []string{"export_time", "app.id"})
+	if err != nil {
+		t.Fatalf("canonicalDSL failed: %v", err)
+	}
+	if string(canonicalA) != string(canonicalB) {
+		t.Errorf("Expected equal documents without the ignored keys, got:\n%s\nand:\n%s", canonicalA, canonicalB)
+	}
+
+	// Paths through missing or non-mapping values are ignored
+	if _, err := canonicalDSL(a, []string{"missing.key", "version.x"}); err != nil {
+		t.Errorf("Expected unknown paths to be ignored, got %v", err)
+	}
+}
+
+func TestSyncAppIgnoreKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		ignoreKeys []string
+		expected   SyncAction
+	}{
+		{name: "downloads when a key differs", expected: ActionDownload},
+		{name: "skips when only an ignored key differs", ignoreKeys: []string{"export_time"}, expected: ActionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer, _, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+			defer cleanup()
+			defaultSyncer := syncer.(*DefaultSyncer)
+			defaultSyncer.config.SkipUnchanged = true
+			defaultSyncer.config.IgnoreKeys = tt.ignoreKeys
+
+			// The local file matches the remote DSL except for its export time
+			local := "export_time: 2022-01-01\nname: Test App\nversion: 1.0.0\n"
+			if err := os.WriteFile(dslPath, []byte(local), 0644); err != nil {
+				t.Fatalf("Failed to write DSL file: %v", err)
+			}
+			oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			if err := os.Chtimes(dslPath, oldTime, oldTime); err != nil {
+				t.Fatalf("Failed to set file time: %v", err)
+			}
+
+			result := defaultSyncer.SyncApp(AppMapping{Filename: filepath.Base(dslPath), AppID: "test-app-id"})
+			if !result.Success || result.Action != tt.expected {
+				t.Errorf("Expected %s, got %+v", tt.expected, result)
+			}
+
+			content, err := os.ReadFile(dslPath)
+			if err != nil {
+				t.Fatalf("Failed to read DSL file: %v", err)
+			}
+			if kept := string(content) == local; kept != (tt.expected == ActionNone) {
+				t.Errorf("Unexpected DSL content after %s: %q", result.Action, content)
+			}
+		})
+	}
+}
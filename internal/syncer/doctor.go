@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileProblem describes a problem with a DSL file found by CheckFiles
+type FileProblem struct {
+	Filename string
+	// AppID is empty for files that are not in the app map
+	AppID   string
+	Problem string
+}
+
+// CheckFiles looks for DSL files left broken by an interrupted download or by hand:
+// app map entries whose file is missing, files that are empty or not valid YAML,
+// and files that are not in the app map.
+func (s *DefaultSyncer) CheckFiles() ([]FileProblem, error) {
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []FileProblem
+	_, apps := splitPatternEntries(appMap.Apps)
+	sortAppMappings(apps)
+	for _, app := range apps {
+		if app.Skip {
+			continue
+		}
+		if problem := s.checkFile(filepath.Join(s.config.DSLDirectory, app.Filename)); problem != "" {
+			problems = append(problems, FileProblem{Filename: app.Filename, AppID: app.AppID, Problem: problem})
+		}
+	}
+
+	orphans, err := s.FindOrphanedFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range orphans {
+		problems = append(problems, FileProblem{Filename: name, Problem: "not in the app map"})
+	}
+
+	return problems, nil
+}
+
+// checkFile returns what is wrong with a DSL file, or "" if it is a non-empty YAML document
+func (s *DefaultSyncer) checkFile(path string) string {
+	data, err := s.fileStore().ReadFile(path)
+	if os.IsNotExist(err) {
+		return "file is missing"
+	}
+	if err != nil {
+		return fmt.Sprintf("failed to read file: %v", err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return "file is empty"
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Sprintf("invalid YAML: %v", err)
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return "file is not a YAML mapping"
+	}
+
+	return ""
+}
@@ -0,0 +1,91 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	files := map[string]string{
+		"good.yaml":      "app:\n  name: Good\nkind: app\n",
+		"truncated.yaml": "app:\n  name: \"Trunc",
+		"empty.yaml":     "\n",
+		"extra.yaml":     "app:\n  name: Extra\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dslDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write DSL file: %v", err)
+		}
+	}
+
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	content := `{"version": 1, "apps": [
+		{"filename": "good.yaml", "app_id": "app-good"},
+		{"filename": "truncated.yaml", "app_id": "app-truncated"},
+		{"filename": "empty.yaml", "app_id": "app-empty"},
+		{"filename": "missing.yaml", "app_id": "app-missing"},
+		{"filename": "skipped.yaml", "app_id": "app-skipped", "skip": true}
+	]}`
+	if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	syncer := &DefaultSyncer{config: Config{DSLDirectory: dslDir, AppMapFile: appMapPath}}
+	problems, err := syncer.CheckFiles()
+	if err != nil {
+		t.Fatalf("CheckFiles failed: %v", err)
+	}
+
+	got := make(map[string]string, len(problems))
+	for _, problem := range problems {
+		got[problem.Filename] = problem.Problem
+	}
+
+	if len(problems) != 4 {
+		t.Errorf("Expected 4 problems, got %+v", problems)
+	}
+	if got["missing.yaml"] != "file is missing" {
+		t.Errorf("Expected missing.yaml to be reported missing, got %q", got["missing.yaml"])
+	}
+	if !strings.HasPrefix(got["truncated.yaml"], "invalid YAML") {
+		t.Errorf("Expected truncated.yaml to be reported as invalid YAML, got %q", got["truncated.yaml"])
+	}
+	if got["empty.yaml"] != "file is empty" {
+		t.Errorf("Expected empty.yaml to be reported empty, got %q", got["empty.yaml"])
+	}
+	if got["extra.yaml"] != "not in the app map" {
+		t.Errorf("Expected extra.yaml to be reported as not in the app map, got %q", got["extra.yaml"])
+	}
+	if _, ok := got["good.yaml"]; ok {
+		t.Error("Expected good.yaml to have no problems")
+	}
+
+	// Problems of mapped files carry the app ID
+	for _, problem := range problems {
+		if problem.Filename == "missing.yaml" && problem.AppID != "app-missing" {
+			t.Errorf("Expected app ID app-missing, got %q", problem.AppID)
+		}
+	}
+}
+
+func TestCheckFilesNoProblems(t *testing.T) {
+	syncer, _, _, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	problems, err := syncer.(*DefaultSyncer).CheckFiles()
+	if err != nil {
+		t.Fatalf("CheckFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(problems, []FileProblem(nil)) {
+		t.Errorf("Expected no problems, got %+v", problems)
+	}
+}
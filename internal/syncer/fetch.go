@@ -0,0 +1,50 @@
+package syncer
+
+import "fmt"
+
+// FetchAll downloads the DSL of every app in the app map into memory, keyed by filename.
+// Nothing is written, renamed or deleted; apps marked skip are left out. Pattern entries
+// are expanded from the workspace app list. It stops at the first app that fails.
+func (s *DefaultSyncer) FetchAll() (map[string][]byte, error) {
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, apps := splitPatternEntries(appMap.Apps)
+	if len(patterns) > 0 || hasNamedEntries(apps) {
+		remoteAppList, err := s.listRemoteApps()
+		if err != nil {
+			return nil, err
+		}
+		apps, err = s.resolveNamedEntries(apps, remoteAppList)
+		if err != nil {
+			return nil, err
+		}
+		expandedApps, err := s.expandPatterns(patterns, apps, remoteAppList)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand app map patterns: %w", err)
+		}
+		apps = append(apps, expandedApps...)
+	}
+	sortAppMappings(apps)
+
+	dsls := make(map[string][]byte, len(apps))
+	for _, app := range apps {
+		if app.Skip {
+			continue
+		}
+
+		dsl, err := s.getDSL(app.AppID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get DSL for %s (app_id: %s): %w", app.Filename, app.AppID, err)
+		}
+		dsls[app.Filename] = normalizeLineEndings(dsl, s.config.LineEnding)
+
+		if s.config.Verbose {
+			fmt.Printf("Fetched %s (app_id: %s)\n", app.Filename, app.AppID)
+		}
+	}
+
+	return dsls, nil
+}
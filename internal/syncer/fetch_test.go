@@ -0,0 +1,40 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFetchAll(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// The local file is missing and a skipped app is mapped as well
+	if err := os.Remove(dslPath); err != nil {
+		t.Fatalf("Failed to remove DSL file: %v", err)
+	}
+	appMap := `{"apps": [{"filename": "test.yaml", "app_id": "test-app-id"}, {"filename": "skipped.yaml", "app_id": "skipped-app-id", "skip": true}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMap), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+
+	root := filepath.Dir(dslDir)
+	before := snapshotDir(t, root)
+
+	dsls, err := syncer.FetchAll()
+	if err != nil {
+		t.Fatalf("Failed to fetch all: %v", err)
+	}
+
+	expected := map[string][]byte{"test.yaml": []byte("name: Test App\nversion: 1.0.0")}
+	if !reflect.DeepEqual(dsls, expected) {
+		t.Errorf("Expected %q, got %q", expected, dsls)
+	}
+
+	// Nothing is written, not even the missing DSL file or a renamed one
+	if after := snapshotDir(t, root); !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected FetchAll to leave files unchanged, before %v, after %v", before, after)
+	}
+}
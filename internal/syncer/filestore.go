@@ -0,0 +1,133 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileStore is the filesystem used by the syncer for DSL files, the app map and the files
+// kept next to it: the lock, state, progress and manifest files. Output written for other
+// tools, i.e. the audit log, metrics, reports and archives, always uses the local filesystem.
+// It allows the syncer to be tested in memory or to keep files in other backends.
+type FileStore interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Remove(name string) error
+	Rename(oldpath, newpath string) error
+	MkdirAll(path string, perm os.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// chmodFileStore is implemented by file stores that can change the permissions of
+// an existing file, which WriteFile leaves untouched
+type chmodFileStore interface {
+	Chmod(name string, mode os.FileMode) error
+}
+
+// lstatFileStore is implemented by file stores that can tell symbolic links apart
+// from the files they point to
+type lstatFileStore interface {
+	Lstat(name string) (os.FileInfo, error)
+}
+
+// exclusiveFileStore is implemented by file stores that can create a file only if it
+// does not exist yet, which is needed to lock the app map
+type exclusiveFileStore interface {
+	CreateExclusive(name string, data []byte) error
+}
+
+// readDirFileStore is implemented by file stores that can list the entries of a directory,
+// which is needed to find orphaned DSL files
+type readDirFileStore interface {
+	ReadDir(name string) ([]os.DirEntry, error)
+}
+
+// osFileStore is the default FileStore backed by the local filesystem
+type osFileStore struct{}
+
+func (osFileStore) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+func (osFileStore) Lstat(name string) (os.FileInfo, error) { return os.Lstat(name) }
+
+func (osFileStore) ReadFile(name string) ([]byte, error) { return os.ReadFile(name) }
+
+func (osFileStore) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+
+func (osFileStore) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (osFileStore) CreateExclusive(name string, data []byte) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (osFileStore) Chmod(name string, mode os.FileMode) error { return os.Chmod(name, mode) }
+
+func (osFileStore) Remove(name string) error { return os.Remove(name) }
+
+func (osFileStore) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+func (osFileStore) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+func (osFileStore) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// fileStore returns the configured FileStore, defaulting to the local filesystem
+func (s *DefaultSyncer) fileStore() FileStore {
+	if s.config.FileStore == nil {
+		return osFileStore{}
+	}
+	return s.config.FileStore
+}
+
+// readDir lists a directory through the file store, if it supports listing directories
+func (s *DefaultSyncer) readDir(name string) ([]os.DirEntry, error) {
+	store, ok := s.fileStore().(readDirFileStore)
+	if !ok {
+		return nil, fmt.Errorf("file store cannot list directory %s", name)
+	}
+	return store.ReadDir(name)
+}
+
+// defaultFileMode is the permission of written DSL files when Config.FileMode is not set
+const defaultFileMode os.FileMode = 0644
+
+// fileMode returns the configured permission of DSL files, defaulting to 0644
+func (s *DefaultSyncer) fileMode() os.FileMode {
+	if s.config.FileMode == 0 {
+		return defaultFileMode
+	}
+	return s.config.FileMode
+}
+
+// writeDSLFile writes a DSL file with the configured permissions, also applying them
+// to an existing file if the file store supports it. A DSL file that is a symbolic link
+// is only written through to its target with FollowSymlinks.
+func (s *DefaultSyncer) writeDSLFile(path string, data []byte) error {
+	if store, ok := s.fileStore().(lstatFileStore); ok && !s.config.FollowSymlinks {
+		if info, err := store.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symbolic link; use --follow-symlinks to write through to its target", path)
+		}
+	}
+
+	if err := s.fileStore().WriteFile(path, data, s.fileMode()); err != nil {
+		return err
+	}
+	if store, ok := s.fileStore().(chmodFileStore); ok {
+		if err := store.Chmod(path, s.fileMode()); err != nil {
+			return fmt.Errorf("failed to set file permissions: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package syncer
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFileStore is an in-memory FileStore
+type memFileStore struct {
+	mu    sync.Mutex
+	files map[string]memFile
+	dirs  map[string]bool
+	// clock sets the modification time of written files (default: the system time)
+	clock Clock
+}
+
+type memFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func newMemFileStore() *memFileStore {
+	return &memFileStore{files: map[string]memFile{}, dirs: map[string]bool{}}
+}
+
+// memFileInfo describes a file or directory of a memFileStore
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.dir }
+func (i memFileInfo) Sys() interface{}   { return nil }
+
+func (i memFileInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (m *memFileStore) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if file, ok := m.files[name]; ok {
+		return memFileInfo{name: filepath.Base(name), size: int64(len(file.data)), modTime: file.modTime}, nil
+	}
+	if m.dirs[name] {
+		return memFileInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (m *memFileStore) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return append([]byte(nil), file.data...), nil
+}
+
+func (m *memFileStore) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if !m.dirs[filepath.Dir(name)] {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	modTime := time.Now()
+	if m.clock != nil {
+		modTime = m.clock.Now()
+	}
+	m.files[name] = memFile{data: append([]byte(nil), data...), modTime: modTime}
+	return nil
+}
+
+func (m *memFileStore) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFileStore) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	file, ok := m.files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	delete(m.files, oldpath)
+	m.files[newpath] = file
+	return nil
+}
+
+func (m *memFileStore) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := filepath.Clean(path); !m.dirs[dir]; dir = filepath.Dir(dir) {
+		m.dirs[dir] = true
+	}
+	return nil
+}
+
+func (m *memFileStore) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	file, ok := m.files[name]
+	if !ok {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrNotExist}
+	}
+	file.modTime = mtime
+	m.files[name] = file
+	return nil
+}
+
+func (m *memFileStore) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if !m.dirs[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	var entries []os.DirEntry
+	for path, file := range m.files {
+		if filepath.Dir(path) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: filepath.Base(path), size: int64(len(file.data)), modTime: file.modTime}))
+		}
+	}
+	for dir := range m.dirs {
+		if dir != name && filepath.Dir(dir) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: filepath.Base(dir), dir: true}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// names returns the paths of all files in the store, sorted
+func (m *memFileStore) names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSyncerWithMemFileStore(t *testing.T) {
+	syncer, _, _, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	store := newMemFileStore()
+	defaultSyncer := syncer.(*DefaultSyncer)
+	defaultSyncer.config.FileStore = store
+	defaultSyncer.config.DSLDirectory = "/mem/dsl"
+	defaultSyncer.config.AppMapFile = "/mem/app_map.json"
+
+	// Init writes the app map and the initial DSL into the store
+	appMap, err := defaultSyncer.InitializeAppMap()
+	if err != nil {
+		t.Fatalf("InitializeAppMap failed: %v", err)
+	}
+	if len(appMap.Apps) != 1 || appMap.Apps[0].Filename != "Test_App.yaml" {
+		t.Fatalf("Unexpected app map: %+v", appMap.Apps)
+	}
+
+	dslPath := filepath.Join("/mem/dsl", "Test_App.yaml")
+	want := []string{"/mem/app_map.json", dslPath}
+	if got := store.names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v in the store, got %v", want, got)
+	}
+	if _, err := os.Stat("/mem"); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written to the local filesystem")
+	}
+
+	// An outdated local file is downloaded again by sync
+	if err := store.WriteFile(dslPath, []byte("name: Old App"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Chtimes(dslPath, oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+
+	// The state, progress and manifest files are kept in the store as well
+	defaultSyncer.config.SinceLastRun = true
+	defaultSyncer.config.Resume = true
+	defaultSyncer.config.ManifestFile = "/mem/manifest.json"
+
+	stats, err := defaultSyncer.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if stats.Downloads != 1 || stats.Errors != 0 {
+		t.Errorf("Expected 1 download and no errors, got %+v", stats)
+	}
+
+	data, err := store.ReadFile(dslPath)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded DSL: %v", err)
+	}
+	if string(data) != "name: Test App\nversion: 1.0.0" {
+		t.Errorf("Unexpected DSL content: %q", data)
+	}
+
+	want = []string{"/mem/.difync-state.json", "/mem/app_map.json", dslPath, "/mem/manifest.json"}
+	if got := store.names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v in the store, got %v", want, got)
+	}
+	if _, err := os.Stat("/mem"); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written to the local filesystem")
+	}
+}
+
+func TestDownloadFromRemoteFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File permissions are not supported on Windows")
+	}
+
+	syncer, _, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+	syncer.(*DefaultSyncer).config.FileMode = 0600
+
+	// The existing file is written with the requested mode as well
+	result := syncer.(*DefaultSyncer).downloadFromRemote(AppMapping{
+		Filename: filepath.Base(dslPath),
+		AppID:    "test-app-id",
+	}, dslPath)
+	if !result.Success {
+		t.Fatalf("Expected download to succeed, got %v", result.Error)
+	}
+
+	info, err := os.Stat(dslPath)
+	if err != nil {
+		t.Fatalf("Failed to stat DSL file: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected mode 0600, got %#o", mode)
+	}
+}
+
+func TestDownloadFromRemoteSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Creating symbolic links needs extra privileges on Windows")
+	}
+
+	syncer, _, dslDir, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+	defaultSyncer := syncer.(*DefaultSyncer)
+
+	// The DSL file links to a central store
+	target := filepath.Join(t.TempDir(), "central.yaml")
+	if err := os.WriteFile(target, []byte("name: Old"), 0644); err != nil {
+		t.Fatalf("Failed to write target file: %v", err)
+	}
+	linkPath := filepath.Join(dslDir, "linked.yaml")
+	if err := os.Symlink(target, linkPath); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	app := AppMapping{Filename: "linked.yaml", AppID: "test-app-id"}
+
+	// By default the symlink is not overwritten
+	result := defaultSyncer.downloadFromRemote(app, linkPath)
+	if result.Success || result.Error == nil || !strings.Contains(result.Error.Error(), "--follow-symlinks") {
+		t.Errorf("Expected an error suggesting --follow-symlinks, got %v", result.Error)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "name: Old" {
+		t.Errorf("Expected the target to be unchanged, got %q", content)
+	}
+
+	// With FollowSymlinks the target is written and the link is kept
+	defaultSyncer.config.FollowSymlinks = true
+	result = defaultSyncer.downloadFromRemote(app, linkPath)
+	if !result.Success {
+		t.Fatalf("Expected download to succeed, got %v", result.Error)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "name: Test App\nversion: 1.0.0" {
+		t.Errorf("Expected the target to be written, got %q", content)
+	}
+	if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the DSL file to remain a symlink, got %v", err)
+	}
+}
+
+func TestOrphanedFilesWithMemFileStore(t *testing.T) {
+	syncer, _, _, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	store := newMemFileStore()
+	defaultSyncer := syncer.(*DefaultSyncer)
+	defaultSyncer.config.FileStore = store
+	defaultSyncer.config.DSLDirectory = "/mem/dsl"
+	defaultSyncer.config.AppMapFile = "/mem/app_map.json"
+	defaultSyncer.config.LogOutput = io.Discard
+
+	if _, err := defaultSyncer.InitializeAppMap(); err != nil {
+		t.Fatalf("Failed to initialize app map: %v", err)
+	}
+	for _, name := range []string{"stale.yaml", "notes.txt"} {
+		if err := store.WriteFile(filepath.Join("/mem/dsl", name), []byte("name: Stale"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// Orphans are listed and removed through the store
+	orphans, err := defaultSyncer.FindOrphanedFiles()
+	if err != nil {
+		t.Fatalf("Failed to find orphaned files: %v", err)
+	}
+	if len(orphans) != 1 || orphans[0] != "stale.yaml" {
+		t.Fatalf("Expected stale.yaml to be orphaned, got %v", orphans)
+	}
+
+	removed, err := defaultSyncer.RemoveOrphanedFiles(orphans)
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected 1 file to be removed, got %d: %v", removed, err)
+	}
+	if _, err := store.Stat("/mem/dsl/stale.yaml"); !os.IsNotExist(err) {
+		t.Errorf("Expected stale.yaml to be removed from the store, got %v", err)
+	}
+	if _, err := store.Stat("/mem/dsl/notes.txt"); err != nil {
+		t.Errorf("Expected notes.txt to be kept: %v", err)
+	}
+
+	// A file that is already gone is reported
+	removed, err = defaultSyncer.RemoveOrphanedFiles(orphans)
+	if err == nil || removed != 0 {
+		t.Errorf("Expected an error for a missing file, got %d removed: %v", removed, err)
+	}
+}
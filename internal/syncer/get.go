@@ -0,0 +1,60 @@
+package syncer
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// GetAppDSL downloads the DSL of a single app by ID without using the app map.
+// It fails with a clear error if the app does not exist in Dify.
+func (s *DefaultSyncer) GetAppDSL(appID string) ([]byte, error) {
+	exists, err := s.client.DoesDSLExist(appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if app %s exists: %w", appID, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("app %s does not exist in Dify", appID)
+	}
+
+	dsl, err := s.getDSL(appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DSL of app %s: %w", appID, err)
+	}
+
+	return normalizeLineEndings(dsl, s.config.LineEnding), nil
+}
+
+// ResolveAppID returns the app ID of the app map entry with the given filename, so that
+// ref may name either a DSL file or an app. If no entry has that filename, or there is
+// no app map, ref is returned as the app ID.
+func (s *DefaultSyncer) ResolveAppID(ref string) (string, error) {
+	if s.config.NoAppMap || !s.fileExists(s.config.AppMapFile) {
+		return ref, nil
+	}
+
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return "", err
+	}
+
+	for _, app := range appMap.Apps {
+		if app.Match != nil || app.Filename != filepath.ToSlash(ref) {
+			continue
+		}
+		if !isNamedEntry(app) {
+			return app.AppID, nil
+		}
+
+		remoteAppList, err := s.listRemoteApps()
+		if err != nil {
+			return "", err
+		}
+		ids := appIDsByName(remoteAppList)[app.Name]
+		if len(ids) != 1 {
+			return "", fmt.Errorf("found %d apps named %q in Dify for %s, expected exactly one", len(ids), app.Name, app.Filename)
+		}
+		return ids[0], nil
+	}
+
+	return ref, nil
+}
@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+// isNamedEntry reports whether an app map entry identifies its app by name instead of app ID
+func isNamedEntry(app AppMapping) bool {
+	return app.Match == nil && app.AppID == "" && app.Name != ""
+}
+
+// entryKey identifies the app map entry of an app. Entries identified by name keep an
+// empty app ID in the app map, so they are told apart by their name, also once resolved.
+func entryKey(app AppMapping) string {
+	if app.byName || isNamedEntry(app) {
+		return "name:" + app.Name
+	}
+	return app.AppID
+}
+
+// hasNamedEntries reports whether any app map entry identifies its app by name
+func hasNamedEntries(apps []AppMapping) bool {
+	for _, app := range apps {
+		if isNamedEntry(app) {
+			return true
+		}
+	}
+	return false
+}
+
+// appIDsByName returns the IDs of the remote apps by app name
+func appIDsByName(remoteAppList []api.AppInfo) map[string][]string {
+	idsByName := make(map[string][]string)
+	for _, remoteApp := range remoteAppList {
+		idsByName[remoteApp.Name] = append(idsByName[remoteApp.Name], remoteApp.ID)
+	}
+	return idsByName
+}
+
+// resolveNamedEntries sets the app ID of entries identified by name to the current ID of
+// the remote app with that name, so the entries keep working when an app is recreated in
+// Dify. Entries without a matching remote app are left out with a warning. A name shared
+// by several remote apps is an error, as the entry cannot tell them apart.
+func (s *DefaultSyncer) resolveNamedEntries(apps []AppMapping, remoteAppList []api.AppInfo) ([]AppMapping, error) {
+	if !hasNamedEntries(apps) {
+		return apps, nil
+	}
+
+	idsByName := appIDsByName(remoteAppList)
+	resolved := make([]AppMapping, 0, len(apps))
+	for _, app := range apps {
+		if !isNamedEntry(app) {
+			resolved = append(resolved, app)
+			continue
+		}
+
+		ids := idsByName[app.Name]
+		switch len(ids) {
+		case 0:
+			fmt.Fprintf(s.config.logOutput(), "Warning: No app named %q found in Dify for %s, skipping it\n", app.Name, app.Filename)
+		case 1:
+			app.AppID = ids[0]
+			app.byName = true
+			resolved = append(resolved, app)
+		default:
+			return nil, fmt.Errorf("app name %q of %s matches several apps (%s), use app_id instead", app.Name, app.Filename, strings.Join(ids, ", "))
+		}
+	}
+	return resolved, nil
+}
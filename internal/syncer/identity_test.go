@@ -0,0 +1,147 @@
+package syncer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+func TestResolveNamedEntries(t *testing.T) {
+	remoteApps := []api.AppInfo{
+		{ID: "app-1", Name: "Support Bot"},
+		{ID: "app-2", Name: "Twin"},
+		{ID: "app-3", Name: "Twin"},
+	}
+
+	apps, err := (&DefaultSyncer{}).resolveNamedEntries([]AppMapping{
+		{Filename: "fixed.yaml", AppID: "app-9", Name: "Support Bot"},
+		{Filename: "support.yaml", Name: "Support Bot"},
+		{Filename: "gone.yaml", Name: "Deleted Bot"},
+	}, remoteApps)
+	if err != nil {
+		t.Fatalf("resolveNamedEntries failed: %v", err)
+	}
+
+	// The app ID takes precedence, names are resolved and unknown names are left out
+	if len(apps) != 2 || apps[0].AppID != "app-9" || apps[1].AppID != "app-1" {
+		t.Errorf("Unexpected resolved entries: %+v", apps)
+	}
+
+	// A name shared by several apps is ambiguous
+	_, err = (&DefaultSyncer{}).resolveNamedEntries([]AppMapping{{Filename: "twin.yaml", Name: "Twin"}}, remoteApps)
+	if err == nil || !strings.Contains(err.Error(), "app-2, app-3") {
+		t.Errorf("Expected an error listing both apps, got %v", err)
+	}
+}
+
+func TestSyncAllWithRecreatedApp(t *testing.T) {
+	tmpDir := t.TempDir()
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	// The entry was written for an app that has since been deleted and recreated
+	dslPath := filepath.Join(dslDir, "support.yaml")
+	if err := os.WriteFile(dslPath, []byte("name: Support Bot\nversion: 1"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+	oldTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(dslPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	appMapData := `{"apps": [{"filename": "support.yaml", "app_id": "", "name": "Support Bot"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "new-id", "name": "Support Bot", "updated_at": "2024-01-01T00:00:00Z"}]}`))
+		case "/console/api/apps/new-id":
+			w.Write([]byte(`{"data": {"id": "new-id", "name": "Support Bot", "updated_at": "2024-01-01T00:00:00Z"}}`))
+		case "/console/api/apps/new-id/export":
+			w.Write([]byte(`{"data": "name: Support Bot\nversion: 2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+	})
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if stats.Downloads != 1 || stats.Errors != 0 || stats.Renamed != 0 {
+		t.Errorf("Expected the recreated app to be downloaded, got %+v", stats)
+	}
+
+	content, err := os.ReadFile(dslPath)
+	if err != nil {
+		t.Fatalf("Failed to read DSL file: %v", err)
+	}
+	if string(content) != "name: Support Bot\nversion: 2" {
+		t.Errorf("Unexpected DSL content: %q", content)
+	}
+
+	// The entry keeps identifying the app by name
+	data, err := os.ReadFile(appMapPath)
+	if err != nil {
+		t.Fatalf("Failed to read app map file: %v", err)
+	}
+	var appMap AppMap
+	if err := json.Unmarshal(data, &appMap); err != nil {
+		t.Fatalf("Failed to unmarshal app map: %v", err)
+	}
+	if len(appMap.Apps) != 1 || appMap.Apps[0].AppID != "" || appMap.Apps[0].Name != "Support Bot" {
+		t.Fatalf("Expected the named entry to be kept, got %+v", appMap.Apps)
+	}
+
+	// The sync timestamps are recorded on the named entry
+	if appMap.Apps[0].LastSyncedAt == nil || appMap.Apps[0].LastRemoteUpdatedAt == nil {
+		t.Errorf("Expected the named entry to record its sync timestamps, got %+v", appMap.Apps[0])
+	}
+}
+
+func TestSyncAllRenamesEntryWithAppIDAndName(t *testing.T) {
+	syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// The app ID takes precedence over the name, so the entry is renamed like any other
+	appMapData := `{"apps": [{"filename": "test.yaml", "app_id": "test-app-id", "name": "Old Name"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if stats.Renamed != 1 {
+		t.Errorf("Expected the entry to be renamed, got %+v", stats)
+	}
+	if _, err := os.Stat(filepath.Join(dslDir, "Test_App.yaml")); err != nil {
+		t.Errorf("Expected the renamed file to exist: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFilename is the file in the DSL directory listing files that are not managed by Difync
+const ignoreFilename = ".difyncignore"
+
+// ignoreRule is a single pattern line of an ignore file
+type ignoreRule struct {
+	pattern string
+	// negate re-includes files matched by earlier rules ("!pattern")
+	negate bool
+	// anchored patterns contain a slash and match the whole path relative to the DSL directory;
+	// other patterns match any path element
+	anchored bool
+	// dirOnly patterns end in a slash and match directories, and so every file below them
+	dirOnly bool
+}
+
+// ignoreMatcher matches paths against gitignore-style rules. A nil matcher matches nothing.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// loadIgnore reads the ignore file of the DSL directory. A missing file ignores nothing.
+func (s *DefaultSyncer) loadIgnore() (*ignoreMatcher, error) {
+	data, err := s.fileStore().ReadFile(filepath.Join(s.config.DSLDirectory, ignoreFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ignoreFilename, err)
+	}
+	return parseIgnore(string(data))
+}
+
+// parseIgnore parses gitignore-style patterns, one per line. Blank lines and lines
+// starting with # are skipped.
+func parseIgnore(content string) (*ignoreMatcher, error) {
+	matcher := &ignoreMatcher{}
+	for number, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q on line %d of %s: %w", line, number+1, ignoreFilename, err)
+		}
+
+		rule.pattern = line
+		matcher.rules = append(matcher.rules, rule)
+	}
+	return matcher, nil
+}
+
+// match reports whether a file, given by its slash-separated path relative to the
+// DSL directory, is ignored. The last matching rule decides.
+func (m *ignoreMatcher) match(name string) bool {
+	if m == nil {
+		return false
+	}
+
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.matches(name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches checks a single rule against a file path
+func (r ignoreRule) matches(name string) bool {
+	elements := strings.Split(name, "/")
+
+	// Directory rules match the directories containing the file, not the file itself
+	candidates := elements
+	if r.dirOnly {
+		candidates = elements[:len(elements)-1]
+	}
+
+	if r.anchored {
+		for i := range candidates {
+			if ok, _ := path.Match(r.pattern, strings.Join(elements[:i+1], "/")); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, element := range candidates {
+		if ok, _ := path.Match(r.pattern, element); ok {
+			return true
+		}
+	}
+	return false
+}
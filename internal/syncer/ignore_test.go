@@ -0,0 +1,96 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	matcher, err := parseIgnore(`
+# Hand-authored DSLs
+scratch-*.yaml
+drafts/
+/local/*.yml
+!scratch-keep.yaml
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse ignore file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ignored bool
+	}{
+		{"scratch-1.yaml", true},
+		{"team/scratch-2.yaml", true},
+		{"scratch-keep.yaml", false},
+		{"drafts/bot.yaml", true},
+		{"team/drafts/bot.yaml", true},
+		{"drafts.yaml", false},
+		{"local/bot.yml", true},
+		{"team/local/bot.yml", false},
+		{"bot.yaml", false},
+	}
+
+	for _, tt := range tests {
+		if ignored := matcher.match(tt.name); ignored != tt.ignored {
+			t.Errorf("match(%q) = %v, expected %v", tt.name, ignored, tt.ignored)
+		}
+	}
+
+	// A missing ignore file matches nothing
+	var none *ignoreMatcher
+	if none.match("scratch-1.yaml") {
+		t.Error("Expected a nil matcher to match nothing")
+	}
+
+	// Malformed patterns are reported with their line
+	if _, err := parseIgnore("ok.yaml\n[bad"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error for line 2, got %v", err)
+	}
+}
+
+func TestFindOrphanedFilesIgnore(t *testing.T) {
+	tmpDir := t.TempDir()
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	for _, name := range []string{"mapped.yaml", "stale.yaml", "scratch-idea.yaml"} {
+		if err := os.WriteFile(filepath.Join(dslDir, name), []byte("app: {}"), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dslDir, ignoreFilename), []byte("scratch-*.yaml\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "mapped.yaml", "app_id": "app-id-1"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	syncer := &DefaultSyncer{config: Config{DSLDirectory: dslDir, AppMapFile: appMapPath}}
+
+	// The ignored file is protected from prune and not reported by doctor
+	orphans, err := syncer.FindOrphanedFiles()
+	if err != nil {
+		t.Fatalf("Failed to find orphaned files: %v", err)
+	}
+	if strings.Join(orphans, ",") != "stale.yaml" {
+		t.Errorf("Expected only stale.yaml to be orphaned, got %v", orphans)
+	}
+
+	problems, err := syncer.CheckFiles()
+	if err != nil {
+		t.Fatalf("CheckFiles failed: %v", err)
+	}
+	for _, problem := range problems {
+		if problem.Filename == "scratch-idea.yaml" {
+			t.Errorf("Expected the ignored file not to be reported, got %+v", problem)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package syncer
+
+import "bytes"
+
+// Line ending modes for downloaded DSL files
+const (
+	// LineEndingPreserve writes DSL files exactly as Dify returns them
+	LineEndingPreserve = "preserve"
+	// LineEndingLF converts line endings to \n
+	LineEndingLF = "lf"
+	// LineEndingCRLF converts line endings to \r\n
+	LineEndingCRLF = "crlf"
+)
+
+// normalizeLineEndings converts the line endings of data to the given mode.
+// Only the \r\n and \n byte sequences are touched, which never occur inside
+// multibyte UTF-8 characters; a lone \r is left as is.
+func normalizeLineEndings(data []byte, mode string) []byte {
+	switch mode {
+	case LineEndingLF:
+		return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	case LineEndingCRLF:
+		lf := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))
+	default:
+		return data
+	}
+}
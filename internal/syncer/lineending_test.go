@@ -0,0 +1,84 @@
+package syncer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		mode     string
+		expected string
+	}{
+		{"lf from crlf", "a: 1\r\nb: 2\r\n", LineEndingLF, "a: 1\nb: 2\n"},
+		{"lf from mixed", "a: 1\r\nb: 2\n", LineEndingLF, "a: 1\nb: 2\n"},
+		{"crlf from lf", "a: 1\nb: 2\n", LineEndingCRLF, "a: 1\r\nb: 2\r\n"},
+		{"crlf keeps crlf", "a: 1\r\nb: 2\n", LineEndingCRLF, "a: 1\r\nb: 2\r\n"},
+		{"lone cr is kept", "a: 1\rb: 2\n", LineEndingLF, "a: 1\rb: 2\n"},
+		{"multibyte content", "名前: テスト\r\n説明: 日本語\r\n", LineEndingLF, "名前: テスト\n説明: 日本語\n"},
+		{"preserve", "a: 1\r\nb: 2\n", LineEndingPreserve, "a: 1\r\nb: 2\n"},
+		{"empty mode preserves", "a: 1\r\n", "", "a: 1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(normalizeLineEndings([]byte(tt.input), tt.mode))
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDownloadFromRemoteLineEnding(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Dify returns the DSL with CRLF line endings
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps/test-app-id/export":
+			w.Write([]byte(`{"data": "name: テスト\r\nversion: 1.0.0\r\n"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: tmpDir,
+		LineEnding:   LineEndingLF,
+		VerifyWrites: true,
+	})
+
+	localPath := filepath.Join(tmpDir, "test.yaml")
+	result := syncer.(*DefaultSyncer).downloadFromRemote(AppMapping{
+		Filename: "test.yaml",
+		AppID:    "test-app-id",
+	}, localPath)
+	if !result.Success {
+		t.Fatalf("Expected download to succeed, got %v", result.Error)
+	}
+
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if string(content) != "name: テスト\nversion: 1.0.0\n" {
+		t.Errorf("Expected LF line endings, got %q", content)
+	}
+}
@@ -0,0 +1,51 @@
+package syncer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrLocked indicates that another difync run holds the lock of the app map
+var ErrLocked = errors.New("another difync is running")
+
+// lockFileSuffix is appended to the app map path to get its lock file
+const lockFileSuffix = ".lock"
+
+// lockAppMap keeps other difync runs from reading or writing the app map until the
+// returned function is called. The lock is a file next to the app map holding the PID
+// of the run. Dry runs, NoLock, NoAppMap and file stores that cannot create files
+// exclusively do not lock. Without an app map directory there is nothing to lock yet.
+func (s *DefaultSyncer) lockAppMap() (func(), error) {
+	unlock := func() {}
+	if s.config.NoLock || s.config.NoAppMap || s.config.DryRun {
+		return unlock, nil
+	}
+	store, ok := s.fileStore().(exclusiveFileStore)
+	if !ok {
+		return unlock, nil
+	}
+
+	path := s.config.AppMapFile + lockFileSuffix
+	err := store.CreateExclusive(path, []byte(strconv.Itoa(os.Getpid())+"\n"))
+	switch {
+	case os.IsExist(err):
+		holder := "an unknown process"
+		if data, err := s.fileStore().ReadFile(path); err == nil && len(bytes.TrimSpace(data)) > 0 {
+			holder = "PID " + string(bytes.TrimSpace(data))
+		}
+		return nil, fmt.Errorf("%w: %s is locked by %s (remove the lock file if that run has ended, or use --no-lock)", ErrLocked, s.config.AppMapFile, holder)
+	case os.IsNotExist(err):
+		return unlock, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to lock app map: %w", err)
+	}
+
+	return func() {
+		if err := s.fileStore().Remove(path); err != nil {
+			fmt.Fprintf(s.config.logOutput(), "Warning: Failed to remove lock file %s: %v\n", path, err)
+		}
+	}, nil
+}
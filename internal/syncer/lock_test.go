@@ -0,0 +1,54 @@
+package syncer
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAppMapLock(t *testing.T) {
+	syncer, _, _, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+	first := syncer.(*DefaultSyncer)
+	second := NewSyncer(first.config).(*DefaultSyncer)
+
+	// The first run holds the lock while it syncs
+	unlock, err := first.lockAppMap()
+	if err != nil {
+		t.Fatalf("Failed to lock app map: %v", err)
+	}
+	lockPath := appMapPath + lockFileSuffix
+	if data, err := os.ReadFile(lockPath); err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected the lock file to hold the PID, got %q: %v", data, err)
+	}
+
+	// A second run fails to acquire it
+	_, err = second.SyncAll()
+	if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "PID "+strconv.Itoa(os.Getpid())) {
+		t.Errorf("Expected ErrLocked naming the PID, got %v", err)
+	}
+	if _, err := second.InitializeAppMap(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected init to fail with ErrLocked, got %v", err)
+	}
+
+	// NoLock runs anyway and leaves the lock of the first run alone
+	second.config.NoLock = true
+	if _, err := second.SyncAll(); err != nil {
+		t.Errorf("Expected sync with NoLock to succeed, got %v", err)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("Expected the lock file to remain, got %v", err)
+	}
+
+	// Once released, the lock can be acquired again and is removed after the run
+	unlock()
+	second.config.NoLock = false
+	if _, err := second.SyncAll(); err != nil {
+		t.Errorf("Expected sync to succeed after unlocking, got %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got %v", err)
+	}
+}
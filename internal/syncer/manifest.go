@@ -0,0 +1,86 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Manifest is a checksum snapshot of the DSL files on disk after a sync.
+// It lists the SHA-256 and size of every synced file, so the tree can be verified later.
+type Manifest struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Files       []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry records the checksum of one DSL file
+type ManifestEntry struct {
+	Filename string `json:"filename"`
+	AppID    string `json:"app_id"`
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size"`
+}
+
+// writeManifest hashes the files of apps in the DSL directory and writes the manifest
+// atomically to the configured path. Apps whose file does not exist are left out.
+func (s *DefaultSyncer) writeManifest(apps []AppMapping) error {
+	manifest := Manifest{
+		GeneratedAt: s.now().UTC(),
+		Files:       make([]ManifestEntry, 0, len(apps)),
+	}
+
+	for _, app := range apps {
+		data, err := s.fileStore().ReadFile(filepath.Join(s.config.DSLDirectory, app.Filename))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", app.Filename, err)
+		}
+
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Filename: app.Filename,
+			AppID:    app.AppID,
+			SHA256:   hex.EncodeToString(sum[:]),
+			Size:     int64(len(data)),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	return writeFileAtomic(s.fileStore(), s.config.ManifestFile, append(data, '\n'), 0644)
+}
+
+// finalApps returns apps as they are after a sync: deleted apps are dropped and
+// renamed apps use their new filename
+func finalApps(apps, deletedApps, renamedApps []AppMapping) []AppMapping {
+	deleted := make(map[string]bool, len(deletedApps))
+	for _, app := range deletedApps {
+		deleted[app.AppID] = true
+	}
+	renamed := make(map[string]string, len(renamedApps))
+	for _, app := range renamedApps {
+		renamed[app.AppID] = app.Filename
+	}
+
+	result := make([]AppMapping, 0, len(apps))
+	for _, app := range apps {
+		if deleted[app.AppID] {
+			continue
+		}
+		if filename, ok := renamed[app.AppID]; ok {
+			app.Filename = filename
+		}
+		result = append(result, app)
+	}
+	sortAppMappings(result)
+	return result
+}
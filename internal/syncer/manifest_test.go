@@ -0,0 +1,90 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncAllWritesManifest(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Use the remote name so the app is downloaded rather than renamed
+	renamedPath := filepath.Join(dslDir, "Test_App.yaml")
+	if err := os.Rename(dslPath, renamedPath); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(renamedPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	manifestPath := filepath.Join(filepath.Dir(appMapPath), "manifest.json")
+	syncer.(*DefaultSyncer).config.ManifestFile = manifestPath
+
+	if _, err := syncer.SyncAll(); err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	if len(manifest.Files) != 1 {
+		t.Fatalf("Expected 1 file in manifest, got %d", len(manifest.Files))
+	}
+	entry := manifest.Files[0]
+	if entry.Filename != "Test_App.yaml" || entry.AppID != "test-app-id" {
+		t.Errorf("Unexpected manifest entry: %+v", entry)
+	}
+
+	// The checksum must match the downloaded content on disk
+	content, err := os.ReadFile(renamedPath)
+	if err != nil {
+		t.Fatalf("Failed to read DSL file: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	if entry.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected sha256 %x, got %s", sum, entry.SHA256)
+	}
+	if entry.Size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d", len(content), entry.Size)
+	}
+	if manifest.GeneratedAt.IsZero() {
+		t.Error("Expected generated_at to be set")
+	}
+}
+
+func TestFinalApps(t *testing.T) {
+	apps := []AppMapping{
+		{Filename: "a.yaml", AppID: "a"},
+		{Filename: "b.yaml", AppID: "b"},
+		{Filename: "c.yaml", AppID: "c"},
+	}
+	deleted := []AppMapping{{Filename: "b.yaml", AppID: "b"}}
+	renamed := []AppMapping{{Filename: "0.yaml", AppID: "c"}}
+
+	result := finalApps(apps, deleted, renamed)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 apps, got %d", len(result))
+	}
+	if result[0].AppID != "c" || result[0].Filename != "0.yaml" {
+		t.Errorf("Expected renamed app first, got %+v", result[0])
+	}
+	if result[1].AppID != "a" {
+		t.Errorf("Expected app a, got %+v", result[1])
+	}
+}
@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+// AppMatch selects remote apps by pattern instead of a single app ID
+type AppMatch struct {
+	// Name is a glob pattern matched against the app name (e.g. "Generated *")
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// ID is a regular expression matched against the app ID (e.g. "^gen-")
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+}
+
+// matches checks if an app matches all patterns that are set
+func (m *AppMatch) matches(app api.AppInfo) (bool, error) {
+	if m.Name == "" && m.ID == "" {
+		return false, fmt.Errorf("match entry must set at least one of name or id")
+	}
+
+	if m.Name != "" {
+		ok, err := path.Match(m.Name, app.Name)
+		if err != nil {
+			return false, fmt.Errorf("invalid name pattern %q: %w", m.Name, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	if m.ID != "" {
+		re, err := regexp.Compile(m.ID)
+		if err != nil {
+			return false, fmt.Errorf("invalid id pattern %q: %w", m.ID, err)
+		}
+		if !re.MatchString(app.ID) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// splitPatternEntries separates pattern entries from concrete entries in the app map
+func splitPatternEntries(apps []AppMapping) (patterns, concrete []AppMapping) {
+	for _, app := range apps {
+		if app.Match != nil {
+			patterns = append(patterns, app)
+		} else {
+			concrete = append(concrete, app)
+		}
+	}
+	return patterns, concrete
+}
+
+// expandPatterns turns pattern entries into concrete app mappings for the matching remote apps.
+// Apps that already have a concrete entry are skipped, so concrete entries take precedence.
+// When several patterns match the same app, the first pattern in the app map wins.
+func (s *DefaultSyncer) expandPatterns(patterns, concrete []AppMapping, remoteAppList []api.AppInfo) ([]AppMapping, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	claimed := make(map[string]bool)
+	usedFilenames := make(map[string]bool)
+	for _, app := range concrete {
+		claimed[app.AppID] = true
+		usedFilenames[app.Filename] = true
+	}
+
+	// Expand in a stable order so duplicate names get the same suffixes on every run
+	remoteApps := make([]api.AppInfo, len(remoteAppList))
+	copy(remoteApps, remoteAppList)
+	sort.SliceStable(remoteApps, func(i, j int) bool {
+		if remoteApps[i].Name != remoteApps[j].Name {
+			return remoteApps[i].Name < remoteApps[j].Name
+		}
+		return remoteApps[i].ID < remoteApps[j].ID
+	})
+
+	expanded := []AppMapping{}
+	for _, pattern := range patterns {
+		for _, remoteApp := range remoteApps {
+			if claimed[remoteApp.ID] {
+				continue
+			}
+
+			ok, err := pattern.Match.matches(remoteApp)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			// Create a unique filename from the app name
+			safeName := s.sanitizeFilename(remoteApp.Name)
+			filename := safeName + s.dslExtension()
+			for counter := 1; usedFilenames[filename]; counter++ {
+				filename = fmt.Sprintf("%s_%d%s", safeName, counter, s.dslExtension())
+			}
+
+			claimed[remoteApp.ID] = true
+			usedFilenames[filename] = true
+			expanded = append(expanded, AppMapping{
+				Filename: filename,
+				AppID:    remoteApp.ID,
+				Skip:     pattern.Skip,
+				ReadOnly: pattern.ReadOnly,
+			})
+		}
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,219 @@
+package syncer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+func TestAppMatch(t *testing.T) {
+	app := api.AppInfo{ID: "gen-123", Name: "Generated Report"}
+
+	testCases := []struct {
+		match    AppMatch
+		expected bool
+		wantErr  bool
+		desc     string
+	}{
+		{
+			match:    AppMatch{Name: "Generated *"},
+			expected: true,
+			desc:     "Match name glob",
+		},
+		{
+			match:    AppMatch{Name: "Manual *"},
+			expected: false,
+			desc:     "Reject name glob",
+		},
+		{
+			match:    AppMatch{ID: "^gen-"},
+			expected: true,
+			desc:     "Match ID regex",
+		},
+		{
+			match:    AppMatch{ID: "^app-"},
+			expected: false,
+			desc:     "Reject ID regex",
+		},
+		{
+			match:    AppMatch{Name: "Generated *", ID: "^app-"},
+			expected: false,
+			desc:     "Require both patterns to match",
+		},
+		{
+			match:   AppMatch{ID: "("},
+			wantErr: true,
+			desc:    "Invalid ID regex",
+		},
+		{
+			match:   AppMatch{Name: "["},
+			wantErr: true,
+			desc:    "Invalid name glob",
+		},
+		{
+			match:   AppMatch{},
+			wantErr: true,
+			desc:    "Empty match",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := tc.match.matches(app)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestExpandPatterns(t *testing.T) {
+	syncer := &DefaultSyncer{}
+
+	remoteApps := []api.AppInfo{
+		{ID: "gen-2", Name: "Generated"},
+		{ID: "gen-1", Name: "Generated"},
+		{ID: "gen-3", Name: "Generated Concrete"},
+		{ID: "app-1", Name: "Manual"},
+	}
+
+	concrete := []AppMapping{
+		{Filename: "my-concrete.yaml", AppID: "gen-3"},
+	}
+	patterns := []AppMapping{
+		{Match: &AppMatch{ID: "^gen-"}},
+		{Match: &AppMatch{Name: "*"}},
+	}
+
+	expanded, err := syncer.expandPatterns(patterns, concrete, remoteApps)
+	if err != nil {
+		t.Fatalf("Failed to expand patterns: %v", err)
+	}
+
+	// The concrete entry wins for gen-3, and the second pattern only picks up the remaining app
+	expected := []AppMapping{
+		{Filename: "Generated.yaml", AppID: "gen-1"},
+		{Filename: "Generated_1.yaml", AppID: "gen-2"},
+		{Filename: "Manual.yaml", AppID: "app-1"},
+	}
+
+	if len(expanded) != len(expected) {
+		t.Fatalf("Expected %d expanded apps, got %d: %+v", len(expected), len(expanded), expanded)
+	}
+	for i, app := range expanded {
+		if app.Filename != expected[i].Filename || app.AppID != expected[i].AppID {
+			t.Errorf("Expected expanded app %d to be %+v, got %+v", i, expected[i], app)
+		}
+	}
+
+	// Invalid patterns are reported
+	_, err = syncer.expandPatterns([]AppMapping{{Match: &AppMatch{ID: "("}}}, nil, remoteApps)
+	if err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestSyncAllWithPatterns(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	appMapData := `{"apps": [{"match": {"id": "^gen-"}}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "gen-1", "name": "Generated One"}, {"id": "app-1", "name": "Manual"}]}`))
+		case "/console/api/apps/gen-1/export":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": "name: Generated One"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+	})
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+
+	if stats.Total != 1 || stats.Downloads != 1 {
+		t.Errorf("Expected 1 app downloaded, got Total=%d Downloads=%d", stats.Total, stats.Downloads)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dslDir, "Generated_One.yaml"))
+	if err != nil {
+		t.Fatalf("Expected matched app to be downloaded: %v", err)
+	}
+	if string(content) != "name: Generated One" {
+		t.Errorf("Unexpected DSL content: %s", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(dslDir, "Manual.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected unmatched app not to be downloaded")
+	}
+
+	// The pattern entry is kept and expanded apps are not written to the app map
+	data, err := os.ReadFile(appMapPath)
+	if err != nil {
+		t.Fatalf("Failed to read app map file: %v", err)
+	}
+	var appMap AppMap
+	if err := json.Unmarshal(data, &appMap); err != nil {
+		t.Fatalf("Failed to unmarshal app map: %v", err)
+	}
+	if len(appMap.Apps) != 1 || appMap.Apps[0].Match == nil {
+		t.Errorf("Expected app map to contain only the pattern entry, got %+v", appMap.Apps)
+	}
+
+	// Files of matched apps are not orphans
+	orphans, err := syncer.(*DefaultSyncer).FindOrphanedFiles()
+	if err != nil {
+		t.Fatalf("Failed to find orphaned files: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("Expected no orphans, got %v", orphans)
+	}
+}
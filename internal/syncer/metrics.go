@@ -0,0 +1,86 @@
+package syncer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lastSuccessMetric is the metric that is carried over from the previous metrics file
+// when a run has errors
+const lastSuccessMetric = "difync_last_success_timestamp"
+
+// WriteMetrics writes sync statistics to path in the Prometheus textfile collector format.
+// The file is replaced atomically so a collector never reads a partially written file.
+func WriteMetrics(path string, stats *SyncStats) error {
+	// Keep the last success time of an earlier run if this one had errors
+	lastSuccess := float64(0)
+	if stats.Errors == 0 {
+		lastSuccess = float64(stats.EndTime.UnixNano()) / 1e9
+	} else if previous, ok := readMetric(path, lastSuccessMetric); ok {
+		lastSuccess = previous
+	}
+
+	var buf bytes.Buffer
+	if err := renderMetrics(&buf, stats, lastSuccess); err != nil {
+		return fmt.Errorf("failed to render metrics: %w", err)
+	}
+
+	if err := writeFileAtomic(osFileStore{}, path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+
+	return nil
+}
+
+// renderMetrics writes the metrics with their HELP and TYPE lines
+func renderMetrics(w io.Writer, stats *SyncStats, lastSuccess float64) error {
+	metrics := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"difync_apps_total", "Number of apps in the last sync.", float64(stats.Total)},
+		{"difync_downloads_total", "Number of DSL files downloaded in the last sync.", float64(stats.Downloads)},
+		{"difync_errors_total", "Number of apps that failed to sync in the last sync.", float64(stats.Errors)},
+		{"difync_sync_duration_seconds", "Duration of the last sync in seconds.", stats.Duration.Seconds()},
+		{lastSuccessMetric, "UNIX time of the last sync without errors.", lastSuccess},
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n",
+			m.name, m.help, m.name, m.name, strconv.FormatFloat(m.value, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMetric reads the value of an unlabeled metric from an existing metrics file
+func readMetric(path, name string) (float64, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != name {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		return value, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,109 @@
+package syncer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseMetrics parses unlabeled samples from a textfile collector file
+func parseMetrics(t *testing.T, path string) map[string]float64 {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open metrics file: %v", err)
+	}
+	defer file.Close()
+
+	metrics := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			t.Fatalf("Unexpected metrics line: %q", line)
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			t.Fatalf("Failed to parse value of %q: %v", line, err)
+		}
+		metrics[fields[0]] = value
+	}
+	return metrics
+}
+
+func TestWriteMetrics(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-metrics-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "difync.prom")
+	endTime := time.Unix(1700000000, 0)
+
+	stats := &SyncStats{
+		Total:     3,
+		Downloads: 2,
+		EndTime:   endTime,
+		Duration:  1500 * time.Millisecond,
+	}
+	if err := WriteMetrics(path, stats); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+
+	expected := map[string]float64{
+		"difync_apps_total":             3,
+		"difync_downloads_total":        2,
+		"difync_errors_total":           0,
+		"difync_sync_duration_seconds":  1.5,
+		"difync_last_success_timestamp": 1700000000,
+	}
+	metrics := parseMetrics(t, path)
+	for name, value := range expected {
+		got, ok := metrics[name]
+		if !ok {
+			t.Errorf("Expected metric %s to be written", name)
+			continue
+		}
+		if got != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, got)
+		}
+	}
+
+	// A failed run keeps the last success time of the previous run
+	failed := &SyncStats{
+		Total:    3,
+		Errors:   1,
+		EndTime:  endTime.Add(time.Hour),
+		Duration: time.Second,
+	}
+	if err := WriteMetrics(path, failed); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+
+	metrics = parseMetrics(t, path)
+	if metrics["difync_errors_total"] != 1 {
+		t.Errorf("Expected difync_errors_total to be 1, got %v", metrics["difync_errors_total"])
+	}
+	if metrics["difync_last_success_timestamp"] != 1700000000 {
+		t.Errorf("Expected last success timestamp to be kept, got %v", metrics["difync_last_success_timestamp"])
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read temp dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the metrics file, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,27 @@
+package syncer
+
+import "fmt"
+
+// appMapMigrations upgrade an app map by one version; entry i upgrades version i to i+1
+var appMapMigrations = []func(*AppMap){
+	// Version 0 maps are unversioned but otherwise identical to version 1
+	func(*AppMap) {},
+}
+
+// migrateAppMap upgrades an app map to AppMapVersion.
+// Maps written by a newer difync are rejected rather than loaded with fields dropped.
+func migrateAppMap(appMap *AppMap) error {
+	if appMap.Version < 0 {
+		return fmt.Errorf("invalid app map version %d", appMap.Version)
+	}
+	if appMap.Version > AppMapVersion {
+		return fmt.Errorf("app map version %d is newer than the supported version %d, please upgrade difync", appMap.Version, AppMapVersion)
+	}
+
+	for appMap.Version < AppMapVersion {
+		appMapMigrations[appMap.Version](appMap)
+		appMap.Version++
+	}
+
+	return nil
+}
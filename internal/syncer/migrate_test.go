@@ -0,0 +1,69 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAppMapVersion(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name        string
+		content     string
+		expectError bool
+	}{
+		{
+			name:    "unversioned map is treated as version 1",
+			content: `{"apps": [{"filename": "test.yaml", "app_id": "test-app-id", "read_only": true}]}`,
+		},
+		{
+			name:    "current version",
+			content: `{"version": 1, "apps": [{"filename": "test.yaml", "app_id": "test-app-id", "read_only": true}]}`,
+		},
+		{
+			name:        "newer version",
+			content:     `{"version": 2, "apps": [{"filename": "test.yaml", "app_id": "test-app-id"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "negative version",
+			content:     `{"version": -1, "apps": []}`,
+			expectError: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appMapPath := filepath.Join(tmpDir, fmt.Sprintf("app_map_%d.json", i))
+			if err := os.WriteFile(appMapPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			syncer := &DefaultSyncer{config: Config{AppMapFile: appMapPath}}
+			appMap, err := syncer.LoadAppMap()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if appMap.Version != AppMapVersion {
+				t.Errorf("Expected version %d, got %d", AppMapVersion, appMap.Version)
+			}
+			if len(appMap.Apps) != 1 || appMap.Apps[0].Filename != "test.yaml" || !appMap.Apps[0].ReadOnly {
+				t.Errorf("Expected the app entry to be kept, got %+v", appMap.Apps)
+			}
+		})
+	}
+}
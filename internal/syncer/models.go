@@ -2,18 +2,51 @@
 package syncer
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
+// AppMapVersion is the current version of the app map format
+const AppMapVersion = 1
+
 // AppMap represents a mapping between local DSL files and Dify app IDs
 type AppMap struct {
-	Apps []AppMapping `json:"apps"`
+	// Version is the format version of the app map; maps without it are version 0
+	Version int `json:"version" yaml:"version"`
+	// DSLDirectory is the default DSL directory, relative to the app map file.
+	// It is used when Config.DSLDirectory is not set.
+	DSLDirectory string       `json:"dsl_directory,omitempty" yaml:"dsl_directory,omitempty"`
+	Apps         []AppMapping `json:"apps" yaml:"apps"`
 }
 
 // AppMapping represents a single mapping entry between a DSL file and a Dify app
 type AppMapping struct {
-	Filename string `json:"filename"`
-	AppID    string `json:"app_id"`
+	Filename string `json:"filename" yaml:"filename"`
+	AppID    string `json:"app_id" yaml:"app_id"`
+	// Name identifies the app by its name instead of AppID, which changes when an app is
+	// recreated in Dify. It is only used if AppID is empty.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Mode is the Dify app type (e.g. workflow or chat) recorded by init for downstream tooling
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+
+	// LastRemoteUpdatedAt is the remote update time of the DSL at the last download
+	LastRemoteUpdatedAt *time.Time `json:"last_remote_updated_at,omitempty" yaml:"last_remote_updated_at,omitempty"`
+	// LastSyncedAt is the time of the last successful download
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty" yaml:"last_synced_at,omitempty"`
+
+	// Skip excludes the app from syncing entirely
+	Skip bool `json:"skip,omitempty" yaml:"skip,omitempty"`
+	// ReadOnly reports remote changes for the app but never writes, renames or deletes its file
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+
+	// Match makes this a pattern entry that expands to every matching remote app.
+	// Filename and AppID are ignored for pattern entries.
+	Match *AppMatch `json:"match,omitempty" yaml:"match,omitempty"`
+
+	// byName is set on entries identified by name once their app ID has been resolved
+	byName bool
 }
 
 // SyncResult represents the result of a sync operation for a single app
@@ -24,6 +57,13 @@ type SyncResult struct {
 	Success   bool
 	Error     error
 	Timestamp time.Time
+
+	// RemoteUpdatedAt is the remote update time used to decide on the action
+	RemoteUpdatedAt time.Time
+	// NewFilename is the filename the app is renamed to, set for ActionRename
+	NewFilename string
+	// BytesWritten is the size of the DSL written to the local file, 0 if nothing was written
+	BytesWritten int64
 }
 
 // SyncAction represents the action taken during sync
@@ -38,15 +78,148 @@ const (
 
 	// ActionError indicates an error occurred during sync
 	ActionError SyncAction = "error"
+
+	// ActionRename indicates the local file is renamed to follow the remote app name
+	ActionRename SyncAction = "rename"
+
+	// ActionDelete indicates the app was deleted in Dify and is removed from the app map
+	ActionDelete SyncAction = "delete"
+
+	// ActionSkip indicates the app could not be exported and was skipped (see Config.SkipForbidden)
+	ActionSkip SyncAction = "skip"
+
+	// ActionUpload indicates the local DSL was uploaded to Dify. The syncer does not upload yet;
+	// the action is reserved so that consumers of the audit log can already handle it.
+	ActionUpload SyncAction = "upload"
 )
 
+// syncActions is the closed set of valid sync actions
+var syncActions = []SyncAction{ActionNone, ActionDownload, ActionUpload, ActionError, ActionRename, ActionDelete, ActionSkip}
+
+// ParseSyncAction returns the sync action named s, or an error if s is not a known action
+func ParseSyncAction(s string) (SyncAction, error) {
+	for _, action := range syncActions {
+		if string(action) == s {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sync action %q", s)
+}
+
+// String returns the name of the action
+func (a SyncAction) String() string {
+	return string(a)
+}
+
+// MarshalJSON encodes the action as a JSON string, rejecting unknown actions
+func (a SyncAction) MarshalJSON() ([]byte, error) {
+	if _, err := ParseSyncAction(string(a)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(a))
+}
+
+// UnmarshalJSON decodes an action from a JSON string, rejecting unknown actions
+func (a *SyncAction) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	action, err := ParseSyncAction(s)
+	if err != nil {
+		return err
+	}
+	*a = action
+	return nil
+}
+
 // SyncStats represents statistics about a sync operation
 type SyncStats struct {
 	Total     int
 	Downloads int
 	NoAction  int
 	Errors    int
+	Renamed   int
+	Deleted   int
+	Skipped   int
 	StartTime time.Time
 	EndTime   time.Time
 	Duration  time.Duration
+
+	// BytesDownloaded is the total size of the DSLs downloaded
+	BytesDownloaded int64
+
+	// Results holds the result of every app, including skipped, renamed and deleted ones, in sync order
+	Results []SyncResult
+
+	// Planned holds the renames and deletions a dry run would have made, in sync order
+	Planned []SyncResult
+}
+
+// failed reports whether the app of a result failed to sync. Failed downloads keep
+// ActionDownload, so this does not depend on the action.
+func (r SyncResult) failed() bool {
+	return !r.Success || r.Error != nil
+}
+
+// add records the result of an app synced by SyncAll and counts it by its action.
+// Failed apps are counted as errors whatever their action, so that Errors always
+// matches Failed.
+func (s *SyncStats) add(result SyncResult) {
+	s.Results = append(s.Results, result)
+	if result.failed() {
+		s.Errors++
+		return
+	}
+	switch result.Action {
+	case ActionDownload:
+		s.Downloads++
+	case ActionNone:
+		s.NoAction++
+	case ActionSkip:
+		s.Skipped++
+	case ActionRename:
+		s.Renamed++
+	case ActionDelete:
+		s.Deleted++
+	}
+}
+
+// Failed returns the results of the apps that failed to sync, in sync order
+func (s *SyncStats) Failed() []SyncResult {
+	var failed []SyncResult
+	for _, result := range s.Results {
+		if result.failed() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of all apps that failed to sync, each prefixed with the app's
+// filename and ID. It returns nil if every app synced. Use errors.Is or errors.As on the
+// result to inspect the individual errors.
+func (s *SyncStats) Err() error {
+	var errs []error
+	for _, result := range s.Failed() {
+		errs = append(errs, fmt.Errorf("%s (app_id: %s): %w", result.Filename, result.AppID, result.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// FormatBytes formats a byte count for people, e.g. "512 B", "1.5 KB" or "2.0 MB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n) / unit
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TB", value)
 }
@@ -0,0 +1,195 @@
+package syncer
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+// syncRun holds the state shared by the apps of a single SyncAll or Plan run
+type syncRun struct {
+	// remoteApps maps the IDs of the apps in the workspace to their info
+	remoteApps map[string]api.AppInfo
+	// expandedIDs are the IDs of the apps expanded from pattern entries
+	expandedIDs map[string]bool
+	// lastRun is the time of the last clean run with SinceLastRun, or zero
+	lastRun time.Time
+	// progress lists the apps completed by an interrupted run with Resume, or is nil
+	progress *syncProgress
+	// dslDirMissing is set when the DSL directory did not exist before the run
+	dslDirMissing bool
+}
+
+// appPlan is the action SyncAll takes for a single app, as decided by planSyncAll
+type appPlan struct {
+	result SyncResult
+	// deleted is set when the app no longer exists in Dify
+	deleted bool
+}
+
+// Plan returns the action SyncAll would take for every app without writing anything,
+// regardless of the DryRun setting. Renames carry the new filename in NewFilename.
+func (s *DefaultSyncer) Plan() ([]SyncResult, error) {
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return nil, err
+	}
+
+	apps, run, err := s.prepareSync(appMap, !s.fileExists(s.config.DSLDirectory))
+	if err != nil {
+		return nil, err
+	}
+
+	// App info prefetched while planning is not reused by a later run
+	defer func() { s.prefetchedApps = nil }()
+
+	results := make([]SyncResult, 0, len(apps))
+	for _, app := range apps {
+		plan, err := s.planSyncAll(app, run)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, plan.result)
+	}
+
+	return results, nil
+}
+
+// prepareSync lists the apps of the app map to sync, with pattern entries expanded and in a
+// stable order, together with the state needed to decide the action for each of them
+func (s *DefaultSyncer) prepareSync(appMap *AppMap, dslDirMissing bool) ([]AppMapping, *syncRun, error) {
+	// Get current app list to compare names
+	remoteAppList, err := s.listRemoteApps()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Expand pattern entries into concrete apps; these are not written back to the app map
+	patterns, apps := splitPatternEntries(appMap.Apps)
+	apps, err = s.resolveNamedEntries(apps, remoteAppList)
+	if err != nil {
+		return nil, nil, err
+	}
+	expandedApps, err := s.expandPatterns(patterns, apps, remoteAppList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to expand app map patterns: %w", err)
+	}
+
+	run := &syncRun{
+		remoteApps:    make(map[string]api.AppInfo, len(remoteAppList)),
+		expandedIDs:   make(map[string]bool, len(expandedApps)),
+		dslDirMissing: dslDirMissing,
+	}
+	for _, app := range remoteAppList {
+		run.remoteApps[app.ID] = app
+	}
+	for _, app := range expandedApps {
+		run.expandedIDs[app.AppID] = true
+	}
+
+	// Apps not updated in Dify since the last clean run are left alone
+	if s.config.SinceLastRun {
+		run.lastRun = s.loadLastRun()
+	}
+
+	// Continue where an interrupted run stopped
+	if s.config.Resume && !s.config.DryRun {
+		run.progress = loadProgress(s.fileStore(), s.progressFile(), s.config.logOutput())
+	}
+
+	// Sync apps in a stable order so output is deterministic
+	apps = append(apps, expandedApps...)
+	sortAppMappings(apps)
+	return apps, run, nil
+}
+
+// planSyncAll decides the action SyncAll takes for a single app without writing anything.
+// It returns an error only if the sync cannot go on, e.g. when authentication failed.
+func (s *DefaultSyncer) planSyncAll(app AppMapping, run *syncRun) (appPlan, error) {
+	result := SyncResult{
+		Filename:  app.Filename,
+		AppID:     app.AppID,
+		Action:    ActionNone,
+		Success:   true,
+		Timestamp: s.now(),
+	}
+
+	// Skipped apps and apps completed by the interrupted run are never touched
+	if app.Skip || (run.progress != nil && run.progress.isDone(app.AppID)) {
+		result.Action = ActionSkip
+		return appPlan{result: result}, nil
+	}
+
+	// Apps expanded from patterns come from the live app list, so new ones are downloaded directly
+	if run.expandedIDs[app.AppID] {
+		return appPlan{result: s.planSyncOrDownload(app)}, nil
+	}
+
+	// Check if the app still exists in remote; planApp reuses the app info fetched here
+	appInfo, err := s.client.FindApp(app.AppID)
+	if err != nil {
+		// Authentication failures affect every app, so stop instead of failing each one
+		if api.IsUnauthorized(err) {
+			return appPlan{}, fmt.Errorf("authentication with Dify API failed: %w", err)
+		}
+		result.Action = ActionError
+		result.Success = false
+		result.Error = fmt.Errorf("failed to check if app exists: %w", err)
+		return appPlan{result: result}, nil
+	}
+	s.prefetchApp(app.AppID, appInfo)
+
+	// Apps deleted remotely are removed only with deletion, archiving or pruning, and never if read-only
+	if appInfo == nil {
+		if !app.ReadOnly && (s.config.DeleteOrphans || s.config.ArchiveDeletedDir != "" || s.config.PruneMap) {
+			result.Action = ActionDelete
+		}
+		return appPlan{result: result, deleted: true}, nil
+	}
+
+	// Check if app name has changed (read-only apps and apps identified by name are never renamed)
+	if remoteApp, ok := run.remoteApps[app.AppID]; ok && !app.ReadOnly && !app.byName {
+		if newFilename := s.renamedFilename(app, remoteApp.Name); newFilename != "" {
+			result.Action = ActionRename
+			result.NewFilename = newFilename
+			return appPlan{result: result}, nil
+		}
+	}
+
+	// A freshly created DSL directory has no local files to compare yet
+	if run.dslDirMissing {
+		return appPlan{result: s.planSyncOrDownload(app)}, nil
+	}
+
+	if s.unchangedSinceLastRun(app, run.remoteApps, run.lastRun) {
+		return appPlan{result: result}, nil
+	}
+
+	return appPlan{result: s.planApp(app)}, nil
+}
+
+// planSyncOrDownload plans a download for an app without a local file yet, except for
+// read-only apps, and plans any other app like SyncApp.
+// It is used for apps expanded from patterns and for a freshly created DSL directory.
+func (s *DefaultSyncer) planSyncOrDownload(app AppMapping) SyncResult {
+	if s.fileExists(filepath.Join(s.config.DSLDirectory, app.Filename)) {
+		return s.planApp(app)
+	}
+
+	result := SyncResult{
+		Filename:  app.Filename,
+		AppID:     app.AppID,
+		Action:    ActionDownload,
+		Success:   true,
+		Timestamp: s.now(),
+	}
+	if app.ReadOnly {
+		if s.config.Verbose {
+			fmt.Printf("Read-only app %s (ID: %s) has no local file, not downloading\n", app.Filename, app.AppID)
+		}
+		result.Action = ActionNone
+	}
+	return result
+}
@@ -0,0 +1,238 @@
+package syncer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// snapshotDir records the contents and modification time of every file under dir
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	snapshot := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			snapshot[path] = "dir"
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		snapshot[path] = info.ModTime().String() + "\n" + string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to snapshot directory: %v", err)
+	}
+	return snapshot
+}
+
+func TestPlanRename(t *testing.T) {
+	syncer, _, dslDir, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	root := filepath.Dir(dslDir)
+	before := snapshotDir(t, root)
+
+	results, err := syncer.Plan()
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Action != ActionRename {
+		t.Errorf("Expected action %s, got %s", ActionRename, results[0].Action)
+	}
+	if results[0].NewFilename != "Test_App.yaml" {
+		t.Errorf("Expected new filename Test_App.yaml, got %s", results[0].NewFilename)
+	}
+
+	if after := snapshotDir(t, root); !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected Plan to leave files unchanged, before %v, after %v", before, after)
+	}
+}
+
+func TestPlanDownload(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Use the remote name so no rename is planned
+	renamedPath := filepath.Join(dslDir, "Test_App.yaml")
+	if err := os.Rename(dslPath, renamedPath); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	appMap := AppMap{Apps: []AppMapping{{Filename: "Test_App.yaml", AppID: "test-app-id"}}}
+	data, err := json.Marshal(appMap)
+	if err != nil {
+		t.Fatalf("Failed to marshal app map: %v", err)
+	}
+	if err := os.WriteFile(appMapPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+
+	// Make the local file older than the remote app
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(renamedPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	root := filepath.Dir(dslDir)
+	before := snapshotDir(t, root)
+
+	results, err := syncer.Plan()
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Action != ActionDownload {
+		t.Errorf("Expected action %s, got %s", ActionDownload, results[0].Action)
+	}
+
+	if after := snapshotDir(t, root); !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected Plan to leave files unchanged, before %v, after %v", before, after)
+	}
+}
+
+func TestSyncAllDryRunReportsPlannedChanges(t *testing.T) {
+	syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Add an app that no longer exists in Dify
+	if err := os.WriteFile(filepath.Join(dslDir, "gone.yaml"), []byte("name: Gone"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+	appMap := `{"apps": [{"filename": "test.yaml", "app_id": "test-app-id"}, {"filename": "gone.yaml", "app_id": "gone-app-id"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMap), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+
+	config := &syncer.(*DefaultSyncer).config
+	config.DryRun = true
+	config.DeleteOrphans = true
+
+	root := filepath.Dir(dslDir)
+	before := snapshotDir(t, root)
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+
+	if after := snapshotDir(t, root); !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected dry run to leave files unchanged, before %v, after %v", before, after)
+	}
+
+	planned := make(map[SyncAction]SyncResult)
+	for _, result := range stats.Planned {
+		planned[result.Action] = result
+	}
+	if len(stats.Planned) != 2 {
+		t.Fatalf("Expected 2 planned changes, got %+v", stats.Planned)
+	}
+	if rename := planned[ActionRename]; rename.Filename != "test.yaml" || rename.NewFilename != "Test_App.yaml" {
+		t.Errorf("Expected rename of test.yaml to Test_App.yaml, got %+v", rename)
+	}
+	if deletion := planned[ActionDelete]; deletion.Filename != "gone.yaml" || deletion.AppID != "gone-app-id" {
+		t.Errorf("Expected deletion of gone.yaml, got %+v", deletion)
+	}
+}
+
+func TestPlanSinceLastRunAndResume(t *testing.T) {
+	appInfoRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "A", "updated_at": "2023-01-01T12:00:00Z"}]}`))
+		case "/console/api/apps/app-a":
+			appInfoRequests++
+			w.Write([]byte(`{"data": {"id": "app-a", "name": "A", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+	localPath := filepath.Join(dslDir, "A.yaml")
+	if err := os.WriteFile(localPath, []byte("name: A"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(localPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "A.yaml", "app_id": "app-a"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+	}).(*DefaultSyncer)
+
+	planAction := func(t *testing.T) SyncAction {
+		t.Helper()
+		results, err := syncer.Plan()
+		if err != nil {
+			t.Fatalf("Failed to plan: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		return results[0].Action
+	}
+
+	// The outdated file is downloaded, looking the app up only once
+	if action := planAction(t); action != ActionDownload {
+		t.Errorf("Expected action %s, got %s", ActionDownload, action)
+	}
+	if appInfoRequests != 1 {
+		t.Errorf("Expected the app to be looked up once, got %d requests", appInfoRequests)
+	}
+
+	// An app not updated since the last clean run is left alone
+	syncer.config.SinceLastRun = true
+	if err := syncer.saveLastRun(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+	if action := planAction(t); action != ActionNone {
+		t.Errorf("Expected action %s with --since-last-run, got %s", ActionNone, action)
+	}
+	syncer.config.SinceLastRun = false
+
+	// An app completed by an interrupted run is skipped
+	syncer.config.Resume = true
+	if err := loadProgress(syncer.fileStore(), syncer.progressFile(), io.Discard).markDone("app-a"); err != nil {
+		t.Fatalf("Failed to write progress file: %v", err)
+	}
+	if action := planAction(t); action != ActionSkip {
+		t.Errorf("Expected action %s with --resume, got %s", ActionSkip, action)
+	}
+}
@@ -0,0 +1,87 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// defaultProgressFilename is the progress file created next to the app map for Resume
+const defaultProgressFilename = ".difync-progress.json"
+
+// syncProgress records the apps that a sync completed, so an interrupted sync can resume
+type syncProgress struct {
+	store     FileStore
+	path      string
+	Completed []string `json:"completed"`
+	done      map[string]bool
+}
+
+// progressFile returns the path of the progress file next to the app map
+func (s *DefaultSyncer) progressFile() string {
+	return filepath.Join(filepath.Dir(s.config.AppMapFile), defaultProgressFilename)
+}
+
+// loadProgress reads the progress of an earlier, unfinished sync from store.
+// A missing file starts a fresh run; an unreadable one only causes a warning written to w.
+func loadProgress(store FileStore, path string, w io.Writer) *syncProgress {
+	progress := &syncProgress{store: store, path: path, done: make(map[string]bool)}
+
+	data, err := store.ReadFile(path)
+	if os.IsNotExist(err) {
+		return progress
+	}
+	if err == nil {
+		err = json.Unmarshal(data, progress)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "Warning: Failed to read progress file %s, syncing all apps: %v\n", path, err)
+		progress.Completed = nil
+		return progress
+	}
+
+	for _, appID := range progress.Completed {
+		progress.done[appID] = true
+	}
+	return progress
+}
+
+// isDone reports whether an earlier run already synced the app
+func (p *syncProgress) isDone(appID string) bool {
+	return p.done[appID]
+}
+
+// markDone records a synced app and writes the progress file atomically so it survives a crash
+func (p *syncProgress) markDone(appID string) error {
+	if p.done[appID] {
+		return nil
+	}
+	p.done[appID] = true
+	p.Completed = append(p.Completed, appID)
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+	return writeFileAtomic(p.store, p.path, append(data, '\n'), 0644)
+}
+
+// remove deletes the progress file once a run finished cleanly
+func (p *syncProgress) remove() error {
+	if err := p.store.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// recordProgress marks a successfully synced app as done, warning if the progress file cannot be written
+func (s *DefaultSyncer) recordProgress(progress *syncProgress, result SyncResult) {
+	if progress == nil || !result.Success {
+		return
+	}
+	if err := progress.markDone(result.AppID); err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: Failed to write progress file: %v\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncAllResume(t *testing.T) {
+	ids := []string{"app-a", "app-b", "app-c", "app-d"}
+	failing := ""
+
+	// Every app has remote changes
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/console/api/")
+		switch {
+		case path == "login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case path == "apps":
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "app-a"}, {"id": "app-b", "name": "app-b"}, {"id": "app-c", "name": "app-c"}, {"id": "app-d", "name": "app-d"}]}`))
+		case strings.HasSuffix(path, "/export"):
+			id := strings.TrimSuffix(strings.TrimPrefix(path, "apps/"), "/export")
+			if id == failing {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(fmt.Sprintf(`{"data": "name: %s"}`, id)))
+		case strings.HasPrefix(path, "apps/"):
+			id := strings.TrimPrefix(path, "apps/")
+			w.Write([]byte(fmt.Sprintf(`{"data": {"id": %q, "name": %q, "updated_at": "2023-01-01T12:00:00Z"}}`, id, id)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+	var entries []string
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range ids {
+		path := filepath.Join(dslDir, id+".yaml")
+		if err := os.WriteFile(path, []byte("name: old"), 0644); err != nil {
+			t.Fatalf("Failed to write DSL file: %v", err)
+		}
+		if err := os.Chtimes(path, oldTime, oldTime); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+		entries = append(entries, fmt.Sprintf(`{"filename": "%s.yaml", "app_id": %q}`, id, id))
+	}
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [`+strings.Join(entries, ",")+`]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	// An earlier run completed the first half of the apps
+	progressPath := filepath.Join(tmpDir, defaultProgressFilename)
+	if err := os.WriteFile(progressPath, []byte(`{"completed": ["app-a", "app-b"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write progress file: %v", err)
+	}
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+		Resume:       true,
+	})
+
+	// The resumed run fails on app-d, so the progress file is kept with app-c added
+	failing = "app-d"
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Skipped != 2 || stats.Downloads+stats.Errors != 2 || len(stats.Results) != 4 {
+		t.Fatalf("Expected 2 skipped and 2 synced apps, got %d skipped and %d results", stats.Skipped, len(stats.Results))
+	}
+	data, err := os.ReadFile(progressPath)
+	if err != nil {
+		t.Fatalf("Expected the progress file to be kept after errors: %v", err)
+	}
+	var progress syncProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		t.Fatalf("Failed to decode progress file: %v", err)
+	}
+	if expected := []string{"app-a", "app-b", "app-c"}; !reflect.DeepEqual(progress.Completed, expected) {
+		t.Errorf("Expected completed apps %v, got %v", expected, progress.Completed)
+	}
+
+	// The pre-marked apps were not downloaded again
+	for _, id := range []string{"app-a", "app-b"} {
+		if data, _ := os.ReadFile(filepath.Join(dslDir, id+".yaml")); string(data) != "name: old" {
+			t.Errorf("Expected %s to be skipped, got content %q", id, data)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dslDir, "app-c.yaml")); string(data) != "name: app-c" {
+		t.Errorf("Expected app-c to be downloaded, got content %q", data)
+	}
+
+	// The next run only syncs app-d and removes the progress file once it finishes cleanly
+	failing = ""
+	stats, err = syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Skipped != 3 || stats.Downloads != 1 || stats.Results[3].AppID != "app-d" || stats.Results[3].Action != ActionDownload {
+		t.Errorf("Expected only app-d to be synced, got %d skipped and results %+v", stats.Skipped, stats.Results)
+	}
+	if _, err := os.Stat(progressPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the progress file to be removed after a clean run, got %v", err)
+	}
+}
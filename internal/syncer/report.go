@@ -0,0 +1,109 @@
+package syncer
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// markdownReportTemplate renders a sync report as Markdown
+var markdownReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cell":  markdownCell,
+	"bytes": reportBytes,
+}).Parse(`# Difync Sync Report
+
+Started: {{.StartTime.Format "2006-01-02 15:04:05 MST"}}
+Duration: {{.Duration}}
+
+## Summary
+
+| Total | Downloads | Renamed | Deleted | Skipped | No action | Errors |
+| ----- | --------- | ------- | ------- | ------- | --------- | ------ |
+| {{.Total}} | {{.Downloads}} | {{.Renamed}} | {{.Deleted}} | {{.Skipped}} | {{.NoAction}} | {{.Errors}} |
+
+## Apps
+
+| Filename | App ID | Action | Error | Written |
+| -------- | ------ | ------ | ----- | ------- |
+{{range .Results}}| {{cell .Filename}} | {{cell .AppID}} | {{.Action}} | {{if .Error}}{{cell .Error.Error}}{{end}} | {{bytes .BytesWritten}} |
+{{end}}{{if .Planned}}
+## Planned Changes (dry run)
+
+| Filename | App ID | Action | New filename |
+| -------- | ------ | ------ | ------------ |
+{{range .Planned}}| {{cell .Filename}} | {{cell .AppID}} | {{.Action}} | {{cell .NewFilename}} |
+{{end}}{{end}}`))
+
+// htmlReportTemplate renders a sync report as a standalone HTML page
+var htmlReportTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(htmltemplate.FuncMap{
+	"bytes": reportBytes,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Difync Sync Report</title>
+</head>
+<body>
+<h1>Difync Sync Report</h1>
+<p>Started: {{.StartTime.Format "2006-01-02 15:04:05 MST"}}<br>
+Duration: {{.Duration}}</p>
+<h2>Summary</h2>
+<table>
+<tr><th>Total</th><th>Downloads</th><th>Renamed</th><th>Deleted</th><th>Skipped</th><th>No action</th><th>Errors</th></tr>
+<tr><td>{{.Total}}</td><td>{{.Downloads}}</td><td>{{.Renamed}}</td><td>{{.Deleted}}</td><td>{{.Skipped}}</td><td>{{.NoAction}}</td><td>{{.Errors}}</td></tr>
+</table>
+<h2>Apps</h2>
+<table>
+<tr><th>Filename</th><th>App ID</th><th>Action</th><th>Error</th><th>Written</th></tr>
+{{range .Results}}<tr><td>{{.Filename}}</td><td>{{.AppID}}</td><td>{{.Action}}</td><td>{{if .Error}}{{.Error.Error}}{{end}}</td><td>{{bytes .BytesWritten}}</td></tr>
+{{end}}</table>
+{{if .Planned}}<h2>Planned Changes (dry run)</h2>
+<table>
+<tr><th>Filename</th><th>App ID</th><th>Action</th><th>New filename</th></tr>
+{{range .Planned}}<tr><td>{{.Filename}}</td><td>{{.AppID}}</td><td>{{.Action}}</td><td>{{.NewFilename}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// WriteReport writes a human-readable sync report to path.
+// The report is HTML if the path ends in .html and Markdown otherwise.
+func WriteReport(path string, stats *SyncStats) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer file.Close()
+
+	if err := renderReport(file, path, stats); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+
+	return nil
+}
+
+// renderReport renders the report in the format implied by the path's extension
+func renderReport(w io.Writer, path string, stats *SyncStats) error {
+	if strings.EqualFold(filepath.Ext(path), ".html") {
+		return htmlReportTemplate.Execute(w, stats)
+	}
+	return markdownReportTemplate.Execute(w, stats)
+}
+
+// markdownCell escapes a value for use inside a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// reportBytes formats the size written for an app, leaving the cell empty if nothing was written
+func reportBytes(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return FormatBytes(n)
+}
@@ -0,0 +1,127 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteReport(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-report-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	stats := &SyncStats{
+		Total:     3,
+		Downloads: 1,
+		NoAction:  1,
+		Errors:    1,
+		StartTime: time.Now().Add(-1 * time.Second),
+		EndTime:   time.Now(),
+		Duration:  time.Second,
+		Results: []SyncResult{
+			{Filename: "alpha.yaml", AppID: "app-1", Action: ActionDownload, Success: true},
+			{Filename: "beta.yaml", AppID: "app-2", Action: ActionNone, Success: true},
+			{Filename: "gamma<1>.yaml", AppID: "app-3", Action: ActionError, Error: fmt.Errorf("export failed")},
+		},
+		Planned: []SyncResult{
+			{Filename: "delta.yaml", AppID: "app-4", Action: ActionRename, Success: true, NewFilename: "Delta.yaml"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		contains []string
+	}{
+		{
+			name:     "markdown",
+			filename: "report.md",
+			contains: []string{
+				"| 3 | 1 | 0 | 0 | 0 | 1 | 1 |",
+				"| alpha.yaml | app-1 | download |",
+				"| beta.yaml | app-2 | none |",
+				"| gamma<1>.yaml | app-3 | error | export failed |",
+				"## Planned Changes (dry run)",
+				"| delta.yaml | app-4 | rename | Delta.yaml |",
+			},
+		},
+		{
+			name:     "html",
+			filename: "report.html",
+			contains: []string{
+				"<html>",
+				"<td>alpha.yaml</td><td>app-1</td><td>download</td>",
+				"<td>beta.yaml</td><td>app-2</td><td>none</td>",
+				"<td>gamma&lt;1&gt;.yaml</td><td>app-3</td><td>error</td><td>export failed</td>",
+				"<td>delta.yaml</td><td>app-4</td><td>rename</td><td>Delta.yaml</td>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.filename)
+			if err := WriteReport(path, stats); err != nil {
+				t.Fatalf("WriteReport returned error: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read report: %v", err)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("Expected report to contain %q, got:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteReportIncludesRenamesAndDeletions(t *testing.T) {
+	syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Add an app that no longer exists in Dify
+	if err := os.WriteFile(filepath.Join(dslDir, "gone.yaml"), []byte("name: Gone"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+	appMap := `{"apps": [{"filename": "test.yaml", "app_id": "test-app-id"}, {"filename": "gone.yaml", "app_id": "gone-app-id"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMap), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+	syncer.(*DefaultSyncer).config.DeleteOrphans = true
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Renamed != 1 || stats.Deleted != 1 || len(stats.Results) != 2 {
+		t.Fatalf("Expected 1 rename and 1 deletion in the results, got %+v", stats)
+	}
+
+	path := filepath.Join(filepath.Dir(dslDir), "report.md")
+	if err := WriteReport(path, stats); err != nil {
+		t.Fatalf("WriteReport returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	for _, want := range []string{
+		"| test.yaml | test-app-id | rename |",
+		"| gone.yaml | gone-app-id | delete |",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, data)
+		}
+	}
+}
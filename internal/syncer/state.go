@@ -0,0 +1,81 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+// defaultStateFilename is the state file created next to the app map when no path is set
+const defaultStateFilename = ".difync-state.json"
+
+// runState is persisted between runs for SinceLastRun
+type runState struct {
+	// LastSuccess is the start time of the last sync that completed without errors
+	LastSuccess time.Time `json:"last_success"`
+}
+
+// stateFile returns the configured state file path, defaulting to a file next to the app map
+func (s *DefaultSyncer) stateFile() string {
+	if s.config.StateFile != "" {
+		return s.config.StateFile
+	}
+	return filepath.Join(filepath.Dir(s.config.AppMapFile), defaultStateFilename)
+}
+
+// loadLastRun returns the start time of the last clean sync, or the zero time if it is unknown.
+// A missing state file is expected on the first run; other read errors only cause a warning.
+func (s *DefaultSyncer) loadLastRun() time.Time {
+	data, err := s.fileStore().ReadFile(s.stateFile())
+	if os.IsNotExist(err) {
+		return time.Time{}
+	}
+	if err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: Failed to read state file, syncing all apps: %v\n", err)
+		return time.Time{}
+	}
+
+	var state runState
+	if err := json.Unmarshal(data, &state); err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: Failed to decode state file %s, syncing all apps: %v\n", s.stateFile(), err)
+		return time.Time{}
+	}
+	return state.LastSuccess
+}
+
+// saveLastRun atomically records the start time of a sync that completed without errors
+func (s *DefaultSyncer) saveLastRun(startTime time.Time) error {
+	data, err := json.MarshalIndent(runState{LastSuccess: startTime.UTC()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return writeFileAtomic(s.fileStore(), s.stateFile(), append(data, '\n'), 0644)
+}
+
+// unchangedSinceLastRun reports whether an app was last updated in Dify before the last
+// clean sync, so it needs no further requests. Apps without a known remote update time
+// or local file are never considered unchanged.
+func (s *DefaultSyncer) unchangedSinceLastRun(app AppMapping, remoteApps map[string]api.AppInfo, lastRun time.Time) bool {
+	if lastRun.IsZero() {
+		return false
+	}
+
+	remoteApp, ok := remoteApps[app.AppID]
+	if !ok {
+		return false
+	}
+	updatedAt, ok := parseUpdatedAt(remoteApp.UpdatedAt)
+	if !ok {
+		return false
+	}
+
+	// Allow for clock differences between Dify and this machine
+	if updatedAt.After(lastRun.Add(-s.clockSkewTolerance())) {
+		return false
+	}
+	return s.fileExists(filepath.Join(s.config.DSLDirectory, app.Filename))
+}
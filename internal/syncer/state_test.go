@@ -0,0 +1,90 @@
+package syncer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readLastRun reads the last success time from a state file
+func readLastRun(t *testing.T, path string) time.Time {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	var state runState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to decode state file: %v", err)
+	}
+	return state.LastSuccess
+}
+
+func TestSyncAllSinceLastRun(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Use the remote name so the app is downloaded rather than renamed
+	renamedPath := filepath.Join(dslDir, "Test_App.yaml")
+	if err := os.Rename(dslPath, renamedPath); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(renamedPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	syncer.(*DefaultSyncer).config.SinceLastRun = true
+	statePath := filepath.Join(filepath.Dir(appMapPath), defaultStateFilename)
+
+	// The first run has no state, so the app is downloaded and the state file written
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Downloads != 1 {
+		t.Fatalf("Expected 1 download, got %d", stats.Downloads)
+	}
+	firstRun := readLastRun(t, statePath)
+	if !firstRun.Equal(stats.StartTime.UTC()) {
+		t.Errorf("Expected last success %v, got %v", stats.StartTime, firstRun)
+	}
+
+	// The remote app was not updated since, so it is not downloaded even though
+	// the local file is older than the remote update time
+	if err := os.Chtimes(renamedPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+	stats, err = syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Downloads != 0 || stats.NoAction != 1 {
+		t.Errorf("Expected the unchanged app to be left alone, got %d downloads and %d in sync", stats.Downloads, stats.NoAction)
+	}
+	secondRun := readLastRun(t, statePath)
+	if !secondRun.After(firstRun) {
+		t.Errorf("Expected the state file to advance after a clean run, got %v then %v", firstRun, secondRun)
+	}
+
+	// A run with errors leaves the state file alone
+	if err := os.Remove(renamedPath); err != nil {
+		t.Fatalf("Failed to remove DSL file: %v", err)
+	}
+	stats, err = syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Errors != 1 {
+		t.Fatalf("Expected 1 error for the missing file, got %d", stats.Errors)
+	}
+	if lastRun := readLastRun(t, statePath); !lastRun.Equal(secondRun) {
+		t.Errorf("Expected the state file to stay at %v after a failed run, got %v", secondRun, lastRun)
+	}
+}
@@ -1,22 +1,33 @@
 package syncer
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pepabo/difync/internal/api"
+	"github.com/pepabo/difync/internal/color"
 )
 
 // Syncer defines the interface for syncing between local DSL files and Dify
 type Syncer interface {
+	Validate() error
 	LoadAppMap() (*AppMap, error)
 	SyncAll() (*SyncStats, error)
 	SyncApp(app AppMapping) SyncResult
+	Plan() ([]SyncResult, error)
+	FetchAll() (map[string][]byte, error)
 }
 
 // Config represents the configuration for the syncer
@@ -24,173 +35,769 @@ type Config struct {
 	DifyBaseURL  string
 	DifyEmail    string
 	DifyPassword string
+	// DSLDirectory is the directory of the DSL files. If empty, the app map's dsl_directory
+	// is used, falling back to "dsl".
 	DSLDirectory string
 	AppMapFile   string
 	DryRun       bool
 	Verbose      bool
+	Quiet        bool
+	// SummaryOnly suppresses the per-app lines of a sync, which otherwise list the apps that
+	// changed or failed (and, with Verbose, the apps in sync as well)
+	SummaryOnly bool
+	// Color colorizes verbose output with ANSI escape codes
+	Color bool
+
+	// DeleteOrphans removes local files for apps that no longer exist in Dify
+	DeleteOrphans bool
+	// PruneMap removes apps that no longer exist in Dify from the app map
+	PruneMap bool
+	// RateLimit limits API requests per second (0 disables limiting)
+	RateLimit float64
+	// MaxBandwidth limits how fast DSL downloads are read, in bytes per second (0 disables throttling)
+	MaxBandwidth int64
+	// MaxIdleConns is the number of idle connections to Dify kept open for reuse (default: 32)
+	MaxIdleConns int
+	// IdleConnTimeout is how long an idle connection to Dify is kept open (default: 90s)
+	IdleConnTimeout time.Duration
+	// ConnectTimeout limits establishing a connection to Dify or the proxy (default: 10s)
+	ConnectTimeout time.Duration
+	// TLSTimeout limits the TLS handshake with Dify (default: 10s)
+	TLSTimeout time.Duration
+	// DSLExtension is the extension used for new DSL filenames (default: .yaml)
+	DSLExtension string
+	// APIPathPrefix is prepended to all API paths for a Dify mounted under a subpath
+	APIPathPrefix string
+	// ExtraHeaders are added to every API request, e.g. for an API gateway in front of Dify
+	ExtraHeaders map[string]string
+	// UserAgent is sent with every API request (default: difync)
+	UserAgent string
+	// BasicAuth holds "user:password" HTTP basic auth credentials for an API gateway in front of Dify
+	BasicAuth string
+	// ExportPath overrides the DSL export endpoint relative to /console/api/apps/{id}
+	// (default: /export?include_secret=false, older Dify versions: /dsl)
+	ExportPath string
+	// AuditLogFile is the path of a JSON Lines file that records every sync result
+	AuditLogFile string
+	// Trace writes every API request and response, with credentials redacted, to LogOutput
+	Trace bool
+	// ErrorDumpDir receives a file with the request and full response of every failed API request
+	ErrorDumpDir string
+	// LogOutput receives warnings, messages logged while constructing the syncer and the API trace (default: stderr).
+	// Set it to io.Discard to suppress them.
+	LogOutput io.Writer
+	// FileStore stores the DSL files, the app map and the state files next to it (default: the local filesystem)
+	FileStore FileStore
+	// Clock provides the current time for sync results, statistics and clock skew checks
+	// (default: the system time)
+	Clock Clock
+	// VerifyWrites re-reads downloaded files to detect truncated writes
+	VerifyWrites bool
+	// SkipUnchanged compares a downloaded DSL with the local file and leaves the file
+	// untouched if they are identical, e.g. when Dify only bumped updated_at
+	SkipUnchanged bool
+	// IgnoreKeys are YAML keys, as dot-separated paths, that SkipUnchanged ignores when
+	// comparing, e.g. export timestamps that change on every export
+	IgnoreKeys []string
+	// TouchOnSync sets the modification time of files that are in sync to the remote update time,
+	// if it is not older than the file, for tools that compare modification times
+	TouchOnSync bool
+	// RedownloadMissing downloads the DSL of apps in the app map whose local file was
+	// deleted, instead of failing them
+	RedownloadMissing bool
+	// SkipForbidden counts apps that can be seen but not exported (403) as skipped instead of failed
+	SkipForbidden bool
+	// Variant selects the DSL to download: VariantDraft (default) or VariantPublished.
+	// For VariantPublished, only the publish time decides whether to download.
+	Variant string
+	// FollowSymlinks writes downloaded DSLs through to the target of a DSL file that is a
+	// symbolic link. Without it, such files are not overwritten and the download fails.
+	FollowSymlinks bool
+	// FileMode is the permission of written DSL files and archives (default: 0644),
+	// e.g. 0600 for DSLs exported with secrets
+	FileMode os.FileMode
+	// LineEnding converts line endings of downloaded DSL files: lf, crlf or preserve (default)
+	LineEnding string
+	// FailFast stops SyncAll at the first app that fails, including failed downloads. The app map is still updated for the
+	// apps synced before it, and SyncAll returns the stats so far together with the error.
+	FailFast bool
+	// ReportFile is the path of a Markdown or HTML report written after a sync
+	ReportFile string
+	// MetricsFile is the path of a Prometheus textfile collector file written after a sync
+	MetricsFile string
+	// ManifestFile is the path of a JSON manifest with checksums of the synced files
+	ManifestFile string
+	// SinceLastRun only syncs apps updated in Dify since the last sync without errors
+	SinceLastRun bool
+	// StateFile stores the time of the last sync without errors for SinceLastRun
+	// (default: .difync-state.json next to the app map)
+	StateFile string
+	// Resume skips apps completed by an earlier sync that did not finish cleanly.
+	// Progress is kept in .difync-progress.json next to the app map until a run has no errors.
+	Resume bool
+	// Dedupe keeps the first of duplicate app map entries instead of failing
+	Dedupe bool
+	// Reinit makes init rebuild the app map from scratch instead of updating an existing one
+	Reinit bool
+	// NoAppMap syncs every app in the workspace under its sanitized name without an app map file.
+	// The mapping is built from the live app list on each run and is never written.
+	NoAppMap bool
+	// AppIDs limits init to these apps, fetched one by one instead of listing the workspace
+	AppIDs []string
+	// Overwrite makes init download the DSL of every app, replacing existing local files.
+	// By default init only downloads files that do not exist yet.
+	Overwrite bool
+	// ArchiveDeletedDir moves files of apps deleted in Dify into this directory
+	// instead of deleting them, and removes the apps from the app map
+	ArchiveDeletedDir string
+	// ClockSkewTolerance is how much newer the remote timestamp must be than the
+	// local file before it is downloaded (default: 2s)
+	ClockSkewTolerance time.Duration
+	// MaxApps aborts init if more apps than this are found (0 means unlimited)
+	MaxApps int
+	// ConfirmMaxApps is asked whether to continue when init finds more than MaxApps apps.
+	// If nil, init aborts.
+	ConfirmMaxApps func(count int) bool
+	// Concurrency is the number of DSLs init downloads at once (default: 1)
+	Concurrency int
+	// NoLock disables the lock file that keeps concurrent runs from updating the app map
+	NoLock bool
+	// Progress is called by SyncAll before each app with the number of apps done so far,
+	// and once more with done equal to total when all apps are done. Apps are synced one
+	// at a time, so it is called from the goroutine running SyncAll.
+	Progress func(done, total int, filename string)
 }
 
 // DefaultSyncer handles the synchronization between local DSL files and Dify
 type DefaultSyncer struct {
-	config Config
-	client *api.Client
+	config      Config
+	client      *api.Client
+	auditLogger *AuditLogger
+	loginErr    error
+
+	// Counts of remote timestamps compared and of those ahead of the local clock
+	timestampsCompared int
+	timestampsAhead    int
+
+	// mu guards bytesDownloaded, which is updated by concurrent downloads
+	mu sync.Mutex
+	// bytesDownloaded is the total size of the DSLs downloaded by the syncer
+	bytesDownloaded int64
+
+	// prefetchedApps holds app info fetched by SyncAll for the next planApp of each app
+	prefetchedApps map[string]*api.AppInfo
 }
 
+// defaultClockSkewTolerance is used when Config.ClockSkewTolerance is not set
+const defaultClockSkewTolerance = 2 * time.Second
+
 // NewSyncer creates a new syncer with the given configuration
 func NewSyncer(config Config) Syncer {
 	client := api.NewClient(config.DifyBaseURL)
+	client.APIPathPrefix = config.APIPathPrefix
+	client.ExportPath = config.ExportPath
+	client.ExtraHeaders = config.ExtraHeaders
+	client.UserAgent = config.UserAgent
+	if config.BasicAuth != "" {
+		client.BasicAuthUser, client.BasicAuthPassword, _ = strings.Cut(config.BasicAuth, ":")
+	}
+	client.SetRateLimit(config.RateLimit)
+	client.SetMaxBandwidth(config.MaxBandwidth)
+	client.SetConnectionPool(config.MaxIdleConns, config.IdleConnTimeout)
+	client.SetTimeouts(config.ConnectTimeout, config.TLSTimeout)
+	if config.Trace {
+		client.TraceOutput = config.logOutput()
+	}
+	client.ErrorDumpDir = config.ErrorDumpDir
 
 	// Login to get token
-	if err := client.Login(config.DifyEmail, config.DifyPassword); err != nil {
+	loginErr := client.Login(config.DifyEmail, config.DifyPassword)
+	if loginErr != nil {
 		// Log the error if login fails
-		fmt.Printf("Failed to login to Dify API: %v\n", err)
+		fmt.Fprintf(config.logOutput(), "Failed to login to Dify API: %v\n", loginErr)
+	}
+
+	syncer := newDefaultSyncer(config, client)
+	syncer.loginErr = loginErr
+	return syncer
+}
+
+// NewSyncerWithClient creates a new syncer that uses an already configured client.
+// The caller is responsible for authenticating the client; no login is attempted.
+func NewSyncerWithClient(config Config, client *api.Client) Syncer {
+	return newDefaultSyncer(config, client)
+}
+
+// newDefaultSyncer creates a DefaultSyncer, opening the audit log if configured
+func newDefaultSyncer(config Config, client *api.Client) *DefaultSyncer {
+	var auditLogger *AuditLogger
+	if config.AuditLogFile != "" {
+		logger, err := NewAuditLogger(config.AuditLogFile)
+		if err != nil {
+			fmt.Fprintf(config.logOutput(), "Warning: %v\n", err)
+		} else {
+			auditLogger = logger
+		}
 	}
 
 	return &DefaultSyncer{
-		config: config,
-		client: client,
+		config:      config,
+		client:      client,
+		auditLogger: auditLogger,
+	}
+}
+
+// logOutput returns the writer for constructor messages, defaulting to stderr
+func (c Config) logOutput() io.Writer {
+	if c.LogOutput == nil {
+		return os.Stderr
 	}
+	return c.LogOutput
 }
 
-// LoadAppMap loads the app map from the app map file
+// ErrAuthentication indicates that logging in to the Dify API failed
+var ErrAuthentication = errors.New("authentication failed")
+
+// ErrNoApps indicates that init found no apps in the Dify workspace
+var ErrNoApps = errors.New("no applications found in Dify account")
+
+// ErrNoExportPermission indicates that the user can see an app but is not allowed to export its DSL
+var ErrNoExportPermission = errors.New("no export permission")
+
+// Validate checks that the syncer is ready to use, i.e. that login succeeded
+func (s *DefaultSyncer) Validate() error {
+	if s.loginErr != nil {
+		return fmt.Errorf("%w: %v", ErrAuthentication, s.loginErr)
+	}
+	return nil
+}
+
+// Logout invalidates the Dify console token used by the syncer
+func (s *DefaultSyncer) Logout() error {
+	return s.client.Logout()
+}
+
+// LoadAppMap loads the app map from the app map file.
+// With NoAppMap, it returns an app map that discovers every app in the workspace instead.
 func (s *DefaultSyncer) LoadAppMap() (*AppMap, error) {
+	if s.config.NoAppMap {
+		appMap := discoveryAppMap()
+		s.resolveDSLDirectory(appMap)
+		return appMap, nil
+	}
+
 	// Check if app map file exists
-	_, err := os.Stat(s.config.AppMapFile)
+	_, err := s.fileStore().Stat(s.config.AppMapFile)
 	if os.IsNotExist(err) {
 		// If the file doesn't exist, prompt for initialization regardless of dry-run mode
 		return nil, fmt.Errorf("app map file not found at %s. Please run 'difync init' first to initialize the app map", s.config.AppMapFile)
 	}
 
-	file, err := os.Open(s.config.AppMapFile)
+	data, err := s.fileStore().ReadFile(s.config.AppMapFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open app map file: %w", err)
 	}
-	defer file.Close()
 
 	var appMap AppMap
-	if err := json.NewDecoder(file).Decode(&appMap); err != nil {
+	if err := unmarshalAppMap(s.config.AppMapFile, data, &appMap); err != nil {
 		return nil, fmt.Errorf("failed to decode app map: %w", err)
 	}
 
+	// Upgrade older formats in memory; the new version is written on the next save
+	if err := migrateAppMap(&appMap); err != nil {
+		return nil, err
+	}
+
+	s.resolveDSLDirectory(&appMap)
+
+	// Filenames must stay inside the DSL directory
+	if err := validateFilenames(appMap.Apps); err != nil {
+		return nil, err
+	}
+
+	// Duplicate entries make renames and deletions unpredictable
+	apps, duplicates := dedupeAppMappings(appMap.Apps)
+	if len(duplicates) > 0 {
+		if !s.config.Dedupe {
+			return nil, fmt.Errorf("app map contains duplicate entries (use --dedupe to keep the first of each): %s", strings.Join(duplicates, "; "))
+		}
+		for _, duplicate := range duplicates {
+			fmt.Fprintf(s.config.logOutput(), "Warning: Ignoring duplicate app map entry: %s\n", duplicate)
+		}
+		appMap.Apps = apps
+	}
+
 	return &appMap, nil
 }
 
-// InitializeAppMap creates a new app map file by fetching app list from Dify API
+// validateFilenames rejects filenames that are absolute or would escape the DSL directory.
+// Filenames may contain subdirectories such as "team-a/bot.yaml".
+func validateFilenames(apps []AppMapping) error {
+	for _, app := range apps {
+		if app.Match != nil {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(app.Filename)) {
+			return fmt.Errorf("invalid filename %q for app %s: must be a relative path inside the DSL directory", app.Filename, app.AppID)
+		}
+	}
+	return nil
+}
+
+// dedupeAppMappings removes entries that repeat the app ID or filename of an earlier entry.
+// It returns the remaining entries and a description of each removed duplicate.
+func dedupeAppMappings(apps []AppMapping) ([]AppMapping, []string) {
+	filenamesByID := make(map[string]string) // app ID -> filename
+	idsByFilename := make(map[string]string) // filename -> app ID
+
+	kept := make([]AppMapping, 0, len(apps))
+	var duplicates []string
+
+	for _, app := range apps {
+		// Pattern entries have no app ID or filename of their own
+		if app.Match != nil {
+			kept = append(kept, app)
+			continue
+		}
+
+		// Entries identified by name are told apart by their name
+		id, key, label := app.AppID, app.AppID, "app_id"
+		if isNamedEntry(app) {
+			id, key, label = app.Name, "name:"+app.Name, "name"
+		}
+
+		if filename, ok := filenamesByID[key]; ok {
+			duplicates = append(duplicates, fmt.Sprintf("%s %s is mapped to both %s and %s", label, id, filename, app.Filename))
+			continue
+		}
+		if appID, ok := idsByFilename[app.Filename]; ok {
+			duplicates = append(duplicates, fmt.Sprintf("filename %s is used by both %s and %s", app.Filename, appID, id))
+			continue
+		}
+
+		filenamesByID[key] = app.Filename
+		idsByFilename[app.Filename] = id
+		kept = append(kept, app)
+	}
+
+	return kept, duplicates
+}
+
+// InitializeAppMap creates the app map file by fetching app list from Dify API.
+// An existing app map is updated in place: entries for apps that still exist keep their
+// filename and overrides, new apps are added, and deleted apps are removed only with PruneMap.
+// Set Reinit to rebuild the app map from scratch.
 func (s *DefaultSyncer) InitializeAppMap() (*AppMap, error) {
+	// Only the apps in AppIDs are checked, so pruning would remove every other entry
+	if s.config.PruneMap && len(s.config.AppIDs) > 0 {
+		return nil, fmt.Errorf("--prune-map cannot be used with --app-id")
+	}
+
 	// Fetch application list from API
-	appList, err := s.client.GetAppList()
+	appList, err := s.initAppList()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get app list from API: %w", err)
+		return nil, err
 	}
 
+	// An empty list usually means the wrong workspace or account; leave an existing app map alone
 	if len(appList) == 0 {
-		return nil, fmt.Errorf("no applications found in Dify account")
+		return nil, ErrNoApps
 	}
 
-	// Create DSL directory and its parent directories if they don't exist
-	if err := os.MkdirAll(s.config.DSLDirectory, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create DSL directory: %w", err)
+	// Guard against runaway downloads, e.g. when pointed at the wrong workspace
+	if s.config.MaxApps > 0 && len(appList) > s.config.MaxApps {
+		if s.config.ConfirmMaxApps == nil || !s.config.ConfirmMaxApps(len(appList)) {
+			return nil, fmt.Errorf("found %d applications, which exceeds the limit of %d (use --max-apps to raise it)", len(appList), s.config.MaxApps)
+		}
 	}
 
+	// Process apps in a stable order so duplicate names get the same suffixes on every run
+	sort.SliceStable(appList, func(i, j int) bool {
+		if appList[i].Name != appList[j].Name {
+			return appList[i].Name < appList[j].Name
+		}
+		return appList[i].ID < appList[j].ID
+	})
+
 	// Create app map directory and its parent directories if they don't exist
 	appMapDir := filepath.Dir(s.config.AppMapFile)
-	if err := os.MkdirAll(appMapDir, 0755); err != nil {
+	if err := s.fileStore().MkdirAll(appMapDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create directory for app map file: %w", err)
 	}
 
+	// Keep other runs from changing the app map between reading and writing it
+	unlock, err := s.lockAppMap()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Build on the existing app map unless a from-scratch init is requested
+	existingMap, err := s.loadExistingAppMap()
+	if err != nil {
+		return nil, err
+	}
+	var existing []AppMapping
+	if existingMap != nil {
+		existing = existingMap.Apps
+	}
+	s.resolveDSLDirectory(existingMap)
+
+	// Create DSL directory and its parent directories if they don't exist
+	if err := s.fileStore().MkdirAll(s.config.DSLDirectory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create DSL directory: %w", err)
+	}
+
+	remoteIDs := make(map[string]bool, len(appList))
+	for _, app := range appList {
+		remoteIDs[app.ID] = true
+	}
+
 	// Create app map
 	appMap := &AppMap{
-		Apps: make([]AppMapping, 0, len(appList)),
+		Version: AppMapVersion,
+		Apps:    make([]AppMapping, 0, len(appList)),
+	}
+	if existingMap != nil {
+		appMap.DSLDirectory = existingMap.DSLDirectory
+	}
+
+	// Existing entries keep their filename and overrides; entries for deleted apps are kept unless pruning
+	existingByID := make(map[string]AppMapping)
+	removed := 0
+	for _, app := range existing {
+		if app.Match == nil && !remoteIDs[app.AppID] {
+			if s.config.PruneMap {
+				if !s.config.Quiet {
+					fmt.Printf("Removing %s (ID: %s) from app map: app no longer exists in Dify\n", app.Filename, app.AppID)
+				}
+				removed++
+				continue
+			}
+			appMap.Apps = append(appMap.Apps, app)
+			continue
+		}
+		if app.Match == nil {
+			existingByID[app.AppID] = app
+		}
+	}
+
+	// Apps matched by pattern entries are expanded on sync and get no entry of their own
+	patterns, concrete := splitPatternEntries(existing)
+	expandedApps, err := s.expandPatterns(patterns, concrete, appList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand app map patterns: %w", err)
+	}
+	expandedIDs := make(map[string]bool, len(expandedApps))
+	for _, app := range expandedApps {
+		expandedIDs[app.AppID] = true
+	}
+	for _, app := range patterns {
+		appMap.Apps = append(appMap.Apps, app)
 	}
 
 	// Map to track used filenames to avoid duplicates
 	usedFilenames := make(map[string]bool)
+	for _, app := range existing {
+		if app.Match == nil {
+			usedFilenames[app.Filename] = true
+		}
+	}
 
-	// For each app, add an entry to the app map
+	// For each app, add an entry to the app map. Filenames are chosen here, one app after
+	// the other, so that duplicate names get the same suffixes regardless of Concurrency.
+	added := 0
+	var downloads []initDownload
 	for _, app := range appList {
-		// Create a safe filename from app name
-		// Preserve non-ASCII characters like Japanese
-		safeName := s.sanitizeFilename(app.Name)
-		fmt.Printf("Debug - sanitizeFilename(%q) = %q\n", app.Name, safeName)
-		filename := safeName + ".yaml"
+		if expandedIDs[app.ID] {
+			continue
+		}
 
-		// Avoid duplicate filenames
-		// Check if file exists in filesystem
-		fileExists := s.fileExists(filepath.Join(s.config.DSLDirectory, filename))
-		// Check if filename is already used in the map
-		filenameUsed := usedFilenames[filename]
+		mapping, ok := existingByID[app.ID]
+		if !ok {
+			mapping = AppMapping{
+				Filename: s.newAppFilename(app, usedFilenames),
+				AppID:    app.ID,
+			}
+			added++
+		}
+		if app.Mode != "" {
+			mapping.Mode = app.Mode
+		}
 
-		counter := 1
-		baseName := safeName
+		// Record the filename as used
+		usedFilenames[mapping.Filename] = true
 
-		// Loop until a unique filename is found
-		for fileExists || filenameUsed {
-			fmt.Printf("Debug - File exists or already used: %s, incrementing counter to %d\n", filename, counter)
-			filename = fmt.Sprintf("%s_%d.yaml", baseName, counter)
-			fileExists = s.fileExists(filepath.Join(s.config.DSLDirectory, filename))
-			filenameUsed = usedFilenames[filename]
-			counter++
+		appMap.Apps = append(appMap.Apps, mapping)
+		if mapping.Skip || mapping.ReadOnly {
+			continue
 		}
 
-		fmt.Printf("Debug - Final filename for app %q (ID: %s): %s\n", app.Name, app.ID, filename)
+		// Also download the DSL for this app if it doesn't exist yet, or always with Overwrite
+		localPath := filepath.Join(s.config.DSLDirectory, mapping.Filename)
+		_, statErr := s.fileStore().Stat(localPath)
+		if exists := !os.IsNotExist(statErr); !exists || s.config.Overwrite {
+			downloads = append(downloads, initDownload{
+				index:  len(appMap.Apps) - 1,
+				app:    app,
+				path:   localPath,
+				exists: exists,
+			})
+		}
+	}
 
-		// Record the filename as used
-		usedFilenames[filename] = true
+	// Download the DSLs, up to Concurrency at once; each download only updates its own entry
+	forEachConcurrently(len(downloads), s.concurrency(), func(i int) {
+		download := downloads[i]
+		if syncedAt, ok := s.downloadInitialDSL(download); ok {
+			appMap.Apps[download.index].LastSyncedAt = &syncedAt
+		}
+	})
 
-		appMap.Apps = append(appMap.Apps, AppMapping{
-			Filename: filename,
-			AppID:    app.ID,
-		})
+	sortAppMappings(appMap.Apps)
 
-		// Also download the DSL for this app if it doesn't exist yet
-		localPath := filepath.Join(s.config.DSLDirectory, filename)
-		if _, err := os.Stat(localPath); os.IsNotExist(err) {
-			if s.config.Verbose {
-				fmt.Printf("Downloading initial DSL for %s to %s\n", app.Name, localPath)
-			}
+	if s.bytesDownloaded > 0 && !s.config.Quiet {
+		fmt.Printf("Downloaded %s of DSL files\n", FormatBytes(s.bytesDownloaded))
+	}
 
-			dsl, err := s.client.GetDSL(app.ID)
-			if err != nil {
-				fmt.Printf("Warning: Failed to download DSL for %s: %v\n", app.Name, err)
-				continue
-			}
+	// Write the app map to file
+	if !s.config.DryRun {
+		if err := s.writeAppMap(appMap); err != nil {
+			return nil, fmt.Errorf("failed to write app map file: %w", err)
+		}
+	}
 
-			if !s.config.DryRun {
-				if err := os.WriteFile(localPath, dsl, 0644); err != nil {
-					fmt.Printf("Warning: Failed to write DSL file for %s: %v\n", app.Name, err)
-				}
-			}
+	if !s.config.Quiet {
+		switch {
+		case !s.config.DryRun && existingMap != nil:
+			fmt.Printf("Updated app map file at %s with %d applications (%d added, %d removed)\n", s.config.AppMapFile, len(appMap.Apps), added, removed)
+		case !s.config.DryRun:
+			fmt.Printf("Created new app map file at %s with %d applications\n", s.config.AppMapFile, len(appMap.Apps))
+		case existingMap != nil:
+			fmt.Printf("Dry run: Would update app map file at %s with %d applications (%d added, %d removed)\n", s.config.AppMapFile, len(appMap.Apps), added, removed)
+		default:
+			fmt.Printf("Dry run: Would create app map file at %s with %d applications\n", s.config.AppMapFile, len(appMap.Apps))
 		}
 	}
 
-	// Write the app map to file
-	if !s.config.DryRun {
-		file, err := os.Create(s.config.AppMapFile)
+	return appMap, nil
+}
+
+// initAppList returns the apps to initialize: the apps in Config.AppIDs if set,
+// or every app in the workspace
+func (s *DefaultSyncer) initAppList() ([]api.AppInfo, error) {
+	if len(s.config.AppIDs) == 0 {
+		appList, err := s.client.GetAppList()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create app map file: %w", err)
+			return nil, fmt.Errorf("failed to get app list from API: %w", err)
 		}
-		defer file.Close()
+		return appList, nil
+	}
 
-		encoder := json.NewEncoder(file)
-		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(appMap); err != nil {
-			return nil, fmt.Errorf("failed to write app map file: %w", err)
+	appList := make([]api.AppInfo, 0, len(s.config.AppIDs))
+	seen := make(map[string]bool, len(s.config.AppIDs))
+	for _, appID := range s.config.AppIDs {
+		if seen[appID] {
+			continue
+		}
+		seen[appID] = true
+
+		info, err := s.client.GetAppInfo(appID)
+		if api.IsNotFound(err) {
+			return nil, fmt.Errorf("app %s does not exist in Dify", appID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get app info for %s: %w", appID, err)
 		}
+		if info.ID == "" {
+			info.ID = appID
+		}
+		appList = append(appList, *info)
+	}
+	return appList, nil
+}
 
-		fmt.Printf("Created new app map file at %s with %d applications\n", s.config.AppMapFile, len(appMap.Apps))
-	} else {
-		fmt.Printf("Dry run: Would create app map file at %s with %d applications\n", s.config.AppMapFile, len(appMap.Apps))
+// loadExistingAppMap returns the current app map, or nil when there is none or Reinit is set
+func (s *DefaultSyncer) loadExistingAppMap() (*AppMap, error) {
+	if s.config.Reinit || !s.fileExists(s.config.AppMapFile) {
+		return nil, nil
 	}
 
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing app map (use --reinit to recreate it): %w", err)
+	}
 	return appMap, nil
 }
 
+// newAppFilename creates a filename for a new app map entry that is neither used in the map
+// nor taken by an existing file
+func (s *DefaultSyncer) newAppFilename(app api.AppInfo, usedFilenames map[string]bool) string {
+	// Create a safe filename from app name
+	// Preserve non-ASCII characters like Japanese
+	safeName := s.sanitizeFilename(app.Name)
+	filename := safeName + s.dslExtension()
+
+	// Avoid duplicate filenames
+	// Check if file exists in filesystem
+	fileExists := s.fileExists(filepath.Join(s.config.DSLDirectory, filename))
+	// Check if filename is already used in the map
+	filenameUsed := usedFilenames[filename]
+
+	counter := 1
+	baseName := safeName
+
+	// Loop until a unique filename is found
+	for fileExists || filenameUsed {
+		filename = fmt.Sprintf("%s_%d%s", baseName, counter, s.dslExtension())
+		fileExists = s.fileExists(filepath.Join(s.config.DSLDirectory, filename))
+		filenameUsed = usedFilenames[filename]
+		counter++
+	}
+
+	return filename
+}
+
+// dslExtension returns the configured DSL file extension, defaulting to .yaml
+func (s *DefaultSyncer) dslExtension() string {
+	ext := s.config.DSLExtension
+	if ext == "" {
+		return ".yaml"
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// FindOrphanedFiles lists DSL files in the DSL directory that are not referenced by the app map,
+// except for files matched by the .difyncignore file of the DSL directory
+func (s *DefaultSyncer) FindOrphanedFiles() ([]string, error) {
+	appMap, err := s.LoadAppMap()
+	if err != nil {
+		return nil, err
+	}
+
+	// Files of apps matched by pattern entries are not orphans either
+	patterns, apps := splitPatternEntries(appMap.Apps)
+	if len(patterns) > 0 {
+		remoteAppList, err := s.client.GetAppList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get app list from API: %w", err)
+		}
+		expandedApps, err := s.expandPatterns(patterns, apps, remoteAppList)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand app map patterns: %w", err)
+		}
+		apps = append(apps, expandedApps...)
+	}
+
+	mapped := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		mapped[app.Filename] = true
+	}
+
+	entries, err := s.readDir(s.config.DSLDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DSL directory: %w", err)
+	}
+
+	// Files listed in .difyncignore are managed by hand
+	ignore, err := s.loadIgnore()
+	if err != nil {
+		return nil, err
+	}
+
+	// Consider the configured extension as well as the common YAML ones
+	extensions := map[string]bool{".yaml": true, ".yml": true, s.dslExtension(): true}
+
+	orphans := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !extensions[filepath.Ext(entry.Name())] {
+			continue
+		}
+		if !mapped[entry.Name()] && !ignore.match(entry.Name()) {
+			orphans = append(orphans, entry.Name())
+		}
+	}
+
+	return orphans, nil
+}
+
+// RemoveOrphanedFiles removes DSL files found by FindOrphanedFiles from the DSL directory.
+// Files that cannot be removed are skipped with a warning. It returns the number of removed
+// files, and an error if any file could not be removed.
+func (s *DefaultSyncer) RemoveOrphanedFiles(names []string) (int, error) {
+	removed := 0
+	for _, name := range names {
+		path := filepath.Join(s.config.DSLDirectory, name)
+		if err := s.fileStore().Remove(path); err != nil {
+			fmt.Fprintf(s.config.logOutput(), "Warning: Failed to remove %s: %v\n", path, err)
+			continue
+		}
+		removed++
+		if s.config.Verbose {
+			fmt.Printf("Removed %s\n", path)
+		}
+	}
+
+	if failed := len(names) - removed; failed > 0 {
+		return removed, fmt.Errorf("failed to remove %d of %d orphaned files", failed, len(names))
+	}
+	return removed, nil
+}
+
+// sortAppMappings sorts app mappings by filename, then app ID, to keep the app map stable
+func sortAppMappings(apps []AppMapping) {
+	sort.SliceStable(apps, func(i, j int) bool {
+		if apps[i].Filename != apps[j].Filename {
+			return apps[i].Filename < apps[j].Filename
+		}
+		return apps[i].AppID < apps[j].AppID
+	})
+}
+
 // fileExists checks if a file exists
 func (s *DefaultSyncer) fileExists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := s.fileStore().Stat(path)
 	return !os.IsNotExist(err)
 }
 
+// uniqueFilename returns a filename for the base name and extension that does not exist in the
+// DSL directory yet, adding a numeric suffix if needed
+func (s *DefaultSyncer) uniqueFilename(baseName, ext string) string {
+	filename := baseName + ext
+	for counter := 1; s.fileExists(filepath.Join(s.config.DSLDirectory, filename)); counter++ {
+		filename = fmt.Sprintf("%s_%d%s", baseName, counter, ext)
+	}
+	return filename
+}
+
+// renamedFilename returns the filename an app is renamed to after its remote name changed, or
+// "" if its filename still matches the remote name. The extension is not compared and is kept
+// on rename, so changing --dsl-extension does not rename existing files.
+func (s *DefaultSyncer) renamedFilename(app AppMapping, remoteName string) string {
+	safeName := renamedBaseName(app.Filename, s.sanitizeFilename(remoteName))
+	ext := filepath.Ext(app.Filename)
+	if strings.TrimSuffix(app.Filename, ext) == safeName {
+		return ""
+	}
+	if ext == "" {
+		ext = s.dslExtension()
+	}
+	return s.uniqueFilename(safeName, ext)
+}
+
+// renamedBaseName returns the base name a renamed app gets: the sanitized remote name
+// in the same subdirectory as its current filename
+func renamedBaseName(filename, safeName string) string {
+	dir := path.Dir(filepath.ToSlash(filename))
+	if dir == "." {
+		return safeName
+	}
+	return path.Join(dir, safeName)
+}
+
 // sanitizeFilename creates a safe filename from an app name
 func (s *DefaultSyncer) sanitizeFilename(name string) string {
 	// Result string
@@ -202,6 +809,11 @@ func (s *DefaultSyncer) sanitizeFilename(name string) string {
 
 	// Convert spaces to underscores
 	for _, r := range name {
+		// Drop characters that are invisible but would make the filename differ
+		if isInvisibleRune(r) {
+			continue
+		}
+
 		if unicode.IsSpace(r) {
 			result.WriteRune('_')
 		} else {
@@ -220,151 +832,300 @@ func (s *DefaultSyncer) sanitizeFilename(name string) string {
 		}
 	}
 
+	// Windows does not allow filenames ending in a dot or space
+	sanitized := strings.TrimRight(result.String(), ". ")
+
 	// Use default name if result is empty
-	if result.Len() == 0 {
+	if sanitized == "" {
 		return "app"
 	}
 
-	fmt.Printf("Debug - sanitizeFilename internal: %q -> %q\n", name, result.String())
-	return result.String()
+	// Keep the filename within the filesystem limit
+	if len(sanitized) > maxBaseNameBytes {
+		sanitized = truncateBaseName(sanitized, name)
+	}
+
+	// Prefix reserved Windows device names so the file can be created on every OS
+	if isReservedDeviceName(sanitized) {
+		sanitized = "_" + sanitized
+	}
+
+	return sanitized
+}
+
+// zeroWidthRunes are invisible formatting characters that often sneak into pasted names
+var zeroWidthRunes = map[rune]bool{
+	'\u200B': true, // zero width space
+	'\u200C': true, // zero width non-joiner
+	'\u200D': true, // zero width joiner
+	'\u200E': true, // left-to-right mark
+	'\u200F': true, // right-to-left mark
+	'\u2060': true, // word joiner
+	'\uFEFF': true, // byte order mark / zero width no-break space
+}
+
+// isInvisibleRune reports whether a rune is a control character, a zero-width character,
+// or the replacement for invalid UTF-8, none of which belong in a filename
+func isInvisibleRune(r rune) bool {
+	return unicode.IsControl(r) || zeroWidthRunes[r] || r == utf8.RuneError
+}
+
+// maxFilenameBytes is the filename length limit on common filesystems (ext4, APFS, NTFS)
+const maxFilenameBytes = 255
+
+// maxBaseNameBytes leaves room for a DSL extension such as ".yaml" and a "_N" deduplication suffix
+const maxBaseNameBytes = maxFilenameBytes - len(".yaml") - 6
+
+// truncateBaseName shortens a sanitized name to fit within maxBaseNameBytes.
+// A short hash of the original name is appended so distinct long names stay unique.
+func truncateBaseName(sanitized, original string) string {
+	sum := sha256.Sum256([]byte(original))
+	suffix := "_" + hex.EncodeToString(sum[:])[:8]
+
+	// Cut on a rune boundary so multibyte characters are not split
+	limit := maxBaseNameBytes - len(suffix)
+	cut := 0
+	for i, r := range sanitized {
+		if i+utf8.RuneLen(r) > limit {
+			break
+		}
+		cut = i + utf8.RuneLen(r)
+	}
+
+	return strings.TrimRight(sanitized[:cut], ". ") + suffix
+}
+
+// reservedDeviceNames lists the device names that Windows reserves regardless of extension
+var reservedDeviceNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isReservedDeviceName checks if a base name is a reserved Windows device name.
+// Windows ignores anything after the first dot, so "con.backup" is reserved as well.
+func isReservedDeviceName(name string) bool {
+	stem := name
+	if i := strings.Index(stem, "."); i >= 0 {
+		stem = stem[:i]
+	}
+	return reservedDeviceNames[strings.ToUpper(stem)]
 }
 
 // SyncAll synchronizes all apps in the app map
 func (s *DefaultSyncer) SyncAll() (*SyncStats, error) {
+	// Keep other runs from changing the app map between reading and writing it
+	unlock, err := s.lockAppMap()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	appMap, err := s.LoadAppMap()
 	if err != nil {
 		return nil, err
 	}
 
+	// Create a missing DSL directory so apps are downloaded instead of failing one by one
+	dslDirCreated := false
+	if _, err := s.fileStore().Stat(s.config.DSLDirectory); os.IsNotExist(err) {
+		if s.config.DryRun {
+			fmt.Printf("Dry run: Would create DSL directory %s\n", s.config.DSLDirectory)
+		} else {
+			if err := s.fileStore().MkdirAll(s.config.DSLDirectory, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create DSL directory: %w", err)
+			}
+			if !s.config.Quiet {
+				fmt.Printf("Created DSL directory %s\n", s.config.DSLDirectory)
+			}
+		}
+		dslDirCreated = true
+	}
+
 	stats := &SyncStats{
-		Total:     len(appMap.Apps),
-		StartTime: time.Now(),
+		StartTime: s.now(),
 	}
+	bytesBefore := s.bytesDownloaded
 
-	// Get current app list to compare names
-	remoteAppList, err := s.client.GetAppList()
+	// Apps are synced in a stable order; the app map itself keeps its order when it is rewritten below
+	apps, run, err := s.prepareSync(appMap, dslDirCreated)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get app list from API: %w", err)
+		return nil, err
 	}
+	stats.Total = len(apps)
+	progress := run.progress
 
-	// Create a map of app IDs to app info for quick lookup
-	remoteApps := make(map[string]api.AppInfo)
-	for _, app := range remoteAppList {
-		remoteApps[app.ID] = app
-	}
+	s.warnTokenExpiry(len(apps))
 
 	// Track name changes for renaming files
 	nameChanges := make(map[string]string) // old filename -> new filename
 	renamedApps := []AppMapping{}          // Updated app mappings
 
+	// Track downloaded apps to record their sync timestamps
+	syncedApps := make(map[string]AppMapping) // entry key -> updated app mapping
+
 	// First, check for remote apps that have been deleted
 	deletedApps := []AppMapping{}
 
-	for _, app := range appMap.Apps {
-		// Check if the app still exists in remote
-		exists, err := s.client.DoesDSLExist(app.AppID)
+	// Set when FailFast stops the sync at a failed app
+	var failErr error
+
+	// App info prefetched for apps that are not synced is not reused by a later run
+	defer func() { s.prefetchedApps = nil }()
+
+	for i, app := range apps {
+		s.reportProgress(i, len(apps), app.Filename)
+
+		plan, err := s.planSyncAll(app, run)
 		if err != nil {
-			fmt.Printf("Warning: Failed to check if app %s exists: %v\n", app.AppID, err)
-			continue
+			return nil, err
 		}
 
-		if !exists {
-			// App has been deleted remotely
-			deletedApps = append(deletedApps, app)
+		switch {
+		case plan.result.Action == ActionSkip:
+			// Skipped apps and apps completed by an interrupted run are never touched
+			stats.add(plan.result)
+			s.audit(plan.result)
 			if s.config.Verbose {
-				fmt.Printf("App %s (ID: %s) has been deleted remotely\n", app.Filename, app.AppID)
-			}
-
-			// Delete local file if not in dry run mode
-			if !s.config.DryRun {
-				localPath := filepath.Join(s.config.DSLDirectory, app.Filename)
-				if err := os.Remove(localPath); err != nil {
-					fmt.Printf("Warning: Failed to delete local file %s: %v\n", localPath, err)
-				} else if s.config.Verbose {
-					fmt.Printf("Deleted local file %s\n", localPath)
+				if app.Skip {
+					fmt.Printf("Skipped %s (app_id: %s)\n", app.Filename, app.AppID)
+				} else {
+					fmt.Printf("Skipped %s (app_id: %s), completed by an earlier run\n", app.Filename, app.AppID)
 				}
 			}
-
-			// Count as download since we're reflecting remote state
-			stats.Downloads++
 			continue
-		}
-
-		// Check if app name has changed
-		if remoteApp, ok := remoteApps[app.AppID]; ok {
-			// Create a safe filename from the remote app name
-			safeName := s.sanitizeFilename(remoteApp.Name)
-			expectedFilename := safeName + ".yaml"
 
-			// If the current filename doesn't match the expected one based on remote name
-			if app.Filename != expectedFilename {
-				if s.config.Verbose {
-					fmt.Printf("App name changed for %s (ID: %s): %s -> %s\n",
-						app.Filename, app.AppID, app.Filename, expectedFilename)
-				}
+		case plan.deleted:
+			// App has been deleted remotely
+			if s.config.Verbose {
+				fmt.Printf("App %s (ID: %s) has been deleted remotely\n", app.Filename, app.AppID)
+			}
 
-				// Check if file exists in filesystem
-				fileExists := s.fileExists(filepath.Join(s.config.DSLDirectory, expectedFilename))
-				counter := 1
-				baseName := safeName
+			// Read-only apps keep their file and app map entry
+			if app.ReadOnly {
+				fmt.Fprintf(s.config.logOutput(), "Warning: Read-only app %s (ID: %s) no longer exists remotely, keeping it\n", app.Filename, app.AppID)
+				stats.add(plan.result)
+				s.audit(plan.result)
+				continue
+			}
 
-				// Loop until a unique filename is found
-				for fileExists {
-					expectedFilename = fmt.Sprintf("%s_%d.yaml", baseName, counter)
-					fileExists = s.fileExists(filepath.Join(s.config.DSLDirectory, expectedFilename))
-					counter++
+			switch {
+			case s.config.ArchiveDeletedDir != "":
+				// Move local file into the archive directory if not in dry run mode
+				if !s.config.DryRun {
+					localPath := filepath.Join(s.config.DSLDirectory, app.Filename)
+					if archivedPath, err := s.archiveDeletedFile(localPath); err != nil {
+						fmt.Fprintf(s.config.logOutput(), "Warning: Failed to archive local file %s: %v\n", localPath, err)
+					} else if s.config.Verbose {
+						fmt.Printf("Archived local file %s to %s\n", localPath, archivedPath)
+					}
 				}
-
+			case s.config.DeleteOrphans:
+				// Delete local file if not in dry run mode
 				if !s.config.DryRun {
-					// Rename the file
-					oldPath := filepath.Join(s.config.DSLDirectory, app.Filename)
-					newPath := filepath.Join(s.config.DSLDirectory, expectedFilename)
-
-					if err := os.Rename(oldPath, newPath); err != nil {
-						fmt.Printf("Warning: Failed to rename file %s to %s: %v\n", oldPath, newPath, err)
+					localPath := filepath.Join(s.config.DSLDirectory, app.Filename)
+					if err := s.fileStore().Remove(localPath); err != nil {
+						fmt.Fprintf(s.config.logOutput(), "Warning: Failed to delete local file %s: %v\n", localPath, err)
 					} else if s.config.Verbose {
-						fmt.Printf("Renamed file from %s to %s\n", oldPath, newPath)
+						fmt.Printf("Deleted local file %s\n", localPath)
 					}
 				}
+			default:
+				fmt.Fprintf(s.config.logOutput(), "Warning: App %s (ID: %s) no longer exists remotely, keeping local file (use --delete-orphans to remove it)\n", app.Filename, app.AppID)
+			}
+
+			// Without deletion, archiving or pruning the app is left untouched
+			if plan.result.Action != ActionDelete {
+				stats.add(plan.result)
+				s.audit(plan.result)
+				continue
+			}
 
-				// Record the name change
-				nameChanges[app.Filename] = expectedFilename
+			// Remove the app from the app map once its local file is gone or pruning is requested
+			deletedApps = append(deletedApps, app)
+			stats.add(plan.result)
+			s.audit(plan.result)
+			s.printAppResult(plan.result, "")
+			if s.config.DryRun {
+				stats.Planned = append(stats.Planned, plan.result)
+			}
+			continue
+
+		case plan.result.Action == ActionRename:
+			expectedFilename := plan.result.NewFilename
+			if s.config.Verbose {
+				fmt.Printf("App name changed for %s (ID: %s): %s -> %s\n",
+					app.Filename, app.AppID, app.Filename, expectedFilename)
+			}
 
-				// Update the app mapping
-				newMapping := AppMapping{
-					Filename: expectedFilename,
-					AppID:    app.AppID,
+			if !s.config.DryRun {
+				// Rename the file
+				oldPath := filepath.Join(s.config.DSLDirectory, app.Filename)
+				newPath := filepath.Join(s.config.DSLDirectory, expectedFilename)
+
+				if err := s.fileStore().MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+					fmt.Fprintf(s.config.logOutput(), "Warning: Failed to create directory for %s: %v\n", newPath, err)
+				} else if err := s.fileStore().Rename(oldPath, newPath); err != nil {
+					fmt.Fprintf(s.config.logOutput(), "Warning: Failed to rename file %s to %s: %v\n", oldPath, newPath, err)
+				} else if s.config.Verbose {
+					fmt.Printf("Renamed file from %s to %s\n", oldPath, newPath)
 				}
-				renamedApps = append(renamedApps, newMapping)
+			}
 
-				// Don't process this app further in this iteration
-				continue
+			// Record the name change
+			nameChanges[app.Filename] = expectedFilename
+
+			// Update the app mapping, keeping its other fields
+			newMapping := app
+			newMapping.Filename = expectedFilename
+			renamedApps = append(renamedApps, newMapping)
+			stats.add(plan.result)
+			s.audit(plan.result)
+			s.printAppResult(plan.result, "")
+			if s.config.DryRun {
+				stats.Planned = append(stats.Planned, plan.result)
 			}
+
+			// Don't process this app further in this iteration
+			continue
 		}
 
-		// Process existing apps
-		result := s.SyncApp(app)
+		// Download or keep the app as planned
+		result := s.applyPlan(app, plan.result)
+		s.audit(result)
+		s.recordProgress(progress, result)
+		stats.add(result)
+
+		// Apps expanded from patterns have no entry of their own in the app map
+		expanded := run.expandedIDs[app.AppID]
+		if result.Action == ActionDownload && result.Success && !s.config.DryRun && !expanded {
+			syncedAt := result.Timestamp
+			remoteUpdatedAt := result.RemoteUpdatedAt
+			app.LastSyncedAt = &syncedAt
+			app.LastRemoteUpdatedAt = &remoteUpdatedAt
+			syncedApps[entryKey(app)] = app
+		}
 
-		switch result.Action {
-		case ActionDownload:
-			stats.Downloads++
-		case ActionNone:
-			stats.NoAction++
-		case ActionError:
-			stats.Errors++
+		if expanded {
+			s.printAppResult(result, ", from pattern")
+		} else {
+			s.printAppResult(result, "")
 		}
 
-		if s.config.Verbose {
-			fmt.Printf("Synced %s (app_id: %s): %s\n", app.Filename, app.AppID, result.Action)
-			if result.Error != nil {
-				fmt.Printf("  Error: %v\n", result.Error)
-			}
+		// Failed downloads keep ActionDownload, so check Success rather than the action
+		if !result.Success && s.config.FailFast {
+			failErr = fmt.Errorf("stopped after %s (app_id: %s) failed: %w", app.Filename, app.AppID, result.Error)
+			break
 		}
 	}
+	s.reportProgress(len(apps), len(apps), "")
 
-	// Update app map if apps were deleted or renamed
-	if (len(deletedApps) > 0 || len(renamedApps) > 0) && !s.config.DryRun {
-		// Create new app map without deleted apps and with updated filenames
+	// Update app map if apps were deleted, renamed or downloaded
+	if (len(deletedApps) > 0 || len(renamedApps) > 0 || len(syncedApps) > 0) && !s.config.DryRun {
+		// Create new app map without deleted apps and with updated filenames.
+		// Entries keep their original order, renamed ones included, to keep diffs small.
 		updatedApps := make([]AppMapping, 0, len(appMap.Apps)-len(deletedApps))
 
 		for _, app := range appMap.Apps {
@@ -391,6 +1152,15 @@ func (s *DefaultSyncer) SyncAll() (*SyncStats, error) {
 				}
 			}
 
+			// Add the downloaded app with its sync timestamps; entries identified by name keep
+			// their empty app ID
+			if syncedApp, ok := syncedApps[entryKey(app)]; ok && !isRenamed {
+				app.LastSyncedAt = syncedApp.LastSyncedAt
+				app.LastRemoteUpdatedAt = syncedApp.LastRemoteUpdatedAt
+				updatedApps = append(updatedApps, app)
+				continue
+			}
+
 			// Add the unchanged app
 			if !isRenamed {
 				updatedApps = append(updatedApps, app)
@@ -399,18 +1169,12 @@ func (s *DefaultSyncer) SyncAll() (*SyncStats, error) {
 
 		// Save updated app map
 		updatedAppMap := &AppMap{
-			Apps: updatedApps,
+			Version:      AppMapVersion,
+			DSLDirectory: appMap.DSLDirectory,
+			Apps:         updatedApps,
 		}
 
-		file, err := os.Create(s.config.AppMapFile)
-		if err != nil {
-			return stats, fmt.Errorf("failed to update app map file: %w", err)
-		}
-		defer file.Close()
-
-		encoder := json.NewEncoder(file)
-		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(updatedAppMap); err != nil {
+		if err := s.writeAppMap(updatedAppMap); err != nil {
 			return stats, fmt.Errorf("failed to write updated app map file: %w", err)
 		}
 
@@ -424,25 +1188,256 @@ func (s *DefaultSyncer) SyncAll() (*SyncStats, error) {
 		}
 	}
 
-	stats.EndTime = time.Now()
+	// Write the manifest from the files as they are on disk now
+	if s.config.ManifestFile != "" && !s.config.DryRun {
+		if err := s.writeManifest(finalApps(apps, deletedApps, renamedApps)); err != nil {
+			return stats, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+
+	// Only a run without errors moves the starting point of the next run forward
+	// and ends an interrupted run
+	clean := failErr == nil && stats.Err() == nil && stats.Errors == 0
+	if s.config.SinceLastRun && !s.config.DryRun && clean {
+		if err := s.saveLastRun(stats.StartTime); err != nil {
+			return stats, fmt.Errorf("failed to write state file: %w", err)
+		}
+	}
+	if progress != nil && clean {
+		if err := progress.remove(); err != nil {
+			return stats, fmt.Errorf("failed to remove progress file: %w", err)
+		}
+	}
+
+	s.warnClockSkew()
+
+	stats.BytesDownloaded = s.bytesDownloaded - bytesBefore
+	stats.EndTime = s.now()
 	stats.Duration = stats.EndTime.Sub(stats.StartTime)
 
-	return stats, nil
+	return stats, failErr
+}
+
+// clockSkewTolerance returns the configured tolerance, defaulting to 2s
+func (s *DefaultSyncer) clockSkewTolerance() time.Duration {
+	if s.config.ClockSkewTolerance <= 0 {
+		return defaultClockSkewTolerance
+	}
+	return s.config.ClockSkewTolerance
+}
+
+// listRemoteApps fetches the app list used to detect renames and expand pattern entries.
+// Accounts that can read individual apps but not list them get a 403; the sync then
+// continues with the app map alone, so an empty list is returned without an error.
+func (s *DefaultSyncer) listRemoteApps() ([]api.AppInfo, error) {
+	remoteAppList, err := s.client.GetAppList()
+	if err == nil {
+		return remoteAppList, nil
+	}
+	if !api.IsForbidden(err) {
+		return nil, fmt.Errorf("failed to get app list from API: %w", err)
+	}
+
+	fmt.Fprintf(s.config.logOutput(), "Warning: No permission to list apps, syncing mapped apps one by one; rename detection and pattern entries are disabled: %v\n", err)
+	return nil, nil
+}
+
+// recordRemoteTimestamp notes whether a remote timestamp lies in the local clock's future
+func (s *DefaultSyncer) recordRemoteTimestamp(remote time.Time) {
+	s.timestampsCompared++
+	if remote.Sub(s.now()) > s.clockSkewTolerance() {
+		s.timestampsAhead++
+	}
+}
+
+// warnClockSkew warns when most remote timestamps were ahead of the local clock,
+// which suggests the clocks of this machine and Dify disagree
+func (s *DefaultSyncer) warnClockSkew() {
+	if s.timestampsAhead == 0 || s.timestampsAhead*2 < s.timestampsCompared {
+		return
+	}
+	fmt.Fprintf(s.config.logOutput(), "Warning: %d of %d remote timestamps are ahead of the local clock by more than %v; check the clocks of this machine and Dify\n",
+		s.timestampsAhead, s.timestampsCompared, s.clockSkewTolerance())
+}
+
+// Estimates used to predict how long a sync takes
+const (
+	// requestsPerApp is the number of API requests needed to sync an app with a download
+	requestsPerApp = 3
+	// estimatedRequestTime is the assumed duration of a single API request
+	estimatedRequestTime = 200 * time.Millisecond
+)
+
+// estimateSyncDuration predicts how long syncing appCount apps takes, respecting the rate limit
+func (s *DefaultSyncer) estimateSyncDuration(appCount int) time.Duration {
+	perRequest := estimatedRequestTime
+	if s.config.RateLimit > 0 {
+		if limited := time.Duration(float64(time.Second) / s.config.RateLimit); limited > perRequest {
+			perRequest = limited
+		}
+	}
+	return time.Duration(appCount*requestsPerApp) * perRequest
+}
+
+// warnTokenExpiry warns when the access token expires before a sync of appCount apps is
+// estimated to finish. It does nothing when the token lifetime is unknown.
+func (s *DefaultSyncer) warnTokenExpiry(appCount int) {
+	expiry := s.client.TokenExpiry()
+	if expiry.IsZero() {
+		return
+	}
+
+	remaining := expiry.Sub(s.now())
+	estimated := s.estimateSyncDuration(appCount)
+	if remaining >= estimated {
+		return
+	}
+	fmt.Fprintf(s.config.logOutput(), "Warning: The access token expires in %v, before this sync of %d apps is estimated to finish (%v)\n",
+		remaining.Round(time.Second), appCount, estimated.Round(time.Second))
+}
+
+// archiveDeletedFile moves the file of a deleted app into the archive directory,
+// adding a timestamp suffix if a file with the same name was archived before
+func (s *DefaultSyncer) archiveDeletedFile(localPath string) (string, error) {
+	if err := s.fileStore().MkdirAll(s.config.ArchiveDeletedDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	name := filepath.Base(localPath)
+	archivedPath := filepath.Join(s.config.ArchiveDeletedDir, name)
+	if s.fileExists(archivedPath) {
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		archivedPath = filepath.Join(s.config.ArchiveDeletedDir,
+			fmt.Sprintf("%s_%s%s", base, s.now().Format("20060102T150405.000000000"), ext))
+	}
+
+	if err := s.fileStore().Rename(localPath, archivedPath); err != nil {
+		return "", err
+	}
+
+	return archivedPath, nil
+}
+
+// reportProgress passes the progress of SyncAll to the Progress callback, if any
+func (s *DefaultSyncer) reportProgress(done, total int, filename string) {
+	if s.config.Progress != nil {
+		s.config.Progress(done, total, filename)
+	}
+}
+
+// printAppResult prints the line of an app synced by SyncAll. Apps that changed or failed
+// are listed by default and apps without changes only with Verbose; Quiet and SummaryOnly
+// suppress all of them.
+func (s *DefaultSyncer) printAppResult(result SyncResult, note string) {
+	if s.config.Quiet || s.config.SummaryOnly {
+		return
+	}
+	unchanged := result.Success && (result.Action == ActionNone || result.Action == ActionSkip)
+	if unchanged && !s.config.Verbose {
+		return
+	}
+
+	target := ""
+	if result.Action == ActionRename {
+		target = " -> " + result.NewFilename
+	}
+	fmt.Printf("Synced %s (app_id: %s%s): %s%s\n", result.Filename, result.AppID, note, s.colorAction(result.Action), target)
+	if result.Error != nil {
+		fmt.Printf("  Error: %v\n", result.Error)
+	}
+}
+
+// colorAction colors an action for terminal output: yellow for downloads, red for errors, green otherwise
+func (s *DefaultSyncer) colorAction(action SyncAction) string {
+	c := color.New(s.config.Color)
+	switch action {
+	case ActionDownload:
+		return c.Yellow(string(action))
+	case ActionError:
+		return c.Red(string(action))
+	default:
+		return c.Green(string(action))
+	}
+}
+
+// audit records a sync result in the audit log if one is configured
+func (s *DefaultSyncer) audit(result SyncResult) {
+	if s.auditLogger == nil {
+		return
+	}
+	if err := s.auditLogger.Log(result); err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: %v\n", err)
+	}
+}
+
+// Close closes the audit log of the syncer, if one is configured
+func (s *DefaultSyncer) Close() error {
+	if s.auditLogger == nil {
+		return nil
+	}
+	return s.auditLogger.Close()
 }
 
 // SyncApp synchronizes a single app
 func (s *DefaultSyncer) SyncApp(app AppMapping) SyncResult {
+	return s.applyPlan(app, s.planApp(app))
+}
+
+// applyPlan carries out the action planned for an app: planned downloads are downloaded,
+// and any other plan is returned as it is
+func (s *DefaultSyncer) applyPlan(app AppMapping, planned SyncResult) SyncResult {
+	if planned.Action != ActionDownload {
+		s.touchInSync(app, planned)
+		return planned
+	}
+
+	localPath := filepath.Join(s.config.DSLDirectory, app.Filename)
+	result := s.downloadFromRemote(app, localPath)
+	result.RemoteUpdatedAt = planned.RemoteUpdatedAt
+	s.touchInSync(app, result)
+	return result
+}
+
+// touchInSync sets the modification time of an in-sync file to the remote update time
+// if TouchOnSync is set and the remote time is not older than the file. Read-only apps
+// are never touched, as their remote changes are not downloaded.
+func (s *DefaultSyncer) touchInSync(app AppMapping, result SyncResult) {
+	if !s.config.TouchOnSync || s.config.DryRun || app.ReadOnly {
+		return
+	}
+	if result.Action != ActionNone || !result.Success || result.RemoteUpdatedAt.IsZero() {
+		return
+	}
+
+	localPath := filepath.Join(s.config.DSLDirectory, app.Filename)
+	info, err := s.fileStore().Stat(localPath)
+	if err != nil || info.ModTime().After(result.RemoteUpdatedAt) || info.ModTime().Equal(result.RemoteUpdatedAt) {
+		return
+	}
+
+	if err := s.fileStore().Chtimes(localPath, result.RemoteUpdatedAt, result.RemoteUpdatedAt); err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: Failed to set modification time of %s: %v\n", localPath, err)
+	} else if s.config.Verbose {
+		fmt.Printf("Set modification time of %s to %s\n", localPath, result.RemoteUpdatedAt.Format(time.RFC3339))
+	}
+}
+
+// planApp decides which action SyncApp should take for a single app without writing anything.
+// A planned download is returned with Success set once the decision could be made.
+func (s *DefaultSyncer) planApp(app AppMapping) SyncResult {
 	result := SyncResult{
 		Filename:  app.Filename,
 		AppID:     app.AppID,
 		Action:    ActionNone,
-		Timestamp: time.Now(),
+		Timestamp: s.now(),
 	}
 
 	// Get local file modification time
 	localPath := filepath.Join(s.config.DSLDirectory, app.Filename)
-	localInfo, err := os.Stat(localPath)
-	if err != nil {
+	localInfo, err := s.fileStore().Stat(localPath)
+	missing := err != nil && os.IsNotExist(err) && s.config.RedownloadMissing
+	if err != nil && !missing {
 		result.Action = ActionError
 		result.Error = fmt.Errorf("failed to stat local file: %w", err)
 		if s.config.Verbose {
@@ -450,10 +1445,9 @@ func (s *DefaultSyncer) SyncApp(app AppMapping) SyncResult {
 		}
 		return result
 	}
-	localModTime := localInfo.ModTime()
 
-	// Check if app still exists remotely
-	exists, err := s.client.DoesDSLExist(app.AppID)
+	// Check if app still exists remotely; the same request returns its info
+	appInfo, err := s.findApp(app.AppID)
 	if err != nil {
 		result.Action = ActionError
 		result.Error = fmt.Errorf("failed to check if app exists: %w", err)
@@ -463,7 +1457,7 @@ func (s *DefaultSyncer) SyncApp(app AppMapping) SyncResult {
 		return result
 	}
 
-	if !exists {
+	if appInfo == nil {
 		// App has been deleted remotely
 		if s.config.Verbose {
 			fmt.Printf("App %s (ID: %s) no longer exists remotely\n", app.Filename, app.AppID)
@@ -475,212 +1469,224 @@ func (s *DefaultSyncer) SyncApp(app AppMapping) SyncResult {
 		return result
 	}
 
-	// Get remote app info
-	appInfo, err := s.client.GetAppInfo(app.AppID)
+	remoteLatest, ok, err := s.remoteUpdatedAt(app, appInfo)
 	if err != nil {
 		result.Action = ActionError
-		result.Error = fmt.Errorf("failed to get app info: %w", err)
+		result.Error = err
 		if s.config.Verbose {
 			fmt.Printf("Error accessing app %s (%s): %v\n", app.AppID, app.Filename, err)
 		}
 		return result
 	}
 
-	fmt.Printf("Debug - App Info for %s: %+v\n", app.AppID, appInfo)
-
-	// 获取发布信息
-	appPublish, err := s.client.GetAppPublish(app.AppID)
-	if err != nil {
-		appPublish = nil
-	} else {
-		fmt.Printf("Debug - App Publish for %s: %+v\n", app.AppID, appPublish)
-	}
-
-	// Convert interface{} updated_at to time.Time
-	var remoteModTime time.Time
-	var remotePublishTime time.Time
-	var useLocalTime bool = false
-
-	if appInfo.UpdatedAt == nil {
-		// If UpdatedAt is nil, use a time in the past to ensure the local file is considered newer
-		fmt.Printf("Debug - UpdatedAt is nil, using past timestamp to prioritize local file\n")
-		// Use Unix epoch start as the remote time (1970-01-01) to ensure local is newer
-		remoteModTime = time.Unix(0, 0)
-		useLocalTime = true
-	} else {
-		switch v := appInfo.UpdatedAt.(type) {
-		case string:
-			// For string type: parse the timestamp string
-			if v != "" {
-				// Try RFC3339 format (2023-01-02T15:04:05Z)
-				parsedTime, err := time.Parse(time.RFC3339, v)
-				if err == nil {
-					remoteModTime = parsedTime
-				} else {
-					// Try other formats
-					layouts := []string{
-						"2006-01-02 15:04:05",
-						"2006-01-02T15:04:05",
-						"2006/01/02 15:04:05",
-						time.RFC1123,
-						time.RFC1123Z,
-					}
-
-					for _, layout := range layouts {
-						parsedTime, err := time.Parse(layout, v)
-						if err == nil {
-							remoteModTime = parsedTime
-							break
-						}
-					}
-				}
-			} else {
-				// Empty string, treat as nil case
-				fmt.Printf("Debug - UpdatedAt is empty string, using past timestamp to prioritize local file\n")
-				remoteModTime = time.Unix(0, 0)
-				useLocalTime = true
-			}
-		case float64:
-			// For numeric type: interpret as UNIX timestamp (seconds)
-			remoteModTime = time.Unix(int64(v), 0)
-			fmt.Printf("Debug - Converted float64 timestamp %v to time: %v\n", v, remoteModTime)
-		case int:
-			// For integer type: interpret as UNIX timestamp (seconds)
-			remoteModTime = time.Unix(int64(v), 0)
-			fmt.Printf("Debug - Converted int timestamp %v to time: %v\n", v, remoteModTime)
-		case int64:
-			// For 64-bit integer: interpret as UNIX timestamp
-			remoteModTime = time.Unix(v, 0)
-			fmt.Printf("Debug - Converted int64 timestamp %v to time: %v\n", v, remoteModTime)
-		case json.Number:
-			// For json.Number type
-			if i, err := v.Int64(); err == nil {
-				remoteModTime = time.Unix(i, 0)
-				fmt.Printf("Debug - Converted json.Number timestamp %v to time: %v\n", v, remoteModTime)
-			} else {
-				// If conversion fails, treat as nil case
-				fmt.Printf("Debug - Could not convert json.Number %v to timestamp, using past timestamp\n", v)
-				remoteModTime = time.Unix(0, 0)
-				useLocalTime = true
-			}
-		default:
-			fmt.Printf("Debug - Unknown type for UpdatedAt: %T value: %v, using past timestamp\n", appInfo.UpdatedAt, appInfo.UpdatedAt)
-			remoteModTime = time.Unix(0, 0)
-			useLocalTime = true
+	// A local file deleted by hand is downloaded again, except for read-only apps
+	if missing {
+		if s.config.Verbose {
+			fmt.Printf("Local file %s (ID: %s) is missing\n", app.Filename, app.AppID)
+		}
+		if !app.ReadOnly {
+			result.Action = ActionDownload
 		}
+		result.Success = true
+		result.RemoteUpdatedAt = remoteLatest
+		return result
 	}
+	localModTime := localInfo.ModTime()
 
-	fmt.Printf("Debug - Local mod time: %v, Remote mod time: %v\n", localModTime, remoteModTime)
-
-	// If UpdatedAt was nil or couldn't be parsed, don't sync
-	if useLocalTime {
-		fmt.Printf("Debug - No valid remote timestamp found, skipping sync\n")
+	// If the timestamp was missing or couldn't be parsed, don't sync
+	if !ok {
 		result.Action = ActionNone
 		result.Success = true
 		return result
 	}
 
-	if appPublish != nil && appPublish.UpdatedAt != nil {
-		switch v := appPublish.UpdatedAt.(type) {
-		case string:
-			// For string type: parse the timestamp string
-			if v != "" {
-				// Try RFC3339 format (2023-01-02T15:04:05Z)
-				parsedTime, err := time.Parse(time.RFC3339, v)
-				if err == nil {
-					remotePublishTime = parsedTime
-				} else {
-					// Try other formats
-					layouts := []string{
-						"2006-01-02 15:04:05",
-						"2006-01-02T15:04:05",
-						"2006/01/02 15:04:05",
-						time.RFC1123,
-						time.RFC1123Z,
-					}
+	s.recordRemoteTimestamp(remoteLatest)
 
-					for _, layout := range layouts {
-						parsedTime, err := time.Parse(layout, v)
-						if err == nil {
-							remotePublishTime = parsedTime
-							break
-						}
-					}
-				}
-			} else {
-				// Empty string, treat as nil case
-				fmt.Printf("Debug - Deploy UpdatedAt is empty string, using past timestamp to prioritize local file\n")
-				remotePublishTime = time.Unix(0, 0)
+	// Only download if remote is newer, allowing for clock skew between this machine and Dify
+	if remoteLatest.Sub(localModTime) > s.clockSkewTolerance() {
+		// Read-only apps only report the difference
+		if app.ReadOnly {
+			if !s.config.Quiet {
+				fmt.Printf("Read-only app %s (ID: %s) has remote changes, not downloading\n", app.Filename, app.AppID)
 			}
-		case float64:
-			// For numeric type: interpret as UNIX timestamp (seconds)
-			remotePublishTime = time.Unix(int64(v), 0)
-			fmt.Printf("Debug - Deploy Converted float64 timestamp %v to time: %v\n", v, remotePublishTime)
-		case int:
-			// For integer type: interpret as UNIX timestamp (seconds)
-			remotePublishTime = time.Unix(int64(v), 0)
-			fmt.Printf("Debug - Deploy Converted int timestamp %v to time: %v\n", v, remotePublishTime)
-		case int64:
-			// For 64-bit integer: interpret as UNIX timestamp
-			remotePublishTime = time.Unix(v, 0)
-			fmt.Printf("Debug - Deploy Converted int64 timestamp %v to time: %v\n", v, remotePublishTime)
-		case json.Number:
-			// For json.Number type
-			if i, err := v.Int64(); err == nil {
-				remotePublishTime = time.Unix(i, 0)
-				fmt.Printf("Debug - Deploy Converted json.Number timestamp %v to time: %v\n", v, remotePublishTime)
-			} else {
-				// If conversion fails, treat as nil case
-				fmt.Printf("Debug - Deploy Could not convert json.Number %v to timestamp, using past timestamp\n", v)
-				remotePublishTime = time.Unix(0, 0)
-			}
-		default:
-			fmt.Printf("Debug - Unknown type for UpdatedAt: %T value: %v, using past timestamp\n", appInfo.UpdatedAt, appInfo.UpdatedAt)
-			remotePublishTime = time.Unix(0, 0)
+			result.Action = ActionNone
+			result.Success = true
+			result.RemoteUpdatedAt = remoteLatest
+			return result
 		}
-	} else {
-		remotePublishTime = time.Unix(0, 0)
-	}
 
-	// Only download if remote is newer
-	if remoteModTime.After(localModTime) || remotePublishTime.After(localModTime) {
-		return s.downloadFromRemote(app, localPath)
+		result.Action = ActionDownload
+		result.Success = true
+		result.RemoteUpdatedAt = remoteLatest
+		return result
 	}
 
 	// Files are in sync
 	result.Action = ActionNone
 	result.Success = true
+	result.RemoteUpdatedAt = remoteLatest
 	return result
 }
 
+// prefetchApp keeps app info fetched by SyncAll so that planApp does not request it again
+func (s *DefaultSyncer) prefetchApp(appID string, appInfo *api.AppInfo) {
+	if appInfo == nil {
+		return
+	}
+	if s.prefetchedApps == nil {
+		s.prefetchedApps = make(map[string]*api.AppInfo)
+	}
+	s.prefetchedApps[appID] = appInfo
+}
+
+// findApp returns the app info prefetched for an app, which is used only once,
+// or fetches it. It returns nil if the app does not exist.
+func (s *DefaultSyncer) findApp(appID string) (*api.AppInfo, error) {
+	if appInfo, ok := s.prefetchedApps[appID]; ok {
+		delete(s.prefetchedApps, appID)
+		return appInfo, nil
+	}
+	return s.client.FindApp(appID)
+}
+
 // downloadFromRemote downloads the DSL from Dify to the local file
 func (s *DefaultSyncer) downloadFromRemote(app AppMapping, localPath string) SyncResult {
 	result := SyncResult{
 		Filename:  app.Filename,
 		AppID:     app.AppID,
 		Action:    ActionDownload,
-		Timestamp: time.Now(),
+		Timestamp: s.now(),
 	}
 
 	// Get DSL from Dify
-	dsl, err := s.client.GetDSL(app.AppID)
+	dsl, err := s.getDSL(app.AppID)
+	if api.IsForbidden(err) {
+		// The app is visible but the user's role may not export it
+		fmt.Fprintf(s.config.logOutput(), "Warning: No export permission for app %s (app_id: %s)\n", app.Filename, app.AppID)
+		if s.config.SkipForbidden {
+			result.Action = ActionSkip
+			result.Success = true
+			return result
+		}
+		result.Error = fmt.Errorf("%w for app %s: %w", ErrNoExportPermission, app.AppID, err)
+		return result
+	}
 	if err != nil {
 		result.Error = fmt.Errorf("failed to get DSL from Dify: %w", err)
 		return result
 	}
 
+	dsl = normalizeLineEndings(dsl, s.config.LineEnding)
+
+	// A newer remote timestamp does not always mean the DSL changed
+	if s.config.SkipUnchanged {
+		if local, err := s.fileStore().ReadFile(localPath); err == nil && s.sameContent(local, dsl) {
+			if s.config.Verbose {
+				fmt.Printf("Remote DSL of %s is identical to the local file, not writing it\n", app.Filename)
+			}
+			result.Action = ActionNone
+			result.Success = true
+			return result
+		}
+	}
+
 	// If dry run, just return success
 	if s.config.DryRun {
 		result.Success = true
 		return result
 	}
 
+	// Filenames with subdirectories need their directory to exist
+	if err := s.fileStore().MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		result.Error = fmt.Errorf("failed to create directory for local file: %w", err)
+		return result
+	}
+
 	// Write DSL to local file
-	if err := os.WriteFile(localPath, dsl, 0644); err != nil {
+	if err := s.writeDSLFile(localPath, dsl); err != nil {
 		result.Error = fmt.Errorf("failed to write DSL to local file: %w", err)
 		return result
 	}
 
+	// Check that the file on disk matches what was downloaded
+	if s.config.VerifyWrites {
+		if err := s.verifyWrittenFile(localPath, dsl); err != nil {
+			corruptPath := localPath + ".corrupt"
+			if renameErr := s.fileStore().Rename(localPath, corruptPath); renameErr != nil {
+				fmt.Fprintf(s.config.logOutput(), "Warning: Failed to move corrupt file %s: %v\n", localPath, renameErr)
+			}
+			result.Action = ActionError
+			result.Error = fmt.Errorf("failed to verify written DSL (moved to %s): %w", corruptPath, err)
+			return result
+		}
+	}
+
 	result.Success = true
+	result.BytesWritten = int64(len(dsl))
 	return result
 }
+
+// initDownload is a DSL downloaded by init for the app map entry at index
+type initDownload struct {
+	index  int
+	app    api.AppInfo
+	path   string
+	exists bool
+}
+
+// downloadInitialDSL downloads and writes the DSL of an app found by init.
+// It returns the time of the download if the file was written.
+func (s *DefaultSyncer) downloadInitialDSL(download initDownload) (time.Time, bool) {
+	if s.config.Verbose {
+		if download.exists {
+			fmt.Printf("Downloading DSL for %s to replace %s\n", download.app.Name, download.path)
+		} else {
+			fmt.Printf("Downloading initial DSL for %s to %s\n", download.app.Name, download.path)
+		}
+	}
+
+	dsl, err := s.getDSL(download.app.ID)
+	if err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: Failed to download DSL for %s: %v\n", download.app.Name, err)
+		return time.Time{}, false
+	}
+
+	if s.config.DryRun {
+		return time.Time{}, false
+	}
+	if err := s.writeDSLFile(download.path, normalizeLineEndings(dsl, s.config.LineEnding)); err != nil {
+		fmt.Fprintf(s.config.logOutput(), "Warning: Failed to write DSL file for %s: %v\n", download.app.Name, err)
+		return time.Time{}, false
+	}
+	return s.now(), true
+}
+
+// getDSL downloads the configured variant of an app's DSL and counts its size in bytesDownloaded
+func (s *DefaultSyncer) getDSL(appID string) ([]byte, error) {
+	dsl, err := s.exportDSL(appID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.bytesDownloaded += int64(len(dsl))
+	s.mu.Unlock()
+	return dsl, nil
+}
+
+// verifyWrittenFile re-reads a file and compares its length and SHA-256 with the expected data
+func (s *DefaultSyncer) verifyWrittenFile(path string, expected []byte) error {
+	written, err := s.fileStore().ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read back file: %w", err)
+	}
+
+	if len(written) != len(expected) {
+		return fmt.Errorf("size mismatch: wrote %d bytes, expected %d", len(written), len(expected))
+	}
+
+	if sha256.Sum256(written) != sha256.Sum256(expected) {
+		return fmt.Errorf("checksum mismatch")
+	}
+
+	return nil
+}
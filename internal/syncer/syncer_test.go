@@ -1,15 +1,22 @@
 package syncer
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
+
+	"github.com/pepabo/difync/internal/api"
 )
 
 func TestLoadAppMap(t *testing.T) {
@@ -240,6 +247,240 @@ func TestSyncAll(t *testing.T) {
 	}
 }
 
+func TestSyncAllCountsBytesDownloaded(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Use the remote name so the app is downloaded rather than renamed
+	renamedPath := filepath.Join(dslDir, "Test_App.yaml")
+	if err := os.Rename(dslPath, renamedPath); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(renamedPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+
+	if stats.Downloads != 1 {
+		t.Fatalf("Expected 1 download, got %d", stats.Downloads)
+	}
+	expected := int64(len("name: Test App\nversion: 1.0.0"))
+	if stats.BytesDownloaded != expected {
+		t.Errorf("Expected %d bytes downloaded, got %d", expected, stats.BytesDownloaded)
+	}
+
+	// A sync without downloads counts no bytes
+	stats, err = syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.BytesDownloaded != 0 {
+		t.Errorf("Expected 0 bytes downloaded on the second sync, got %d", stats.BytesDownloaded)
+	}
+}
+
+func TestEstimateSyncDuration(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		rateLimit float64
+		apps      int
+		expected  time.Duration
+	}{
+		{"no rate limit", 0, 10, 10 * requestsPerApp * estimatedRequestTime},
+		{"rate limit slower than requests", 1, 10, 10 * requestsPerApp * time.Second},
+		{"rate limit faster than requests", 100, 10, 10 * requestsPerApp * estimatedRequestTime},
+		{"no apps", 0, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &DefaultSyncer{config: Config{RateLimit: tc.rateLimit}}
+			if got := s.estimateSyncDuration(tc.apps); got != tc.expected {
+				t.Errorf("estimateSyncDuration(%d) = %v, expected %v", tc.apps, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSyncStatsErr(t *testing.T) {
+	exportErr := errors.New("export failed")
+	stats := &SyncStats{
+		Results: []SyncResult{
+			{Filename: "alpha.yaml", AppID: "app-1", Action: ActionDownload, Success: true},
+			{Filename: "beta.yaml", AppID: "app-2", Action: ActionDownload, Error: exportErr},
+			{Filename: "gamma.yaml", AppID: "app-3", Action: ActionError, Error: errors.New("failed to stat local file")},
+		},
+	}
+
+	failed := stats.Failed()
+	if len(failed) != 2 || failed[0].AppID != "app-2" || failed[1].AppID != "app-3" {
+		t.Fatalf("Expected app-2 and app-3 to have failed, got %+v", failed)
+	}
+
+	err := stats.Err()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{"beta.yaml (app_id: app-2): export failed", "gamma.yaml (app_id: app-3): failed to stat local file"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %q", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "alpha.yaml") {
+		t.Errorf("Expected error not to mention the synced app, got %q", err)
+	}
+	if !errors.Is(err, exportErr) {
+		t.Error("Expected the joined error to wrap each app's error")
+	}
+
+	// Without failures there is no error
+	stats.Results = stats.Results[:1]
+	if err := stats.Err(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestSyncActionJSON(t *testing.T) {
+	for _, action := range []SyncAction{ActionNone, ActionDownload, ActionUpload, ActionError, ActionRename, ActionDelete, ActionSkip} {
+		data, err := json.Marshal(action)
+		if err != nil {
+			t.Fatalf("Failed to marshal %s: %v", action, err)
+		}
+		if string(data) != `"`+action.String()+`"` {
+			t.Errorf("Expected %s to marshal as a JSON string, got %s", action, data)
+		}
+
+		var decoded SyncAction
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", data, err)
+		}
+		if decoded != action {
+			t.Errorf("Expected %s after a round trip, got %s", action, decoded)
+		}
+
+		if parsed, err := ParseSyncAction(action.String()); err != nil || parsed != action {
+			t.Errorf("Expected ParseSyncAction(%q) to return %s, got %s, %v", action, action, parsed, err)
+		}
+	}
+
+	// Unknown actions are rejected in every direction
+	if _, err := ParseSyncAction("downlaod"); err == nil {
+		t.Error("Expected ParseSyncAction to reject an unknown action")
+	}
+	if _, err := json.Marshal(SyncAction("downlaod")); err == nil {
+		t.Error("Expected marshaling an unknown action to fail")
+	}
+	var decoded SyncAction
+	if err := json.Unmarshal([]byte(`"downlaod"`), &decoded); err == nil {
+		t.Error("Expected unmarshaling an unknown action to fail")
+	}
+	if err := json.Unmarshal([]byte(`1`), &decoded); err == nil {
+		t.Error("Expected unmarshaling a non-string action to fail")
+	}
+
+	// Actions inside other values are encoded the same way
+	data, err := json.Marshal(struct {
+		Action SyncAction `json:"action"`
+	}{ActionRename})
+	if err != nil || string(data) != `{"action":"rename"}` {
+		t.Errorf("Expected {\"action\":\"rename\"}, got %s, %v", data, err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+		{2 * 1024 * 1024 * 1024 * 1024, "2.0 TB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.bytes); got != tt.expected {
+			t.Errorf("FormatBytes(%d): expected %q, got %q", tt.bytes, tt.expected, got)
+		}
+	}
+}
+
+func TestSyncAllRecordsSyncTimestamps(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Use the filename matching the remote app name so the app is not renamed
+	newPath := filepath.Join(dslDir, "Test_App.yaml")
+	if err := os.Rename(dslPath, newPath); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	appMapData := `{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	// Make the local file older than the remote app so it gets downloaded
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(newPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+
+	// Old app maps without timestamps must still load
+	appMap, err := syncer.LoadAppMap()
+	if err != nil {
+		t.Fatalf("Failed to load app map: %v", err)
+	}
+	if appMap.Apps[0].LastSyncedAt != nil || appMap.Apps[0].LastRemoteUpdatedAt != nil {
+		t.Error("Expected timestamps to be nil for an app map without them")
+	}
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Downloads != 1 {
+		t.Fatalf("Expected 1 download, got %d", stats.Downloads)
+	}
+	if len(stats.Results) != 1 || stats.Results[0].Filename != "Test_App.yaml" || stats.Results[0].Action != ActionDownload {
+		t.Errorf("Expected a single download result for Test_App.yaml, got %+v", stats.Results)
+	}
+
+	appMap, err = syncer.LoadAppMap()
+	if err != nil {
+		t.Fatalf("Failed to load updated app map: %v", err)
+	}
+
+	app := appMap.Apps[0]
+	if app.LastSyncedAt == nil || app.LastSyncedAt.IsZero() {
+		t.Error("Expected LastSyncedAt to be recorded after download")
+	}
+
+	expectedRemote := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	if app.LastRemoteUpdatedAt == nil || !app.LastRemoteUpdatedAt.Equal(expectedRemote) {
+		t.Errorf("Expected LastRemoteUpdatedAt to be %v, got %v", expectedRemote, app.LastRemoteUpdatedAt)
+	}
+
+	// Timestamps are omitted from the JSON when not set
+	data, err := json.Marshal(AppMapping{Filename: "test.yaml", AppID: "test-app-id"})
+	if err != nil {
+		t.Fatalf("Failed to marshal app mapping: %v", err)
+	}
+	if strings.Contains(string(data), "last_synced_at") || strings.Contains(string(data), "last_remote_updated_at") {
+		t.Errorf("Expected timestamps to be omitted, got %s", data)
+	}
+}
+
 func TestSyncApp(t *testing.T) {
 	syncer, _, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
 	defer cleanup()
@@ -267,6 +508,204 @@ func TestSyncApp(t *testing.T) {
 	}
 }
 
+func TestSyncAppBytesWritten(t *testing.T) {
+	syncer, _, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+	defaultSyncer := syncer.(*DefaultSyncer)
+	app := AppMapping{Filename: filepath.Base(dslPath), AppID: "test-app-id"}
+	payload := "name: Test App\nversion: 1.0.0"
+
+	// Dry runs download but write nothing
+	defaultSyncer.config.DryRun = true
+	if result := defaultSyncer.downloadFromRemote(app, dslPath); !result.Success || result.BytesWritten != 0 {
+		t.Errorf("Expected nothing written in a dry run, got %+v", result)
+	}
+	defaultSyncer.config.DryRun = false
+
+	// The outdated local file is replaced by the payload
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(dslPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+	result := defaultSyncer.SyncApp(app)
+	if result.Action != ActionDownload || result.BytesWritten != int64(len(payload)) {
+		t.Errorf("Expected %d bytes written by the download, got %+v", len(payload), result)
+	}
+
+	// Files in sync are not written
+	result = defaultSyncer.SyncApp(app)
+	if result.Action != ActionNone || result.BytesWritten != 0 {
+		t.Errorf("Expected no bytes written for an app in sync, got %+v", result)
+	}
+}
+
+func TestSyncAppMissingLocalFile(t *testing.T) {
+	syncer, _, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+	defaultSyncer := syncer.(*DefaultSyncer)
+	app := AppMapping{Filename: filepath.Base(dslPath), AppID: "test-app-id"}
+
+	if err := os.Remove(dslPath); err != nil {
+		t.Fatalf("Failed to delete DSL file: %v", err)
+	}
+
+	// Without RedownloadMissing the missing file is an error
+	result := defaultSyncer.SyncApp(app)
+	if result.Action != ActionError || result.Error == nil {
+		t.Errorf("Expected an error for the missing file, got %+v", result)
+	}
+
+	// With RedownloadMissing the file is downloaded again
+	defaultSyncer.config.RedownloadMissing = true
+	result = defaultSyncer.SyncApp(app)
+	if result.Action != ActionDownload || !result.Success {
+		t.Fatalf("Expected the missing file to be downloaded, got %+v", result)
+	}
+	content, err := os.ReadFile(dslPath)
+	if err != nil {
+		t.Fatalf("Expected the DSL file to be restored: %v", err)
+	}
+	if string(content) != "name: Test App\nversion: 1.0.0" {
+		t.Errorf("Unexpected DSL content: %q", content)
+	}
+
+	// Read-only apps are not downloaded
+	if err := os.Remove(dslPath); err != nil {
+		t.Fatalf("Failed to delete DSL file: %v", err)
+	}
+	app.ReadOnly = true
+	result = defaultSyncer.SyncApp(app)
+	if result.Action != ActionNone || !result.Success {
+		t.Errorf("Expected no action for a read-only app, got %+v", result)
+	}
+	if _, err := os.Stat(dslPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no file for a read-only app, got %v", err)
+	}
+}
+
+func TestSyncAppFetchesAppOnce(t *testing.T) {
+	syncer, server, _, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Count requests for the app, which answer both whether it exists and when it changed
+	appRequests := 0
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/console/api/apps/test-app-id" {
+			appRequests++
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	result := syncer.SyncApp(AppMapping{Filename: "test.yaml", AppID: "test-app-id"})
+	if !result.Success {
+		t.Fatalf("Expected SyncApp to succeed, got %v", result.Error)
+	}
+	if appRequests != 1 {
+		t.Errorf("Expected 1 request for the app from SyncApp, got %d", appRequests)
+	}
+
+	// SyncAll reuses the app info of its existence check
+	appRequests = 0
+	if _, err := syncer.SyncAll(); err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if appRequests != 1 {
+		t.Errorf("Expected 1 request for the app from SyncAll, got %d", appRequests)
+	}
+}
+
+// captureStdout returns everything fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	originalStdout := os.Stdout
+	defer func() {
+		os.Stdout = originalStdout
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read captured stdout: %v", err)
+	}
+	return string(output)
+}
+
+func TestSyncAllProgress(t *testing.T) {
+	syncer, _, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	var calls []string
+	syncer.(*DefaultSyncer).config.Progress = func(done, total int, filename string) {
+		calls = append(calls, fmt.Sprintf("%d/%d %s", done, total, filename))
+	}
+
+	if _, err := syncer.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	want := []string{"0/1 " + filepath.Base(dslPath), "1/1 "}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected progress %q, got %q", want, calls)
+	}
+}
+
+func TestPrintAppResult(t *testing.T) {
+	results := []SyncResult{
+		{Filename: "in-sync.yaml", AppID: "app-1", Action: ActionNone, Success: true},
+		{Filename: "changed.yaml", AppID: "app-2", Action: ActionDownload, Success: true},
+		{Filename: "old.yaml", AppID: "app-3", Action: ActionRename, Success: true, NewFilename: "new.yaml"},
+		{Filename: "broken.yaml", AppID: "app-4", Action: ActionDownload, Error: errors.New("export failed")},
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{
+			name:   "default lists changed and failed apps only",
+			config: Config{},
+			expected: "Synced changed.yaml (app_id: app-2): download\n" +
+				"Synced old.yaml (app_id: app-3): rename -> new.yaml\n" +
+				"Synced broken.yaml (app_id: app-4): download\n  Error: export failed\n",
+		},
+		{
+			name:   "verbose lists apps in sync as well",
+			config: Config{Verbose: true},
+			expected: "Synced in-sync.yaml (app_id: app-1): none\n" +
+				"Synced changed.yaml (app_id: app-2): download\n" +
+				"Synced old.yaml (app_id: app-3): rename -> new.yaml\n" +
+				"Synced broken.yaml (app_id: app-4): download\n  Error: export failed\n",
+		},
+		{name: "summary only", config: Config{SummaryOnly: true, Verbose: true}, expected: ""},
+		{name: "quiet", config: Config{Quiet: true}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer := &DefaultSyncer{config: tt.config}
+			output := captureStdout(t, func() {
+				for _, result := range results {
+					syncer.printAppResult(result, "")
+				}
+			})
+			if output != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
 func TestDryRun(t *testing.T) {
 	syncer, _, _, _, _, cleanup := setupTestSyncerAndServer(t)
 	defer cleanup()
@@ -406,6 +845,92 @@ func TestNewSyncer(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	// Create a test server that rejects the credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "Invalid credentials"}`))
+	}))
+	defer server.Close()
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "wrongpassword",
+	})
+
+	err := syncer.Validate()
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+
+	// Test successful login
+	validSyncer, _, _, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	if err := validSyncer.Validate(); err != nil {
+		t.Errorf("Expected no error after successful login, got %v", err)
+	}
+}
+
+func TestNewSyncerLogOutput(t *testing.T) {
+	// Create a test server that rejects the credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "wrongpassword",
+		LogOutput:    &buf,
+	})
+
+	if !strings.Contains(buf.String(), "Failed to login to Dify API") {
+		t.Errorf("Expected login failure to be written to LogOutput, got %q", buf.String())
+	}
+}
+
+func TestNewSyncerWithClient(t *testing.T) {
+	loginCalled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/console/api/login" {
+			loginCalled = true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+	}))
+	defer server.Close()
+
+	// Authenticate the client before handing it over
+	client := api.NewClient(server.URL)
+	if err := client.Login("test@example.com", "testpassword"); err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+	loginCalled = false
+
+	syncer := NewSyncerWithClient(Config{DSLDirectory: "/path/to/dsl"}, client)
+
+	if loginCalled {
+		t.Error("Expected NewSyncerWithClient not to log in")
+	}
+
+	if err := syncer.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got %v", err)
+	}
+
+	defaultSyncer, ok := syncer.(*DefaultSyncer)
+	if !ok {
+		t.Fatalf("Expected syncer to be *DefaultSyncer")
+	}
+	if defaultSyncer.client != client {
+		t.Error("Expected syncer to use the given client")
+	}
+}
+
 func TestSyncAction(t *testing.T) {
 	// Test SyncAction string representation
 	actions := map[SyncAction]string{
@@ -543,23 +1068,77 @@ func TestDownloadFromRemoteWriteError(t *testing.T) {
 	}
 }
 
-func TestSyncAppError(t *testing.T) {
-	// Create a temporary directory for testing
-	tmpDir, err := os.MkdirTemp("", "difync-test-")
-	if err != nil {
-		t.Fatalf("Failed to create temp directory: %v", err)
+// truncatingFileStore simulates short writes by writing only half of the data
+type truncatingFileStore struct {
+	osFileStore
+}
+
+func (truncatingFileStore) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data[:len(data)/2], perm)
+}
+
+func TestDownloadFromRemoteVerifyWrites(t *testing.T) {
+	syncer, _, dslDir, _, _, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	defaultSyncer := syncer.(*DefaultSyncer)
+	defaultSyncer.config.VerifyWrites = true
+	app := AppMapping{Filename: "test.yaml", AppID: "test-app-id"}
+	localPath := filepath.Join(dslDir, "test.yaml")
+
+	// Test successful verification
+	result := defaultSyncer.downloadFromRemote(app, localPath)
+	if !result.Success || result.Action != ActionDownload {
+		t.Errorf("Expected verified download to succeed, got %+v", result)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Create a test server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-	}))
-	defer server.Close()
+	// Simulate a short write by truncating the data
+	defaultSyncer.config.FileStore = truncatingFileStore{osFileStore{}}
 
-	// Create syncer with test configuration
-	config := Config{
-		DifyBaseURL:  server.URL,
+	result = defaultSyncer.downloadFromRemote(app, localPath)
+	if result.Success {
+		t.Error("Expected Success to be false for a truncated write")
+	}
+	if result.Action != ActionError {
+		t.Errorf("Expected Action to be error, got %s", result.Action)
+	}
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "size mismatch") {
+		t.Errorf("Expected size mismatch error, got %v", result.Error)
+	}
+
+	// The truncated file is moved aside
+	if _, err := os.Stat(localPath + ".corrupt"); err != nil {
+		t.Errorf("Expected corrupt file to be kept: %v", err)
+	}
+	if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+		t.Error("Expected truncated file to be moved away from the DSL path")
+	}
+
+	// Without verification the truncated write goes unnoticed
+	defaultSyncer.config.VerifyWrites = false
+	result = defaultSyncer.downloadFromRemote(app, localPath)
+	if !result.Success {
+		t.Errorf("Expected unverified download to succeed, got %v", result.Error)
+	}
+}
+
+func TestSyncAppError(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// Create syncer with test configuration
+	config := Config{
+		DifyBaseURL:  server.URL,
 		DifyEmail:    "test@example.com",
 		DifyPassword: "testpassword",
 		DSLDirectory: tmpDir,
@@ -731,202 +1310,112 @@ func TestInitializeAppMap(t *testing.T) {
 	*/
 }
 
-func TestSanitizeFilename(t *testing.T) {
-	// Create a DefaultSyncer for testing
-	syncer := &DefaultSyncer{}
-
-	testCases := []struct {
-		input    string
-		expected string
-		desc     string
-	}{
-		{
-			input:    "Simple App Name",
-			expected: "Simple_App_Name",
-			desc:     "Convert spaces to underscores",
-		},
-		{
-			input:    "App/With:Invalid*Chars?",
-			expected: "AppWithInvalidChars",
-			desc:     "Remove invalid characters",
-		},
-		{
-			input:    "日本語のアプリ名",
-			expected: "日本語のアプリ名",
-			desc:     "Preserve Japanese characters",
-		},
-		{
-			input:    "アプリ名（テスト）",
-			expected: "アプリ名（テスト）",
-			desc:     "Preserve Japanese parentheses",
-		},
-		{
-			input:    "Testing <> | / \\ : * ? \" App",
-			expected: "Testing_________App",
-			desc:     "Remove special characters and convert spaces to underscores",
-		},
-		{
-			input:    "",
-			expected: "app",
-			desc:     "Use default name for empty string",
-		},
-		{
-			input:    "       ",
-			expected: "_______",
-			desc:     "Convert whitespace-only string to underscores",
-		},
-		{
-			input:    "Mixed 日本語 and English",
-			expected: "Mixed_日本語_and_English",
-			desc:     "Mix of Japanese and English",
-		},
-		{
-			input:    "App with 特殊文字 *><|",
-			expected: "App_with_特殊文字_",
-			desc:     "Mix of Japanese and English with special characters",
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.desc, func(t *testing.T) {
-			result := syncer.sanitizeFilename(tc.input)
-			if result != tc.expected {
-				t.Errorf("sanitizeFilename(%q) = %q, expected %q", tc.input, result, tc.expected)
-			}
-		})
-	}
-}
-
-func TestFilenameDeduplication(t *testing.T) {
+func TestInitializeAppMapWithDSLExtension(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir, err := os.MkdirTemp("", "difync-test-")
 	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+		t.Fatalf("Failed to create temp directory: %v", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Create a test DSL directory
 	dslDir := filepath.Join(tmpDir, "dsl")
-	if err := os.MkdirAll(dslDir, 0755); err != nil {
-		t.Fatalf("Failed to create DSL directory: %v", err)
-	}
-
-	// Create test files with duplicate base names
-	testFiles := []string{
-		"Duplicate_App.yaml",
-		"Another_App.yaml",
-	}
-
-	for _, filename := range testFiles {
-		filePath := filepath.Join(dslDir, filename)
-		if err := os.WriteFile(filePath, []byte("test content"), 0644); err != nil {
-			t.Fatalf("Failed to create test file %s: %v", filename, err)
-		}
-	}
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
 
-	// Create a mock server that returns a list of apps with duplicate names
+	// Create a mock server that returns a single app
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/console/api/login":
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"status": "success",
-				"data": {
-					"access_token": "test-token"
-				}
-			}`))
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
 		case "/console/api/apps":
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": [
-					{
-						"id": "app-id-1",
-						"name": "Duplicate App",
-						"updated_at": 1672531200
-					},
-					{
-						"id": "app-id-2",
-						"name": "Duplicate App",
-						"updated_at": 1672617600
-					},
-					{
-						"id": "app-id-3",
-						"name": "Another App",
-						"updated_at": 1672704000
-					}
-				]
-			}`))
-		case "/console/api/apps/app-id-1/export":
-		case "/console/api/apps/app-id-2/export":
-		case "/console/api/apps/app-id-3/export":
+			w.Write([]byte(`{"data": [{"id": "test-app-id", "name": "Test App", "updated_at": "2023-01-01T12:00:00Z"}]}`))
+		case "/console/api/apps/test-app-id/export":
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": "name: Test App\nversion: 1.0.0"
-			}`))
+			w.Write([]byte(`{"data": "name: Test App\nversion: 1.0.0"}`))
 		default:
 			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
 	defer server.Close()
 
-	// Create syncer with test configuration
+	// The leading dot is optional
 	config := Config{
 		DifyBaseURL:  server.URL,
 		DifyEmail:    "test@example.com",
 		DifyPassword: "testpassword",
 		DSLDirectory: dslDir,
-		AppMapFile:   filepath.Join(tmpDir, "app_map.json"),
+		AppMapFile:   appMapPath,
+		DSLExtension: "yml",
 	}
 	syncer := NewSyncer(config)
 
-	// Initialize the app map
 	appMap, err := syncer.(*DefaultSyncer).InitializeAppMap()
 	if err != nil {
 		t.Fatalf("Failed to initialize app map: %v", err)
 	}
 
-	if len(appMap.Apps) != 3 {
-		t.Errorf("Expected 3 apps in app map, got %d", len(appMap.Apps))
+	if len(appMap.Apps) != 1 || appMap.Apps[0].Filename != "Test_App.yml" {
+		t.Fatalf("Expected a single app with filename Test_App.yml, got %+v", appMap.Apps)
 	}
 
-	// Count how many files with each base name appear
-	duplicateAppCount := 0
-	anotherAppCount := 0
+	if _, err := os.Stat(filepath.Join(dslDir, "Test_App.yml")); err != nil {
+		t.Errorf("Expected DSL file to be written with .yml extension: %v", err)
+	}
 
-	for _, app := range appMap.Apps {
-		if strings.HasPrefix(app.Filename, "Duplicate_App") {
-			duplicateAppCount++
-		} else if strings.HasPrefix(app.Filename, "Another_App") {
-			anotherAppCount++
-		} else {
-			t.Errorf("Unexpected filename: %s", app.Filename)
-		}
+	// Default extension is .yaml
+	if ext := (&DefaultSyncer{}).dslExtension(); ext != ".yaml" {
+		t.Errorf("Expected default extension to be .yaml, got %s", ext)
 	}
+}
 
-	// Check the total number matches
-	if len(appMap.Apps) != 3 {
-		t.Errorf("Number of unique filenames (%d) doesn't match number of apps (3)", len(appMap.Apps))
+func TestSyncAllRenameKeepsExtension(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+	syncer.(*DefaultSyncer).config.DSLExtension = ".yml"
+
+	// A renamed app keeps the extension of its file, not the configured one
+	results, err := syncer.Plan()
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(results) != 1 || results[0].Action != ActionRename || results[0].NewFilename != "Test_App.yaml" {
+		t.Fatalf("Expected test.yaml to be renamed to Test_App.yaml, got %+v", results)
 	}
 
-	// Check for duplicate filenames
-	foundFilenames := make(map[string]bool)
-	for _, app := range appMap.Apps {
-		if foundFilenames[app.Filename] {
-			t.Errorf("Found duplicate filename: %s", app.Filename)
-		}
-		foundFilenames[app.Filename] = true
+	// A .yaml file matching the remote name is not renamed to .yml
+	if err := os.Rename(dslPath, filepath.Join(dslDir, "Test_App.yaml")); err != nil {
+		t.Fatalf("Failed to rename DSL file: %v", err)
+	}
+	content := `{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`
+	if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
 	}
 
-	// Check the total number matches
-	if len(foundFilenames) != len(appMap.Apps) {
-		t.Errorf("Number of unique filenames (%d) doesn't match number of apps (3)", len(foundFilenames))
+	results, err = syncer.Plan()
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(results) != 1 || results[0].Action == ActionRename {
+		t.Errorf("Expected no rename to be planned, got %+v", results)
+	}
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Renamed != 0 {
+		t.Errorf("Expected no renames, got %d", stats.Renamed)
+	}
+	if _, err := os.Stat(filepath.Join(dslDir, "Test_App.yaml")); err != nil {
+		t.Errorf("Expected Test_App.yaml to be kept: %v", err)
 	}
 }
 
-func TestDryRunWithoutAppMap(t *testing.T) {
+func TestFindOrphanedFiles(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir, err := os.MkdirTemp("", "difync-test-")
 	if err != nil {
@@ -934,43 +1423,49 @@ func TestDryRunWithoutAppMap(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Point to a non-existent app map file
-	appMapPath := filepath.Join(tmpDir, "non_existent_app_map.json")
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.MkdirAll(filepath.Join(dslDir, "subdir.yaml"), 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
 
-	// Create a test server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"success"}`))
-	}))
-	defer server.Close()
+	// Create mapped, orphaned and unrelated files
+	for _, name := range []string{"mapped.yaml", "stale.yaml", "copy.yml", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dslDir, name), []byte("test content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+	}
 
-	// Create syncer with test configuration and dry-run enabled
-	config := Config{
-		DifyBaseURL:  server.URL,
-		DifyEmail:    "test@example.com",
-		DifyPassword: "testpassword",
-		DSLDirectory: filepath.Join(tmpDir, "dsl"),
-		AppMapFile:   appMapPath,
-		DryRun:       true, // Enable dry-run mode
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	appMapData := `{"apps": [{"filename": "mapped.yaml", "app_id": "app-id-1"}]}`
+	if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
 	}
-	syncer := NewSyncer(config)
 
-	// Try to load the app map in dry-run mode
-	_, err = syncer.LoadAppMap()
+	syncer := &DefaultSyncer{
+		config: Config{
+			DSLDirectory: dslDir,
+			AppMapFile:   appMapPath,
+		},
+	}
 
-	// Check that we get the expected error message
-	expectedError := fmt.Sprintf("app map file not found at %s. Please run 'difync init' first to initialize the app map", appMapPath)
-	if err == nil || err.Error() != expectedError {
-		t.Errorf("Expected error message:\n%s\n\nGot:\n%v", expectedError, err)
+	orphans, err := syncer.FindOrphanedFiles()
+	if err != nil {
+		t.Fatalf("Failed to find orphaned files: %v", err)
 	}
 
-	// Verify that no app map file was created in dry-run mode
-	if _, err := os.Stat(appMapPath); !os.IsNotExist(err) {
-		t.Errorf("App map file was created in dry-run mode, which should not happen")
+	expected := []string{"copy.yml", "stale.yaml"}
+	if strings.Join(orphans, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected orphans %v, got %v", expected, orphans)
+	}
+
+	// Test with missing app map
+	syncer.config.AppMapFile = filepath.Join(tmpDir, "missing.json")
+	if _, err := syncer.FindOrphanedFiles(); err == nil {
+		t.Error("Expected error when app map is missing")
 	}
 }
 
-func TestInitializeAppMapWithJapaneseNames(t *testing.T) {
+func TestInitializeAppMapIsDeterministic(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir, err := os.MkdirTemp("", "difync-test-")
 	if err != nil {
@@ -978,347 +1473,755 @@ func TestInitializeAppMapWithJapaneseNames(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Create a test DSL directory
-	dslDir := filepath.Join(tmpDir, "dsl")
-	err = os.Mkdir(dslDir, 0755)
-	if err != nil {
-		t.Fatalf("Failed to create DSL directory: %v", err)
+	// Return the same apps in a different order on every request
+	appLists := []string{
+		`{"data": [{"id": "app-c", "name": "Same Name"}, {"id": "app-b", "name": "Beta"}, {"id": "app-a", "name": "Same Name"}]}`,
+		`{"data": [{"id": "app-a", "name": "Same Name"}, {"id": "app-c", "name": "Same Name"}, {"id": "app-b", "name": "Beta"}]}`,
 	}
+	requestCount := 0
 
-	// Create a mock server that returns apps with Japanese names
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/console/api/login":
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"status": "success",
-				"data": {
-					"access_token": "test-token"
-				}
-			}`))
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
 		case "/console/api/apps":
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": [
-					{
-						"id": "app-id-1",
-						"name": "日本語アプリ",
-						"updated_at": 1672531200
-					},
-					{
-						"id": "app-id-2",
-						"name": "テスト（Test）",
-						"updated_at": 1672617600
-					},
-					{
-						"id": "app-id-3",
-						"name": "英語と日本語Mix",
-						"updated_at": 1672704000
-					}
-				]
-			}`))
-		case "/console/api/apps/app-id-1/export":
-		case "/console/api/apps/app-id-2/export":
-		case "/console/api/apps/app-id-3/export":
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": "name: Test App\nversion: 1.0.0"
-			}`))
+			w.Write([]byte(appLists[requestCount%len(appLists)]))
+			requestCount++
 		default:
+			// Exports are unavailable, so no sync timestamps are recorded
 			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
 	defer server.Close()
 
-	// Create syncer with test configuration
-	config := Config{
-		DifyBaseURL:  server.URL,
-		DifyEmail:    "test@example.com",
-		DifyPassword: "testpassword",
-		DSLDirectory: dslDir,
-		AppMapFile:   filepath.Join(tmpDir, "app_map.json"),
-	}
-	syncer := NewSyncer(config)
+	// Initialize twice into separate directories
+	var contents [][]byte
+	for i := 0; i < 2; i++ {
+		runDir := filepath.Join(tmpDir, fmt.Sprintf("run%d", i))
+		appMapPath := filepath.Join(runDir, "app_map.json")
+
+		syncer := NewSyncer(Config{
+			DifyBaseURL:  server.URL,
+			DifyEmail:    "test@example.com",
+			DifyPassword: "testpassword",
+			DSLDirectory: filepath.Join(runDir, "dsl"),
+			AppMapFile:   appMapPath,
+		})
 
-	// Initialize the app map
-	appMap, err := syncer.(*DefaultSyncer).InitializeAppMap()
-	if err != nil {
-		t.Fatalf("Failed to initialize app map: %v", err)
+		if _, err := syncer.(*DefaultSyncer).InitializeAppMap(); err != nil {
+			t.Fatalf("Failed to initialize app map: %v", err)
+		}
+
+		data, err := os.ReadFile(appMapPath)
+		if err != nil {
+			t.Fatalf("Failed to read app map file: %v", err)
+		}
+		contents = append(contents, data)
 	}
 
-	// Check that Japanese names are preserved in filenames
-	expectedFilenames := map[string]string{
-		"app-id-1": "日本語アプリ.yaml",
-		"app-id-2": "テスト（Test）.yaml",
-		"app-id-3": "英語と日本語Mix.yaml",
+	if string(contents[0]) != string(contents[1]) {
+		t.Errorf("Expected identical app map files, got:\n%s\nand:\n%s", contents[0], contents[1])
 	}
 
-	if len(appMap.Apps) != 3 {
-		t.Errorf("Expected 3 apps in app map, got %d", len(appMap.Apps))
+	// Check order and deduplicated names
+	var appMap AppMap
+	if err := json.Unmarshal(contents[0], &appMap); err != nil {
+		t.Fatalf("Failed to unmarshal app map: %v", err)
 	}
 
-	// Check each app's filename matches expected pattern with Japanese characters
-	for _, app := range appMap.Apps {
-		expectedFilename := expectedFilenames[app.AppID]
-		if expectedFilename != app.Filename {
-			t.Errorf("For app ID %s: expected filename %s, got %s", app.AppID, expectedFilename, app.Filename)
+	expected := []AppMapping{
+		{Filename: "Beta.yaml", AppID: "app-b"},
+		{Filename: "Same_Name.yaml", AppID: "app-a"},
+		{Filename: "Same_Name_1.yaml", AppID: "app-c"},
+	}
+	if len(appMap.Apps) != len(expected) {
+		t.Fatalf("Expected %d apps, got %d", len(expected), len(appMap.Apps))
+	}
+	for i, app := range appMap.Apps {
+		if app.Filename != expected[i].Filename || app.AppID != expected[i].AppID {
+			t.Errorf("Expected app %d to be %+v, got %+v", i, expected[i], app)
 		}
 	}
 }
 
-func TestSyncAppWithDeletedApp(t *testing.T) {
-	// Create a temporary directory for testing
-	tmpDir, err := os.MkdirTemp("", "difync-test-")
-	if err != nil {
-		t.Fatalf("Failed to create temp directory: %v", err)
+func TestInitializeAppMapConcurrency(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Several apps share a name, so their filenames depend on the order they are handled in
+	var apps []string
+	for i := 0; i < 12; i++ {
+		name := "Same Name"
+		if i%3 == 0 {
+			name = fmt.Sprintf("App %d", i)
+		}
+		apps = append(apps, fmt.Sprintf(`{"id": "app-%02d", "name": %q}`, i, name))
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Create a test file
-	localPath := filepath.Join(tmpDir, "test.yaml")
-	err = os.WriteFile(localPath, []byte("name: Test App\nversion: 1.0.0"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write test file: %v", err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case r.URL.Path == "/console/api/apps":
+			w.Write([]byte(`{"data": [` + strings.Join(apps, ", ") + `]}`))
+		case strings.HasSuffix(r.URL.Path, "/export"):
+			appID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/console/api/apps/"), "/export")
+			w.Write([]byte(`{"data": "app: ` + appID + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	initialize := func(concurrency int) ([]AppMapping, string) {
+		runDir := filepath.Join(tmpDir, fmt.Sprintf("concurrency%d", concurrency))
+		dslDir := filepath.Join(runDir, "dsl")
+		syncer := NewSyncer(Config{
+			DifyBaseURL:  server.URL,
+			DifyEmail:    "test@example.com",
+			DifyPassword: "testpassword",
+			DSLDirectory: dslDir,
+			AppMapFile:   filepath.Join(runDir, "app_map.json"),
+			Concurrency:  concurrency,
+		})
+
+		appMap, err := syncer.(*DefaultSyncer).InitializeAppMap()
+		if err != nil {
+			t.Fatalf("Failed to initialize app map with concurrency %d: %v", concurrency, err)
+		}
+
+		// Every app is downloaded to its own file
+		for _, app := range appMap.Apps {
+			content, err := os.ReadFile(filepath.Join(dslDir, app.Filename))
+			if err != nil {
+				t.Fatalf("Expected %s to be downloaded: %v", app.Filename, err)
+			}
+			if string(content) != "app: "+app.AppID {
+				t.Errorf("Expected %s to hold the DSL of %s, got %q", app.Filename, app.AppID, content)
+			}
+			if app.LastSyncedAt == nil {
+				t.Errorf("Expected %s to record the download time", app.Filename)
+			}
+		}
+		return appMap.Apps, FormatBytes(syncer.(*DefaultSyncer).bytesDownloaded)
 	}
 
-	// Create a server that simulates a deleted app
+	sequential, sequentialBytes := initialize(1)
+	concurrent, concurrentBytes := initialize(4)
+
+	if len(sequential) != len(apps) || len(concurrent) != len(sequential) {
+		t.Fatalf("Expected %d apps, got %d and %d", len(apps), len(sequential), len(concurrent))
+	}
+	for i := range sequential {
+		if sequential[i].Filename != concurrent[i].Filename || sequential[i].AppID != concurrent[i].AppID {
+			t.Errorf("Expected entry %d to be %s (%s), got %s (%s)", i,
+				sequential[i].Filename, sequential[i].AppID, concurrent[i].Filename, concurrent[i].AppID)
+		}
+	}
+	if sequentialBytes != concurrentBytes {
+		t.Errorf("Expected %s downloaded, got %s", sequentialBytes, concurrentBytes)
+	}
+}
+
+func TestInitializeAppMapKeepsExistingEntries(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Handle login request
-		if r.URL.Path == "/console/api/login" {
+		switch r.URL.Path {
+		case "/console/api/login":
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"status": "success",
-				"data": {
-					"access_token": "test-token"
-				}
-			}`))
-			return
-		}
-
-		// Handle app info request for deleted app
-		if r.URL.Path == "/console/api/apps/deleted-app-id" {
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "Alpha"}, {"id": "app-b", "name": "Beta"}, {"id": "app-c", "name": "Gamma"}]}`))
+		default:
+			// Exports are unavailable, so no sync timestamps are recorded
 			w.WriteHeader(http.StatusNotFound)
-			return
 		}
-
-		// Default response
-		w.WriteHeader(http.StatusOK)
 	}))
 	defer server.Close()
 
-	// Create syncer with test configuration
-	config := Config{
-		DifyBaseURL:  server.URL,
-		DifyEmail:    "test@example.com",
-		DifyPassword: "testpassword",
-		DSLDirectory: tmpDir,
+	existing := AppMap{
+		Apps: []AppMapping{
+			{Filename: "custom_alpha.yaml", AppID: "app-a", ReadOnly: true},
+			{Filename: "Beta.yaml", AppID: "app-b", Skip: true},
+			{Filename: "gone.yaml", AppID: "app-gone"},
+		},
 	}
-	syncer := NewSyncer(config)
-
-	// Test SyncApp with deleted app
-	result := syncer.SyncApp(AppMapping{
-		Filename: "test.yaml",
-		AppID:    "deleted-app-id",
-	})
 
-	if result.Action != ActionNone {
-		t.Errorf("Expected Action to be none for deleted app, got %s", result.Action)
+	tests := []struct {
+		name     string
+		pruneMap bool
+		reinit   bool
+		expected []AppMapping
+	}{
+		{
+			name: "overrides survive re-init",
+			expected: []AppMapping{
+				{Filename: "Beta.yaml", AppID: "app-b", Skip: true},
+				{Filename: "Gamma.yaml", AppID: "app-c"},
+				{Filename: "custom_alpha.yaml", AppID: "app-a", ReadOnly: true},
+				{Filename: "gone.yaml", AppID: "app-gone"},
+			},
+		},
+		{
+			name:     "prune map removes deleted apps",
+			pruneMap: true,
+			expected: []AppMapping{
+				{Filename: "Beta.yaml", AppID: "app-b", Skip: true},
+				{Filename: "Gamma.yaml", AppID: "app-c"},
+				{Filename: "custom_alpha.yaml", AppID: "app-a", ReadOnly: true},
+			},
+		},
+		{
+			name:   "reinit rebuilds from scratch",
+			reinit: true,
+			expected: []AppMapping{
+				{Filename: "Alpha.yaml", AppID: "app-a"},
+				{Filename: "Beta.yaml", AppID: "app-b"},
+				{Filename: "Gamma.yaml", AppID: "app-c"},
+			},
+		},
 	}
 
-	if !result.Success {
-		t.Error("Expected Success to be true for deleted app")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := os.MkdirTemp("", "difync-test-")
+			if err != nil {
+				t.Fatalf("Failed to create temp directory: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			appMapPath := filepath.Join(tmpDir, "app_map.json")
+			data, err := json.Marshal(existing)
+			if err != nil {
+				t.Fatalf("Failed to marshal app map: %v", err)
+			}
+			if err := os.WriteFile(appMapPath, data, 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			syncer := NewSyncer(Config{
+				DifyBaseURL:  server.URL,
+				DifyEmail:    "test@example.com",
+				DifyPassword: "testpassword",
+				DSLDirectory: filepath.Join(tmpDir, "dsl"),
+				AppMapFile:   appMapPath,
+				PruneMap:     tt.pruneMap,
+				Reinit:       tt.reinit,
+			})
+
+			if _, err := syncer.(*DefaultSyncer).InitializeAppMap(); err != nil {
+				t.Fatalf("Failed to initialize app map: %v", err)
+			}
+
+			appMap, err := syncer.LoadAppMap()
+			if err != nil {
+				t.Fatalf("Failed to load app map: %v", err)
+			}
+
+			if !reflect.DeepEqual(appMap.Apps, tt.expected) {
+				t.Errorf("Expected apps %+v, got %+v", tt.expected, appMap.Apps)
+			}
+		})
 	}
 }
 
-func TestSyncAllWithDeletedApps(t *testing.T) {
-	// Create a temporary directory for testing
+func TestInitializeAppMapRecordsMode(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "difync-test-")
 	if err != nil {
 		t.Fatalf("Failed to create temp directory: %v", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Create DSL directory
-	dslDir := filepath.Join(tmpDir, "dsl")
-	err = os.Mkdir(dslDir, 0755)
-	if err != nil {
-		t.Fatalf("Failed to create DSL directory: %v", err)
-	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "app-w", "name": "Flow", "mode": "workflow"}, {"id": "app-u", "name": "Unknown"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
 
-	// Create test files
-	file1 := filepath.Join(dslDir, "app1.yaml")
-	err = os.WriteFile(file1, []byte("name: App 1\nversion: 1.0.0"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write test file: %v", err)
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: filepath.Join(tmpDir, "dsl"),
+		AppMapFile:   appMapPath,
+	})
+
+	if _, err := syncer.(*DefaultSyncer).InitializeAppMap(); err != nil {
+		t.Fatalf("Failed to initialize app map: %v", err)
 	}
 
-	file2 := filepath.Join(dslDir, "app2.yaml")
-	err = os.WriteFile(file2, []byte("name: App 2\nversion: 1.0.0"), 0644)
+	data, err := os.ReadFile(appMapPath)
 	if err != nil {
-		t.Fatalf("Failed to write test file: %v", err)
+		t.Fatalf("Failed to read app map file: %v", err)
 	}
 
-	// Create app map file
-	appMapFile := filepath.Join(tmpDir, "app_map.json")
-	appMap := AppMap{
-		Apps: []AppMapping{
-			{
-				Filename: "app1.yaml",
-				AppID:    "app-id-1",
-			},
-			{
-				Filename: "app2.yaml",
-				AppID:    "app-id-2", // This one will be deleted
-			},
-		},
+	var appMap AppMap
+	if err := json.Unmarshal(data, &appMap); err != nil {
+		t.Fatalf("Failed to unmarshal app map: %v", err)
 	}
 
-	appMapData, err := json.Marshal(appMap)
-	if err != nil {
-		t.Fatalf("Failed to marshal app map: %v", err)
+	expected := []AppMapping{
+		{Filename: "Flow.yaml", AppID: "app-w", Mode: "workflow"},
+		{Filename: "Unknown.yaml", AppID: "app-u"},
+	}
+	if !reflect.DeepEqual(appMap.Apps, expected) {
+		t.Errorf("Expected apps %+v, got %+v", expected, appMap.Apps)
 	}
 
-	err = os.WriteFile(appMapFile, appMapData, 0644)
-	if err != nil {
-		t.Fatalf("Failed to write app map file: %v", err)
+	// Apps without a mode do not get an empty mode field
+	if strings.Count(string(data), `"mode"`) != 1 {
+		t.Errorf("Expected mode to be omitted when unknown, got:\n%s", data)
 	}
+}
 
-	// Create a server that simulates one deleted app
+func TestInitializeAppMapAppIDs(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Handle login request
-		if r.URL.Path == "/console/api/login" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"status": "success",
-				"data": {
-					"access_token": "test-token"
-				}
-			}`))
-			return
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			t.Error("Expected the app list not to be requested")
+			w.Write([]byte(`{"data": []}`))
+		case "/console/api/apps/app-a":
+			w.Write([]byte(`{"data": {"id": "app-a", "name": "Mine A", "mode": "chat"}}`))
+		case "/console/api/apps/app-b":
+			w.Write([]byte(`{"data": {"id": "app-b", "name": "Mine B", "mode": "workflow"}}`))
+		case "/console/api/apps/app-a/export":
+			w.Write([]byte(`{"data": "name: Mine A"}`))
+		case "/console/api/apps/app-b/export":
+			w.Write([]byte(`{"data": "name: Mine B"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
 		}
+	}))
+	defer server.Close()
 
-		// Handle app list request
-		if r.URL.Path == "/console/api/apps" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": [
-					{
-						"id": "app-id-1",
-						"name": "App 1",
-						"updated_at": "2023-01-01T12:00:00Z"
-					}
-				]
-			}`))
-			return
-		}
+	newSyncer := func(t *testing.T, appIDs ...string) (*DefaultSyncer, string) {
+		tmpDir := t.TempDir()
+		appMapPath := filepath.Join(tmpDir, "app_map.json")
+		syncer := NewSyncer(Config{
+			DifyBaseURL:  server.URL,
+			DifyEmail:    "test@example.com",
+			DifyPassword: "testpassword",
+			DSLDirectory: filepath.Join(tmpDir, "dsl"),
+			AppMapFile:   appMapPath,
+			AppIDs:       appIDs,
+		})
+		return syncer.(*DefaultSyncer), appMapPath
+	}
 
-		// Handle check for app-id-1 (exists)
-		if r.URL.Path == "/console/api/apps/app-id-1/check" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"exists": true}`))
-			return
+	t.Run("only the requested apps", func(t *testing.T) {
+		syncer, _ := newSyncer(t, "app-b", "app-a", "app-b")
+		appMap, err := syncer.InitializeAppMap()
+		if err != nil {
+			t.Fatalf("Failed to initialize app map: %v", err)
 		}
 
-		// Handle check for app-id-2 (deleted)
-		if r.URL.Path == "/console/api/apps/app-id-2/check" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"exists": false}`))
-			return
+		expected := []AppMapping{
+			{Filename: "Mine_A.yaml", AppID: "app-a", Mode: "chat"},
+			{Filename: "Mine_B.yaml", AppID: "app-b", Mode: "workflow"},
 		}
-
-		// App 2 is deleted
-		if strings.Contains(r.URL.Path, "app-id-2") {
-			w.WriteHeader(http.StatusNotFound)
-			return
+		for i := range appMap.Apps {
+			appMap.Apps[i].LastSyncedAt = nil
+		}
+		if !reflect.DeepEqual(appMap.Apps, expected) {
+			t.Errorf("Expected apps %+v, got %+v", expected, appMap.Apps)
 		}
 
-		// Other apps exist
-		if strings.Contains(r.URL.Path, "/export") {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"data": "name: App 1\nversion: 1.0.0"}`))
-			return
+		data, err := os.ReadFile(filepath.Join(syncer.config.DSLDirectory, "Mine_A.yaml"))
+		if err != nil || string(data) != "name: Mine A" {
+			t.Errorf("Expected Mine_A.yaml to be downloaded, got %q, %v", data, err)
 		}
+	})
 
-		// Handle app info request for app 1
-		if r.URL.Path == "/console/api/apps/app-id-1" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": {
-					"id": "app-id-1",
-					"name": "App 1",
-					"updated_at": "2023-01-01T12:00:00Z"
-				}
-			}`))
-			return
+	t.Run("quiet", func(t *testing.T) {
+		syncer, _ := newSyncer(t, "app-a")
+		syncer.config.Quiet = true
+		var err error
+		output := captureStdout(t, func() {
+			_, err = syncer.InitializeAppMap()
+		})
+		if err != nil {
+			t.Fatalf("Failed to initialize app map: %v", err)
+		}
+		if strings.Contains(output, "Downloaded") {
+			t.Errorf("Expected no download summary in quiet mode, got %q", output)
 		}
+	})
 
-		// Default response
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{}`))
+	t.Run("unknown app ID", func(t *testing.T) {
+		syncer, appMapPath := newSyncer(t, "app-a", "app-missing")
+		_, err := syncer.InitializeAppMap()
+		if err == nil || !strings.Contains(err.Error(), "app app-missing does not exist in Dify") {
+			t.Errorf("Expected an error naming the missing app, got %v", err)
+		}
+		if _, err := os.Stat(appMapPath); !os.IsNotExist(err) {
+			t.Error("Expected no app map to be written")
+		}
+	})
+
+	t.Run("prune map", func(t *testing.T) {
+		// Other entries are not checked, so pruning would drop all of them
+		syncer, appMapPath := newSyncer(t, "app-a")
+		content := `{"apps": [{"filename": "Mine_A.yaml", "app_id": "app-a"}, {"filename": "Other.yaml", "app_id": "app-other"}]}`
+		if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write app map file: %v", err)
+		}
+		syncer.config.PruneMap = true
+
+		_, err := syncer.InitializeAppMap()
+		if err == nil || !strings.Contains(err.Error(), "--prune-map cannot be used with --app-id") {
+			t.Errorf("Expected --prune-map to be rejected, got %v", err)
+		}
+		if data, _ := os.ReadFile(appMapPath); string(data) != content {
+			t.Errorf("Expected the app map to be untouched, got %s", data)
+		}
+	})
+}
+
+func TestInitializeAppMapNoApps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
 	}))
 	defer server.Close()
 
-	// Create syncer with test configuration
-	config := Config{
+	tmpDir := t.TempDir()
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	dslDir := filepath.Join(tmpDir, "dsl")
+	syncer := NewSyncer(Config{
 		DifyBaseURL:  server.URL,
 		DifyEmail:    "test@example.com",
 		DifyPassword: "testpassword",
 		DSLDirectory: dslDir,
-		AppMapFile:   appMapFile,
-		Verbose:      true,
+		AppMapFile:   appMapPath,
+	}).(*DefaultSyncer)
+
+	// Without an app map, nothing is created
+	if _, err := syncer.InitializeAppMap(); !errors.Is(err, ErrNoApps) {
+		t.Errorf("Expected ErrNoApps, got %v", err)
+	}
+	if _, err := os.Stat(appMapPath); !os.IsNotExist(err) {
+		t.Error("Expected no app map to be created")
+	}
+	if _, err := os.Stat(dslDir); !os.IsNotExist(err) {
+		t.Error("Expected no DSL directory to be created")
 	}
-	syncer := NewSyncer(config)
 
-	// Run SyncAll
-	stats, err := syncer.SyncAll()
+	// An existing app map is not wiped, even with --reinit or --prune-map
+	content := `{"version": 1, "apps": [{"filename": "mine.yaml", "app_id": "app-1"}]}`
+	if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+	syncer.config.Reinit = true
+	syncer.config.PruneMap = true
+	if _, err := syncer.InitializeAppMap(); !errors.Is(err, ErrNoApps) {
+		t.Errorf("Expected ErrNoApps, got %v", err)
+	}
+	data, err := os.ReadFile(appMapPath)
 	if err != nil {
-		t.Fatalf("SyncAll failed: %v", err)
+		t.Fatalf("Failed to read app map file: %v", err)
 	}
+	if string(data) != content {
+		t.Errorf("Expected the app map to be left alone, got:\n%s", data)
+	}
+}
 
-	// Check that we have one download (for the deleted app)
-	if stats.Downloads != 1 {
-		t.Errorf("Expected 1 download (deleted app), got %d", stats.Downloads)
+func TestInitializeAppMapOverwrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite bool
+		expected  string
+	}{
+		{name: "only new keeps existing files", overwrite: false, expected: "name: Edited locally"},
+		{name: "overwrite replaces existing files", overwrite: true, expected: "name: Test App\nversion: 1.0.0"},
 	}
 
-	// Check that app2.yaml has been deleted
-	if _, err := os.Stat(file2); !os.IsNotExist(err) {
-		t.Error("Expected app2.yaml to be deleted")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+			defer cleanup()
+
+			// The app is already mapped and its file has local edits
+			localPath := filepath.Join(dslDir, "Test_App.yaml")
+			if err := os.WriteFile(localPath, []byte("name: Edited locally"), 0644); err != nil {
+				t.Fatalf("Failed to write DSL file: %v", err)
+			}
+			content := `{"version": 1, "apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`
+			if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			defaultSyncer := syncer.(*DefaultSyncer)
+			defaultSyncer.config.Overwrite = tt.overwrite
+			if _, err := defaultSyncer.InitializeAppMap(); err != nil {
+				t.Fatalf("Failed to initialize app map: %v", err)
+			}
+
+			data, err := os.ReadFile(localPath)
+			if err != nil {
+				t.Fatalf("Failed to read DSL file: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, data)
+			}
+		})
 	}
+}
 
-	// Check that app map has been updated
-	var updatedAppMap AppMap
-	updatedAppMapData, err := os.ReadFile(appMapFile)
-	if err != nil {
-		t.Fatalf("Failed to read updated app map file: %v", err)
+func TestSanitizeFilename(t *testing.T) {
+	// Create a DefaultSyncer for testing
+	syncer := &DefaultSyncer{}
+
+	testCases := []struct {
+		input    string
+		expected string
+		desc     string
+	}{
+		{
+			input:    "Simple App Name",
+			expected: "Simple_App_Name",
+			desc:     "Convert spaces to underscores",
+		},
+		{
+			input:    "App/With:Invalid*Chars?",
+			expected: "AppWithInvalidChars",
+			desc:     "Remove invalid characters",
+		},
+		{
+			input:    "日本語のアプリ名",
+			expected: "日本語のアプリ名",
+			desc:     "Preserve Japanese characters",
+		},
+		{
+			input:    "アプリ名（テスト）",
+			expected: "アプリ名（テスト）",
+			desc:     "Preserve Japanese parentheses",
+		},
+		{
+			input:    "Testing <> | / \\ : * ? \" App",
+			expected: "Testing_________App",
+			desc:     "Remove special characters and convert spaces to underscores",
+		},
+		{
+			input:    "",
+			expected: "app",
+			desc:     "Use default name for empty string",
+		},
+		{
+			input:    "       ",
+			expected: "_______",
+			desc:     "Convert whitespace-only string to underscores",
+		},
+		{
+			input:    "Mixed 日本語 and English",
+			expected: "Mixed_日本語_and_English",
+			desc:     "Mix of Japanese and English",
+		},
+		{
+			input:    "App with 特殊文字 *><|",
+			expected: "App_with_特殊文字_",
+			desc:     "Mix of Japanese and English with special characters",
+		},
+		{
+			input:    "CON",
+			expected: "_CON",
+			desc:     "Prefix reserved device name",
+		},
+		{
+			input:    "prn",
+			expected: "_prn",
+			desc:     "Prefix reserved device name case-insensitively",
+		},
+		{
+			input:    "Aux.backup",
+			expected: "_Aux.backup",
+			desc:     "Prefix reserved device name followed by a dot",
+		},
+		{
+			input:    "COM1",
+			expected: "_COM1",
+			desc:     "Prefix reserved COM port name",
+		},
+		{
+			input:    "lpt9",
+			expected: "_lpt9",
+			desc:     "Prefix reserved LPT port name",
+		},
+		{
+			input:    "COM10",
+			expected: "COM10",
+			desc:     "Keep non-reserved COM-like name",
+		},
+		{
+			input:    "CONSOLE",
+			expected: "CONSOLE",
+			desc:     "Keep name that only starts with a reserved name",
+		},
+		{
+			input:    "My App...",
+			expected: "My_App",
+			desc:     "Trim trailing dots",
+		},
+		{
+			input:    "アプリ。.",
+			expected: "アプリ。",
+			desc:     "Trim trailing dots after Japanese characters",
+		},
+		{
+			input:    "...",
+			expected: "app",
+			desc:     "Use default name when only dots remain",
+		},
+		{
+			input:    "NUL.",
+			expected: "_NUL",
+			desc:     "Trim trailing dot and prefix reserved device name",
+		},
 	}
 
-	err = json.Unmarshal(updatedAppMapData, &updatedAppMap)
-	if err != nil {
-		t.Fatalf("Failed to unmarshal updated app map: %v", err)
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result := syncer.sanitizeFilename(tc.input)
+			if result != tc.expected {
+				t.Errorf("sanitizeFilename(%q) = %q, expected %q", tc.input, result, tc.expected)
+			}
+		})
 	}
+}
+
+func TestSanitizeFilenameInvisibleCharacters(t *testing.T) {
+	syncer := &DefaultSyncer{}
 
-	if len(updatedAppMap.Apps) != 1 {
-		t.Errorf("Expected 1 app in updated app map, got %d", len(updatedAppMap.Apps))
+	testCases := []struct {
+		input    string
+		expected string
+		desc     string
+	}{
+		{
+			input:    "\uFEFFSales Bot",
+			expected: "Sales_Bot",
+			desc:     "Strip leading BOM",
+		},
+		{
+			input:    "Sales\u200B Bot\u200C",
+			expected: "Sales_Bot",
+			desc:     "Strip zero width space and non-joiner",
+		},
+		{
+			input:    "\u200D\u2060\u200E\u200FBot",
+			expected: "Bot",
+			desc:     "Strip joiners and direction marks",
+		},
+		{
+			input:    "Sales\x00\x1fBot\x7f",
+			expected: "SalesBot",
+			desc:     "Strip control characters",
+		},
+		{
+			input:    "Line\nBreak\tTab",
+			expected: "LineBreakTab",
+			desc:     "Strip newlines and tabs",
+		},
+		{
+			input:    "Bad\xff\xfeBytes",
+			expected: "BadBytes",
+			desc:     "Strip invalid UTF-8",
+		},
+		{
+			input:    "\uFEFF日本語\u200Bのアプリ",
+			expected: "日本語のアプリ",
+			desc:     "Preserve Japanese around invisible characters",
+		},
+		{
+			input:    "\uFEFF\u200B",
+			expected: "app",
+			desc:     "Use default name when only invisible characters remain",
+		},
 	}
 
-	if len(updatedAppMap.Apps) > 0 && updatedAppMap.Apps[0].AppID != "app-id-1" {
-		t.Errorf("Expected app-id-1 to remain in app map, got %s", updatedAppMap.Apps[0].AppID)
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result := syncer.sanitizeFilename(tc.input)
+			if result != tc.expected {
+				t.Errorf("sanitizeFilename(%q) = %q, expected %q", tc.input, result, tc.expected)
+			}
+		})
 	}
 }
 
-func TestSyncAppExtensive(t *testing.T) {
+func TestSanitizeFilenameLength(t *testing.T) {
+	syncer := &DefaultSyncer{}
+
+	testCases := []struct {
+		input string
+		desc  string
+	}{
+		{
+			input: strings.Repeat("a", 300),
+			desc:  "Truncate long ASCII name",
+		},
+		{
+			input: strings.Repeat("日本語のアプリ", 30),
+			desc:  "Truncate long Japanese name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result := syncer.sanitizeFilename(tc.input)
+			filename := result + ".yaml"
+
+			if len(filename) > 255 {
+				t.Errorf("Expected filename to fit in 255 bytes, got %d bytes", len(filename))
+			}
+
+			if !utf8.ValidString(result) {
+				t.Errorf("Expected truncated name to be valid UTF-8, got %q", result)
+			}
+
+			// A different long name with the same prefix must produce a different result
+			other := syncer.sanitizeFilename(tc.input + "x")
+			if other == result {
+				t.Errorf("Expected different names to stay unique after truncation, both got %q", result)
+			}
+		})
+	}
+
+	// Short names must not be altered
+	if result := syncer.sanitizeFilename("Short Name"); result != "Short_Name" {
+		t.Errorf("Expected short name to be unchanged, got %q", result)
+	}
+}
+
+func TestFilenameDeduplication(t *testing.T) {
 	// Create a temporary directory for testing
-	tmpDir, err := os.MkdirTemp("", "difync-test-syncapp-")
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
 	if err != nil {
 		t.Fatalf("Failed to create temp dir: %v", err)
 	}
@@ -1330,691 +2233,2324 @@ func TestSyncAppExtensive(t *testing.T) {
 		t.Fatalf("Failed to create DSL directory: %v", err)
 	}
 
-	// Create a test file (app1.yaml)
-	file1 := filepath.Join(dslDir, "app1.yaml")
-	if err := os.WriteFile(file1, []byte("name: App 1\nversion: 1.0.0"), 0644); err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
-	}
-
-	// Set file modification time to a known value (1 day ago)
-	fileTime := time.Now().Add(-24 * time.Hour)
-	if err := os.Chtimes(file1, fileTime, fileTime); err != nil {
-		t.Fatalf("Failed to set file modification time: %v", err)
+	// Create test files with duplicate base names
+	testFiles := []string{
+		"Duplicate_App.yaml",
+		"Another_App.yaml",
 	}
 
-	// Create test mapping
-	app1 := AppMapping{
-		Filename: "app1.yaml",
-		AppID:    "app-id-1",
+	for _, filename := range testFiles {
+		filePath := filepath.Join(dslDir, filename)
+		if err := os.WriteFile(filePath, []byte("test content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", filename, err)
+		}
 	}
 
-	// Create a mock server
-	var serverHandler func(w http.ResponseWriter, r *http.Request)
+	// Create a mock server that returns a list of apps with duplicate names
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		serverHandler(w, r)
-	}))
-	defer server.Close()
-
-	// Basic test cases for SyncApp
-	testCases := []struct {
-		name           string
-		handler        func(w http.ResponseWriter, r *http.Request)
-		expectedAction SyncAction
-		expectedError  bool
-		dryRun         bool
-		verbose        bool
-	}{
-		{
-			name: "file_not_found",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				// This case won't hit the server as the file doesn't exist
-			},
-			expectedAction: ActionError,
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"status": "success",
+				"data": {
+					"access_token": "test-token"
+				}
+			}`))
+		case "/console/api/apps":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": [
+					{
+						"id": "app-id-1",
+						"name": "Duplicate App",
+						"updated_at": 1672531200
+					},
+					{
+						"id": "app-id-2",
+						"name": "Duplicate App",
+						"updated_at": 1672617600
+					},
+					{
+						"id": "app-id-3",
+						"name": "Another App",
+						"updated_at": 1672704000
+					}
+				]
+			}`))
+		case "/console/api/apps/app-id-1/export":
+		case "/console/api/apps/app-id-2/export":
+		case "/console/api/apps/app-id-3/export":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": "name: Test App\nversion: 1.0.0"
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create syncer with test configuration
+	config := Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   filepath.Join(tmpDir, "app_map.json"),
+	}
+	syncer := NewSyncer(config)
+
+	// Initialize the app map
+	appMap, err := syncer.(*DefaultSyncer).InitializeAppMap()
+	if err != nil {
+		t.Fatalf("Failed to initialize app map: %v", err)
+	}
+
+	if len(appMap.Apps) != 3 {
+		t.Errorf("Expected 3 apps in app map, got %d", len(appMap.Apps))
+	}
+
+	// Count how many files with each base name appear
+	duplicateAppCount := 0
+	anotherAppCount := 0
+
+	for _, app := range appMap.Apps {
+		if strings.HasPrefix(app.Filename, "Duplicate_App") {
+			duplicateAppCount++
+		} else if strings.HasPrefix(app.Filename, "Another_App") {
+			anotherAppCount++
+		} else {
+			t.Errorf("Unexpected filename: %s", app.Filename)
+		}
+	}
+
+	// Check the total number matches
+	if len(appMap.Apps) != 3 {
+		t.Errorf("Number of unique filenames (%d) doesn't match number of apps (3)", len(appMap.Apps))
+	}
+
+	// Check for duplicate filenames
+	foundFilenames := make(map[string]bool)
+	for _, app := range appMap.Apps {
+		if foundFilenames[app.Filename] {
+			t.Errorf("Found duplicate filename: %s", app.Filename)
+		}
+		foundFilenames[app.Filename] = true
+	}
+
+	// Check the total number matches
+	if len(foundFilenames) != len(appMap.Apps) {
+		t.Errorf("Number of unique filenames (%d) doesn't match number of apps (3)", len(foundFilenames))
+	}
+}
+
+func TestDryRunWithoutAppMap(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Point to a non-existent app map file
+	appMapPath := filepath.Join(tmpDir, "non_existent_app_map.json")
+
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	// Create syncer with test configuration and dry-run enabled
+	config := Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: filepath.Join(tmpDir, "dsl"),
+		AppMapFile:   appMapPath,
+		DryRun:       true, // Enable dry-run mode
+	}
+	syncer := NewSyncer(config)
+
+	// Try to load the app map in dry-run mode
+	_, err = syncer.LoadAppMap()
+
+	// Check that we get the expected error message
+	expectedError := fmt.Sprintf("app map file not found at %s. Please run 'difync init' first to initialize the app map", appMapPath)
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error message:\n%s\n\nGot:\n%v", expectedError, err)
+	}
+
+	// Verify that no app map file was created in dry-run mode
+	if _, err := os.Stat(appMapPath); !os.IsNotExist(err) {
+		t.Errorf("App map file was created in dry-run mode, which should not happen")
+	}
+}
+
+func TestInitializeAppMapWithJapaneseNames(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Create a test DSL directory
+	dslDir := filepath.Join(tmpDir, "dsl")
+	err = os.Mkdir(dslDir, 0755)
+	if err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	// Create a mock server that returns apps with Japanese names
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"status": "success",
+				"data": {
+					"access_token": "test-token"
+				}
+			}`))
+		case "/console/api/apps":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": [
+					{
+						"id": "app-id-1",
+						"name": "日本語アプリ",
+						"updated_at": 1672531200
+					},
+					{
+						"id": "app-id-2",
+						"name": "テスト（Test）",
+						"updated_at": 1672617600
+					},
+					{
+						"id": "app-id-3",
+						"name": "英語と日本語Mix",
+						"updated_at": 1672704000
+					}
+				]
+			}`))
+		case "/console/api/apps/app-id-1/export":
+		case "/console/api/apps/app-id-2/export":
+		case "/console/api/apps/app-id-3/export":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": "name: Test App\nversion: 1.0.0"
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create syncer with test configuration
+	config := Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   filepath.Join(tmpDir, "app_map.json"),
+	}
+	syncer := NewSyncer(config)
+
+	// Initialize the app map
+	appMap, err := syncer.(*DefaultSyncer).InitializeAppMap()
+	if err != nil {
+		t.Fatalf("Failed to initialize app map: %v", err)
+	}
+
+	// Check that Japanese names are preserved in filenames
+	expectedFilenames := map[string]string{
+		"app-id-1": "日本語アプリ.yaml",
+		"app-id-2": "テスト（Test）.yaml",
+		"app-id-3": "英語と日本語Mix.yaml",
+	}
+
+	if len(appMap.Apps) != 3 {
+		t.Errorf("Expected 3 apps in app map, got %d", len(appMap.Apps))
+	}
+
+	// Check each app's filename matches expected pattern with Japanese characters
+	for _, app := range appMap.Apps {
+		expectedFilename := expectedFilenames[app.AppID]
+		if expectedFilename != app.Filename {
+			t.Errorf("For app ID %s: expected filename %s, got %s", app.AppID, expectedFilename, app.Filename)
+		}
+	}
+}
+
+func TestSyncAppWithDeletedApp(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Create a test file
+	localPath := filepath.Join(tmpDir, "test.yaml")
+	err = os.WriteFile(localPath, []byte("name: Test App\nversion: 1.0.0"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Create a server that simulates a deleted app
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handle login request
+		if r.URL.Path == "/console/api/login" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"status": "success",
+				"data": {
+					"access_token": "test-token"
+				}
+			}`))
+			return
+		}
+
+		// Handle app info request for deleted app
+		if r.URL.Path == "/console/api/apps/deleted-app-id" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Default response
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Create syncer with test configuration
+	config := Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: tmpDir,
+	}
+	syncer := NewSyncer(config)
+
+	// Test SyncApp with deleted app
+	result := syncer.SyncApp(AppMapping{
+		Filename: "test.yaml",
+		AppID:    "deleted-app-id",
+	})
+
+	if result.Action != ActionNone {
+		t.Errorf("Expected Action to be none for deleted app, got %s", result.Action)
+	}
+
+	if !result.Success {
+		t.Error("Expected Success to be true for deleted app")
+	}
+}
+
+func TestSyncAllWithDeletedApps(t *testing.T) {
+	testCases := []struct {
+		name              string
+		deleteOrphans     bool
+		pruneMap          bool
+		archiveDeleted    bool
+		archiveCollision  bool // an app2.yaml was archived before
+		expectFileDeleted bool
+		expectedMapApps   int
+		expectedDownloads int
+		expectedDeleted   int
+	}{
+		{
+			name:              "delete_orphans",
+			deleteOrphans:     true,
+			expectFileDeleted: true,
+			expectedMapApps:   1,
+			expectedDownloads: 0,
+			expectedDeleted:   1,
+		},
+		{
+			name:              "keep_orphans",
+			expectFileDeleted: false,
+			expectedMapApps:   2,
+			expectedDownloads: 0,
+			expectedDeleted:   0,
+		},
+		{
+			name:              "keep_orphans_prune_map",
+			pruneMap:          true,
+			expectFileDeleted: false,
+			expectedMapApps:   1,
+			expectedDownloads: 0,
+			expectedDeleted:   1,
+		},
+		{
+			name:              "archive_deleted",
+			archiveDeleted:    true,
+			expectFileDeleted: true,
+			expectedMapApps:   1,
+			expectedDownloads: 0,
+			expectedDeleted:   1,
+		},
+		{
+			name:              "archive_deleted_collision",
+			archiveDeleted:    true,
+			archiveCollision:  true,
+			expectFileDeleted: true,
+			expectedMapApps:   1,
+			expectedDownloads: 0,
+			expectedDeleted:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a temporary directory for testing
+			tmpDir, err := os.MkdirTemp("", "difync-test-")
+			if err != nil {
+				t.Fatalf("Failed to create temp directory: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			// Create DSL directory
+			dslDir := filepath.Join(tmpDir, "dsl")
+			err = os.Mkdir(dslDir, 0755)
+			if err != nil {
+				t.Fatalf("Failed to create DSL directory: %v", err)
+			}
+
+			// Create test files
+			file1 := filepath.Join(dslDir, "app1.yaml")
+			err = os.WriteFile(file1, []byte("name: App 1\nversion: 1.0.0"), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			file2 := filepath.Join(dslDir, "app2.yaml")
+			err = os.WriteFile(file2, []byte("name: App 2\nversion: 1.0.0"), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			// Create app map file
+			appMapFile := filepath.Join(tmpDir, "app_map.json")
+			appMap := AppMap{
+				Apps: []AppMapping{
+					{
+						Filename: "app1.yaml",
+						AppID:    "app-id-1",
+					},
+					{
+						Filename: "app2.yaml",
+						AppID:    "app-id-2", // This one will be deleted
+					},
+				},
+			}
+
+			appMapData, err := json.Marshal(appMap)
+			if err != nil {
+				t.Fatalf("Failed to marshal app map: %v", err)
+			}
+
+			err = os.WriteFile(appMapFile, appMapData, 0644)
+			if err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			// Create a server that simulates one deleted app
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Handle login request
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+
+				// Handle app list request
+				if r.URL.Path == "/console/api/apps" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": [
+							{
+								"id": "app-id-1",
+								"name": "App 1",
+								"updated_at": "2023-01-01T12:00:00Z"
+							}
+						]
+					}`))
+					return
+				}
+
+				// Handle check for app-id-1 (exists)
+				if r.URL.Path == "/console/api/apps/app-id-1/check" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"exists": true}`))
+					return
+				}
+
+				// Handle check for app-id-2 (deleted)
+				if r.URL.Path == "/console/api/apps/app-id-2/check" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"exists": false}`))
+					return
+				}
+
+				// App 2 is deleted
+				if strings.Contains(r.URL.Path, "app-id-2") {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				// Other apps exist
+				if strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"data": "name: App 1\nversion: 1.0.0"}`))
+					return
+				}
+
+				// Handle app info request for app 1
+				if r.URL.Path == "/console/api/apps/app-id-1" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": "2023-01-01T12:00:00Z"
+						}
+					}`))
+					return
+				}
+
+				// Default response
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			// Create syncer with test configuration
+			config := Config{
+				DifyBaseURL:  server.URL,
+				DifyEmail:    "test@example.com",
+				DifyPassword: "testpassword",
+				DSLDirectory: dslDir,
+				AppMapFile:   appMapFile,
+				Verbose:      true,
+
+				DeleteOrphans: tc.deleteOrphans,
+				PruneMap:      tc.pruneMap,
+			}
+
+			archiveDir := filepath.Join(tmpDir, "archive")
+			if tc.archiveDeleted {
+				config.ArchiveDeletedDir = archiveDir
+			}
+			if tc.archiveCollision {
+				if err := os.MkdirAll(archiveDir, 0755); err != nil {
+					t.Fatalf("Failed to create archive directory: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(archiveDir, "app2.yaml"), []byte("old"), 0644); err != nil {
+					t.Fatalf("Failed to write archived file: %v", err)
+				}
+			}
+
+			syncer := NewSyncer(config)
+
+			// Run SyncAll
+			stats, err := syncer.SyncAll()
+			if err != nil {
+				t.Fatalf("SyncAll failed: %v", err)
+			}
+
+			// Check the download and deletion counts
+			if stats.Downloads != tc.expectedDownloads {
+				t.Errorf("Expected %d downloads, got %d", tc.expectedDownloads, stats.Downloads)
+			}
+			if stats.Deleted != tc.expectedDeleted {
+				t.Errorf("Expected %d deleted, got %d", tc.expectedDeleted, stats.Deleted)
+			}
+
+			// Check whether app2.yaml has been deleted
+			_, statErr := os.Stat(file2)
+			if tc.expectFileDeleted && !os.IsNotExist(statErr) {
+				t.Error("Expected app2.yaml to be deleted")
+			}
+			if !tc.expectFileDeleted && statErr != nil {
+				t.Errorf("Expected app2.yaml to be kept, got %v", statErr)
+			}
+
+			// Check that archived files were moved rather than deleted
+			if tc.archiveDeleted {
+				archived, err := filepath.Glob(filepath.Join(archiveDir, "app2*.yaml"))
+				if err != nil {
+					t.Fatalf("Failed to list archive directory: %v", err)
+				}
+
+				expectedArchived := 1
+				if tc.archiveCollision {
+					expectedArchived = 2
+				}
+				if len(archived) != expectedArchived {
+					t.Fatalf("Expected %d archived files, got %v", expectedArchived, archived)
+				}
+
+				found := false
+				for _, path := range archived {
+					data, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatalf("Failed to read archived file: %v", err)
+					}
+					if string(data) == "name: App 2\nversion: 1.0.0" {
+						found = true
+						if tc.archiveCollision && filepath.Base(path) == "app2.yaml" {
+							t.Error("Expected the earlier archived file not to be overwritten")
+						}
+					}
+				}
+				if !found {
+					t.Error("Expected app2.yaml to be moved into the archive directory")
+				}
+			}
+
+			// Check the app map contents
+			var updatedAppMap AppMap
+			updatedAppMapData, err := os.ReadFile(appMapFile)
+			if err != nil {
+				t.Fatalf("Failed to read updated app map file: %v", err)
+			}
+
+			err = json.Unmarshal(updatedAppMapData, &updatedAppMap)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal updated app map: %v", err)
+			}
+
+			if len(updatedAppMap.Apps) != tc.expectedMapApps {
+				t.Errorf("Expected %d apps in updated app map, got %d", tc.expectedMapApps, len(updatedAppMap.Apps))
+			}
+
+			if len(updatedAppMap.Apps) > 0 && updatedAppMap.Apps[0].AppID != "app-id-1" {
+				t.Errorf("Expected app-id-1 to remain in app map, got %s", updatedAppMap.Apps[0].AppID)
+			}
+		})
+	}
+}
+
+func TestSyncAppExtensive(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-syncapp-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Create a test DSL directory
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.MkdirAll(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	// Create a test file (app1.yaml)
+	file1 := filepath.Join(dslDir, "app1.yaml")
+	if err := os.WriteFile(file1, []byte("name: App 1\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Set file modification time to a known value (1 day ago)
+	fileTime := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(file1, fileTime, fileTime); err != nil {
+		t.Fatalf("Failed to set file modification time: %v", err)
+	}
+
+	// Create test mapping
+	app1 := AppMapping{
+		Filename: "app1.yaml",
+		AppID:    "app-id-1",
+	}
+
+	// Create a mock server
+	var serverHandler func(w http.ResponseWriter, r *http.Request)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverHandler(w, r)
+	}))
+	defer server.Close()
+
+	// Basic test cases for SyncApp
+	testCases := []struct {
+		name           string
+		handler        func(w http.ResponseWriter, r *http.Request)
+		expectedAction SyncAction
+		expectedError  bool
+		dryRun         bool
+		verbose        bool
+	}{
+		{
+			name: "file_not_found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// This case won't hit the server as the file doesn't exist
+			},
+			expectedAction: ActionError,
+			expectedError:  true,
+		},
+		{
+			name: "app_check_error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error": "Internal server error"}`))
+			},
+			expectedAction: ActionError,
+			expectedError:  true,
+		},
+		{
+			name: "app_deleted",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return not found
+				w.WriteHeader(http.StatusNotFound)
+			},
+			expectedAction: ActionNone,
+			expectedError:  false,
+		},
+		{
+			name: "app_info_error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				// For app info, return error
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expectedAction: ActionError,
+			expectedError:  true,
+		},
+		{
+			name: "nil_updated_at",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": null
+						}
+					}`))
+					return
+				}
+			},
+			expectedAction: ActionNone,
+			expectedError:  false,
+		},
+		{
+			name: "empty_string_updated_at",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": ""
+						}
+					}`))
+					return
+				}
+			},
+			expectedAction: ActionNone,
+			expectedError:  false,
+		},
+		{
+			name: "remote_newer_download",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": "` + time.Now().Format(time.RFC3339) + `"
+						}
+					}`))
+					return
+				}
+				// For DSL export
+				if strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
+					return
+				}
+			},
+			expectedAction: ActionDownload,
+			expectedError:  false,
+		},
+		{
+			name: "remote_newer_dry_run",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": "` + time.Now().Format(time.RFC3339) + `"
+						}
+					}`))
+					return
+				}
+				// For DSL export
+				if strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
+					return
+				}
+			},
+			expectedAction: ActionDownload,
+			expectedError:  false,
+			dryRun:         true,
+		},
+		{
+			name: "dsl_error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": "` + time.Now().Format(time.RFC3339) + `"
+						}
+					}`))
+					return
+				}
+				// For DSL export, return error
+				if strings.Contains(r.URL.Path, "/export") {
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`{"error": "Internal server error"}`))
+					return
+				}
+			},
+			expectedAction: ActionDownload,
 			expectedError:  true,
 		},
 		{
-			name: "app_check_error",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(`{"error": "Internal server error"}`))
-			},
-			expectedAction: ActionError,
-			expectedError:  true,
+			name: "remote_older",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": "` + time.Now().Add(-48*time.Hour).Format(time.RFC3339) + `"
+						}
+					}`))
+					return
+				}
+			},
+			expectedAction: ActionNone,
+			expectedError:  false,
+		},
+		{
+			name: "integer_timestamp",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					// Use current timestamp (newer than file)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": ` + fmt.Sprintf("%d", time.Now().Unix()) + `
+						}
+					}`))
+					return
+				}
+				// For DSL export
+				if strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
+					return
+				}
+			},
+			expectedAction: ActionDownload,
+			expectedError:  false,
+		},
+		{
+			name: "float_timestamp",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					// Use current timestamp (newer than file)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": ` + fmt.Sprintf("%f", float64(time.Now().Unix())) + `
+						}
+					}`))
+					return
+				}
+				// For DSL export
+				if strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
+					return
+				}
+			},
+			expectedAction: ActionDownload,
+			expectedError:  false,
+		},
+		{
+			name: "unknown_type_timestamp",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/console/api/login" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"status": "success",
+						"data": {
+							"access_token": "test-token"
+						}
+					}`))
+					return
+				}
+				// For app check, return success
+				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					// Use an object as timestamp (should use default timestamp)
+					w.Write([]byte(`{
+						"data": {
+							"id": "app-id-1",
+							"name": "App 1",
+							"updated_at": {"some": "object"}
+						}
+					}`))
+					return
+				}
+			},
+			expectedAction: ActionNone,
+			expectedError:  false,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup handler
+			serverHandler = tc.handler
+
+			// Create a test file for each test case
+			testFile := filepath.Join(dslDir, app1.Filename)
+			if tc.name != "file_not_found" {
+				if err := os.WriteFile(testFile, []byte("name: App 1\nversion: 1.0.0"), 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+				if err := os.Chtimes(testFile, fileTime, fileTime); err != nil {
+					t.Fatalf("Failed to set file modification time: %v", err)
+				}
+			} else {
+				// For file_not_found case, remove the file if it exists
+				os.Remove(testFile)
+			}
+
+			// Create syncer with test configuration
+			config := Config{
+				DifyBaseURL:  server.URL,
+				DifyEmail:    "test@example.com",
+				DifyPassword: "testpassword",
+				DSLDirectory: dslDir,
+				DryRun:       tc.dryRun,
+				Verbose:      tc.verbose,
+			}
+			syncer := NewSyncer(config)
+
+			// Call SyncApp
+			result := syncer.SyncApp(app1)
+
+			// Check results
+			if result.Action != tc.expectedAction {
+				t.Errorf("Expected action %s, got %s", tc.expectedAction, result.Action)
+			}
+			if (result.Error != nil) != tc.expectedError {
+				t.Errorf("Expected error: %v, got error: %v", tc.expectedError, result.Error)
+			}
+		})
+	}
+}
+
+// TestSyncAllWithRenamedApps tests that files are renamed when remote app names change
+func TestSyncAllWithRenamedApps(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Create a test DSL directory
+	dslDir := filepath.Join(tmpDir, "dsl")
+	err = os.Mkdir(dslDir, 0755)
+	if err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+
+	// Create test DSL files with original names
+	dslContent := "name: Original App Name\nversion: 1.0.0"
+	oldFilename := "Original_App_Name.yaml"
+	oldFilePath := filepath.Join(dslDir, oldFilename)
+	err = os.WriteFile(oldFilePath, []byte(dslContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+
+	// Create test DSL file with Japanese name
+	jpContent := "name: 日本語アプリ\nversion: 1.0.0"
+	jpOldFilename := "日本語アプリ.yaml"
+	jpOldFilePath := filepath.Join(dslDir, jpOldFilename)
+	err = os.WriteFile(jpOldFilePath, []byte(jpContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write Japanese DSL file: %v", err)
+	}
+
+	// Create an app map file with the original filenames
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	appMap := AppMap{
+		Apps: []AppMapping{
+			{
+				Filename: oldFilename,
+				AppID:    "app-id-1",
+			},
+			{
+				Filename: jpOldFilename,
+				AppID:    "app-id-2",
+			},
+		},
+	}
+
+	appMapData, err := json.Marshal(appMap)
+	if err != nil {
+		t.Fatalf("Failed to marshal app map: %v", err)
+	}
+
+	err = os.WriteFile(appMapPath, appMapData, 0644)
+	if err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	// Create a mock server that returns changed app names
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/console/api/login" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"status": "success",
+				"data": {
+					"access_token": "test-token"
+				}
+			}`))
+			return
+		}
+
+		if r.URL.Path == "/console/api/apps" {
+			// Return the app list with changed names
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": [
+					{
+						"id": "app-id-1",
+						"name": "Changed App Name",
+						"updated_at": 1672531200
+					},
+					{
+						"id": "app-id-2",
+						"name": "変更された日本語アプリ",
+						"updated_at": 1672617600
+					}
+				]
+			}`))
+			return
+		}
+
+		if r.URL.Path == "/console/api/apps/app-id-1" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": {
+					"id": "app-id-1",
+					"name": "Changed App Name",
+					"updated_at": 1672531200
+				}
+			}`))
+			return
+		}
+
+		if r.URL.Path == "/console/api/apps/app-id-2" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": {
+					"id": "app-id-2",
+					"name": "変更された日本語アプリ",
+					"updated_at": 1672617600
+				}
+			}`))
+			return
+		}
+
+		if r.URL.Path == "/console/api/apps/app-id-1/export" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": "name: Changed App Name\nversion: 1.0.0"
+			}`))
+			return
+		}
+
+		if r.URL.Path == "/console/api/apps/app-id-2/export" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"data": "name: 変更された日本語アプリ\nversion: 1.0.0"
+			}`))
+			return
+		}
+
+		// Handle checks for app existence
+		if r.URL.Path == "/console/api/apps/app-id-1/check" || r.URL.Path == "/console/api/apps/app-id-2/check" {
+			// All apps exist in this test
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"exists": true}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	// Create syncer with test configuration
+	config := Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+		Verbose:      true, // For better debugging in tests
+	}
+	syncer := NewSyncer(config)
+
+	// Run SyncAll
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+
+	// Check that files were renamed
+	expectedNewFilename := "Changed_App_Name.yaml"
+	expectedJpNewFilename := "変更された日本語アプリ.yaml"
+
+	// Check that the old files no longer exist
+	if _, err := os.Stat(oldFilePath); !os.IsNotExist(err) {
+		t.Errorf("Expected old file %s to be renamed/removed", oldFilename)
+	}
+	if _, err := os.Stat(jpOldFilePath); !os.IsNotExist(err) {
+		t.Errorf("Expected old file %s to be renamed/removed", jpOldFilename)
+	}
+
+	// Check that the new files exist
+	newFilePath := filepath.Join(dslDir, expectedNewFilename)
+	if _, err := os.Stat(newFilePath); os.IsNotExist(err) {
+		t.Errorf("Expected new file %s to exist", expectedNewFilename)
+	}
+	jpNewFilePath := filepath.Join(dslDir, expectedJpNewFilename)
+	if _, err := os.Stat(jpNewFilePath); os.IsNotExist(err) {
+		t.Errorf("Expected new file %s to exist", expectedJpNewFilename)
+	}
+
+	// Check the app map has been updated
+	updatedAppMap, err := syncer.LoadAppMap()
+	if err != nil {
+		t.Fatalf("Failed to load updated app map: %v", err)
+	}
+
+	// Check app map contains the new filenames
+	foundUpdatedEn := false
+	foundUpdatedJp := false
+	for _, app := range updatedAppMap.Apps {
+		if app.AppID == "app-id-1" {
+			if app.Filename != expectedNewFilename {
+				t.Errorf("Expected app id app-id-1 to have filename %s, got %s",
+					expectedNewFilename, app.Filename)
+			}
+			foundUpdatedEn = true
+		}
+		if app.AppID == "app-id-2" {
+			if app.Filename != expectedJpNewFilename {
+				t.Errorf("Expected app id app-id-2 to have filename %s, got %s",
+					expectedJpNewFilename, app.Filename)
+			}
+			foundUpdatedJp = true
+		}
+	}
+
+	if !foundUpdatedEn {
+		t.Errorf("English app mapping not found in updated app map")
+	}
+	if !foundUpdatedJp {
+		t.Errorf("Japanese app mapping not found in updated app map")
+	}
+
+	// Check statistics
+	if stats.Total != 2 {
+		t.Errorf("Expected Total to be 2, got %d", stats.Total)
+	}
+
+	if stats.Renamed != 2 {
+		t.Errorf("Expected Renamed to be 2, got %d", stats.Renamed)
+	}
+}
+
+func TestInitializeAppMapMaxApps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "app-1", "name": "One"}, {"id": "app-2", "name": "Two"}, {"id": "app-3", "name": "Three"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		maxApps     int
+		confirm     func(count int) bool
+		expectError bool
+	}{
+		{
+			name:    "unlimited",
+			maxApps: 0,
+		},
+		{
+			name:    "within limit",
+			maxApps: 3,
 		},
 		{
-			name: "app_deleted",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
-				}
-				// For app check, return not found
-				w.WriteHeader(http.StatusNotFound)
-			},
-			expectedAction: ActionNone,
-			expectedError:  false,
+			name:        "exceeds limit without confirmation",
+			maxApps:     2,
+			expectError: true,
 		},
 		{
-			name: "app_info_error",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
-				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.WriteHeader(http.StatusOK)
-					return
-				}
-				// For app info, return error
-				w.WriteHeader(http.StatusInternalServerError)
-			},
-			expectedAction: ActionError,
-			expectedError:  true,
+			name:        "exceeds limit and declined",
+			maxApps:     2,
+			confirm:     func(count int) bool { return false },
+			expectError: true,
 		},
 		{
-			name: "nil_updated_at",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
+			name:    "exceeds limit and confirmed",
+			maxApps: 2,
+			confirm: func(count int) bool { return count == 3 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := os.MkdirTemp("", "difync-test-")
+			if err != nil {
+				t.Fatalf("Failed to create temp directory: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			syncer := NewSyncer(Config{
+				DifyBaseURL:    server.URL,
+				DifyEmail:      "test@example.com",
+				DifyPassword:   "testpassword",
+				DSLDirectory:   filepath.Join(tmpDir, "dsl"),
+				AppMapFile:     filepath.Join(tmpDir, "app_map.json"),
+				MaxApps:        tt.maxApps,
+				ConfirmMaxApps: tt.confirm,
+			})
+
+			appMap, err := syncer.(*DefaultSyncer).InitializeAppMap()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error when app count exceeds --max-apps")
 				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": null
-						}
-					}`))
-					return
+				if _, statErr := os.Stat(filepath.Join(tmpDir, "dsl")); !os.IsNotExist(statErr) {
+					t.Error("Expected no DSL directory to be created when init is aborted")
 				}
-			},
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(appMap.Apps) != 3 {
+				t.Errorf("Expected 3 apps, got %d", len(appMap.Apps))
+			}
+		})
+	}
+}
+
+func TestSyncAppClockSkewTolerance(t *testing.T) {
+	// The test server reports the app as updated at this time
+	remoteTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		localAge       time.Duration // how much older the local file is than the remote app
+		tolerance      time.Duration
+		expectedAction SyncAction
+	}{
+		{
+			name:           "within default tolerance",
+			localAge:       1 * time.Second,
 			expectedAction: ActionNone,
-			expectedError:  false,
 		},
 		{
-			name: "empty_string_updated_at",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
-				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": ""
-						}
-					}`))
-					return
-				}
-			},
+			name:           "at default tolerance boundary",
+			localAge:       2 * time.Second,
 			expectedAction: ActionNone,
-			expectedError:  false,
 		},
 		{
-			name: "remote_newer_download",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
-				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": "` + time.Now().Format(time.RFC3339) + `"
-						}
-					}`))
-					return
-				}
-				// For DSL export
-				if strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
-					return
-				}
-			},
+			name:           "beyond default tolerance",
+			localAge:       3 * time.Second,
 			expectedAction: ActionDownload,
-			expectedError:  false,
 		},
 		{
-			name: "remote_newer_dry_run",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
+			name:           "at custom tolerance boundary",
+			localAge:       10 * time.Second,
+			tolerance:      10 * time.Second,
+			expectedAction: ActionNone,
+		},
+		{
+			name:           "beyond custom tolerance",
+			localAge:       10*time.Second + time.Millisecond,
+			tolerance:      10 * time.Second,
+			expectedAction: ActionDownload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, server, _, dslPath, _, cleanup := setupTestSyncerAndServer(t)
+			defer server.Close()
+			defer cleanup()
+
+			s.(*DefaultSyncer).config.ClockSkewTolerance = tt.tolerance
+
+			localTime := remoteTime.Add(-tt.localAge)
+			if err := os.Chtimes(dslPath, localTime, localTime); err != nil {
+				t.Fatalf("Failed to change file time: %v", err)
+			}
+
+			result := s.SyncApp(AppMapping{Filename: "test.yaml", AppID: "test-app-id"})
+			if result.Action != tt.expectedAction {
+				t.Errorf("Expected action %s, got %s (error: %v)", tt.expectedAction, result.Action, result.Error)
+			}
+		})
+	}
+}
+
+func TestWarnClockSkew(t *testing.T) {
+	syncer := &DefaultSyncer{}
+
+	// Remote timestamps in the past are not skew
+	syncer.recordRemoteTimestamp(time.Now().Add(-1 * time.Hour))
+	if syncer.timestampsAhead != 0 {
+		t.Errorf("Expected no timestamps ahead, got %d", syncer.timestampsAhead)
+	}
+
+	// A timestamp just within the tolerance is not skew
+	syncer.recordRemoteTimestamp(time.Now().Add(1 * time.Second))
+	if syncer.timestampsAhead != 0 {
+		t.Errorf("Expected no timestamps ahead, got %d", syncer.timestampsAhead)
+	}
+
+	// A timestamp far in the future is skew
+	syncer.recordRemoteTimestamp(time.Now().Add(1 * time.Hour))
+	if syncer.timestampsAhead != 1 {
+		t.Errorf("Expected 1 timestamp ahead, got %d", syncer.timestampsAhead)
+	}
+	if syncer.timestampsCompared != 3 {
+		t.Errorf("Expected 3 timestamps compared, got %d", syncer.timestampsCompared)
+	}
+}
+
+func TestSyncAllWithAppOverrides(t *testing.T) {
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	originalContent := "name: Local Copy"
+
+	tests := []struct {
+		name              string
+		mapping           string
+		remoteDeleted     bool
+		expectedDownloads int
+		expectedSkipped   int
+		expectedDeleted   int
+		expectedRequests  bool // whether the app itself is requested from Dify
+	}{
+		{
+			name:              "default",
+			mapping:           `{"filename": "Test_App.yaml", "app_id": "test-app-id"}`,
+			expectedDownloads: 1,
+			expectedRequests:  true,
+		},
+		{
+			name:            "skip",
+			mapping:         `{"filename": "Test_App.yaml", "app_id": "test-app-id", "skip": true}`,
+			expectedSkipped: 1,
+		},
+		{
+			name:             "read_only",
+			mapping:          `{"filename": "Test_App.yaml", "app_id": "test-app-id", "read_only": true}`,
+			expectedRequests: true,
+		},
+		{
+			name:             "read_only_not_renamed",
+			mapping:          `{"filename": "shared_template.yaml", "app_id": "test-app-id", "read_only": true}`,
+			expectedRequests: true,
+		},
+		{
+			name:             "read_only_deleted_remotely",
+			mapping:          `{"filename": "Test_App.yaml", "app_id": "test-app-id", "read_only": true}`,
+			remoteDeleted:    true,
+			expectedRequests: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := os.MkdirTemp("", "difync-test-")
+			if err != nil {
+				t.Fatalf("Failed to create temp directory: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			dslDir := filepath.Join(tmpDir, "dsl")
+			if err := os.Mkdir(dslDir, 0755); err != nil {
+				t.Fatalf("Failed to create DSL directory: %v", err)
+			}
+
+			var mapping AppMapping
+			if err := json.Unmarshal([]byte(tt.mapping), &mapping); err != nil {
+				t.Fatalf("Failed to parse mapping: %v", err)
+			}
+
+			localPath := filepath.Join(dslDir, mapping.Filename)
+			if err := os.WriteFile(localPath, []byte(originalContent), 0644); err != nil {
+				t.Fatalf("Failed to write DSL file: %v", err)
+			}
+			if err := os.Chtimes(localPath, oldTime, oldTime); err != nil {
+				t.Fatalf("Failed to change file time: %v", err)
+			}
+
+			appMapPath := filepath.Join(tmpDir, "app_map.json")
+			if err := os.WriteFile(appMapPath, []byte(`{"apps": [`+tt.mapping+`]}`), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			appRequested := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/console/api/login":
+					w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+				case "/console/api/apps":
+					w.Write([]byte(`{"data": [{"id": "test-app-id", "name": "Test App"}]}`))
+				case "/console/api/apps/test-app-id":
+					appRequested = true
+					if tt.remoteDeleted {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Write([]byte(`{"data": {"id": "test-app-id", "name": "Test App", "updated_at": "2023-01-01T12:00:00Z"}}`))
+				case "/console/api/apps/test-app-id/export":
+					w.Write([]byte(`{"data": "name: Remote Copy"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			syncer := NewSyncer(Config{
+				DifyBaseURL:   server.URL,
+				DifyEmail:     "test@example.com",
+				DifyPassword:  "testpassword",
+				DSLDirectory:  dslDir,
+				AppMapFile:    appMapPath,
+				DeleteOrphans: true,
+			})
+
+			stats, err := syncer.SyncAll()
+			if err != nil {
+				t.Fatalf("SyncAll failed: %v", err)
+			}
+
+			if stats.Downloads != tt.expectedDownloads {
+				t.Errorf("Expected %d downloads, got %d", tt.expectedDownloads, stats.Downloads)
+			}
+			if stats.Skipped != tt.expectedSkipped {
+				t.Errorf("Expected %d skipped, got %d", tt.expectedSkipped, stats.Skipped)
+			}
+			if stats.Deleted != tt.expectedDeleted {
+				t.Errorf("Expected %d deleted, got %d", tt.expectedDeleted, stats.Deleted)
+			}
+			if appRequested != tt.expectedRequests {
+				t.Errorf("Expected app requested to be %v, got %v", tt.expectedRequests, appRequested)
+			}
+
+			// Only the default mapping may change the local file
+			data, err := os.ReadFile(localPath)
+			if err != nil {
+				t.Fatalf("Expected local file to remain at %s: %v", localPath, err)
+			}
+			if tt.expectedDownloads == 0 && string(data) != originalContent {
+				t.Errorf("Expected local file to be untouched, got %q", string(data))
+			}
+
+			// The overrides must survive in the app map
+			appMap, err := syncer.LoadAppMap()
+			if err != nil {
+				t.Fatalf("Failed to load app map: %v", err)
+			}
+			if len(appMap.Apps) != 1 {
+				t.Fatalf("Expected 1 app in app map, got %d", len(appMap.Apps))
+			}
+			if appMap.Apps[0].Skip != mapping.Skip || appMap.Apps[0].ReadOnly != mapping.ReadOnly {
+				t.Errorf("Expected overrides to be kept, got %+v", appMap.Apps[0])
+			}
+		})
+	}
+}
+
+func TestAppMappingOverridesOmitEmpty(t *testing.T) {
+	data, err := json.Marshal(AppMapping{Filename: "app.yaml", AppID: "app-id"})
+	if err != nil {
+		t.Fatalf("Failed to marshal app mapping: %v", err)
+	}
+	if strings.Contains(string(data), "skip") || strings.Contains(string(data), "read_only") {
+		t.Errorf("Expected overrides to be omitted when false, got %s", data)
+	}
+}
+
+func TestSyncAllWithoutAppListPermission(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "difync-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The account may read its app but not list the workspace
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code": "forbidden"}`))
+		case "/console/api/apps/test-app-id":
+			w.Write([]byte(`{"id": "test-app-id", "name": "Renamed App", "updated_at": "2023-01-01T12:00:00Z"}`))
+		case "/console/api/apps/test-app-id/export":
+			w.Write([]byte(`{"data": "name: Renamed App\nversion: 2.0.0"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dslDir := filepath.Join(tmpDir, "dsl")
+	if err := os.Mkdir(dslDir, 0755); err != nil {
+		t.Fatalf("Failed to create DSL directory: %v", err)
+	}
+	dslPath := filepath.Join(dslDir, "test.yaml")
+	if err := os.WriteFile(dslPath, []byte("name: Test App\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+	oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(dslPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	appMapPath := filepath.Join(tmpDir, "app_map.json")
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "test.yaml", "app_id": "test-app-id"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map file: %v", err)
+	}
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: dslDir,
+		AppMapFile:   appMapPath,
+	})
+
+	stats, err := syncer.SyncAll()
+	if err != nil {
+		t.Fatalf("Expected sync to fall back to the app map, got %v", err)
+	}
+
+	if stats.Downloads != 1 {
+		t.Errorf("Expected 1 download, got %d", stats.Downloads)
+	}
+	if stats.Renamed != 0 {
+		t.Errorf("Expected rename detection to be disabled, got %d renames", stats.Renamed)
+	}
+	if stats.Errors != 0 {
+		t.Errorf("Expected no errors, got %d", stats.Errors)
+	}
+
+	content, err := os.ReadFile(dslPath)
+	if err != nil {
+		t.Fatalf("Expected the file to keep its name: %v", err)
+	}
+	if string(content) != "name: Renamed App\nversion: 2.0.0" {
+		t.Errorf("Expected downloaded content, got %q", content)
+	}
+}
+
+func TestSyncAllFailFast(t *testing.T) {
+	// Both apps have remote changes, but exporting the first one fails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "A"}, {"id": "app-b", "name": "B"}]}`))
+		case "/console/api/apps/app-a":
+			w.Write([]byte(`{"data": {"id": "app-a", "name": "A", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		case "/console/api/apps/app-b":
+			w.Write([]byte(`{"data": {"id": "app-b", "name": "B", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		case "/console/api/apps/app-a/export":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/console/api/apps/app-b/export":
+			w.Write([]byte(`{"data": "name: B"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		failFast        bool
+		expectError     bool
+		expectedResults int
+		expectB         bool
+	}{
+		{
+			name:            "continues after errors by default",
+			expectedResults: 2,
+			expectB:         true,
+		},
+		{
+			name:            "fail fast stops at the first error",
+			failFast:        true,
+			expectError:     true,
+			expectedResults: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := os.MkdirTemp("", "difync-test-")
+			if err != nil {
+				t.Fatalf("Failed to create temp directory: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			dslDir := filepath.Join(tmpDir, "dsl")
+			if err := os.Mkdir(dslDir, 0755); err != nil {
+				t.Fatalf("Failed to create DSL directory: %v", err)
+			}
+			oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			for _, name := range []string{"A.yaml", "B.yaml"} {
+				path := filepath.Join(dslDir, name)
+				if err := os.WriteFile(path, []byte("name: old"), 0644); err != nil {
+					t.Fatalf("Failed to write DSL file: %v", err)
+				}
+				if err := os.Chtimes(path, oldTime, oldTime); err != nil {
+					t.Fatalf("Failed to set file time: %v", err)
+				}
+			}
+
+			appMapPath := filepath.Join(tmpDir, "app_map.json")
+			content := `{"apps": [{"filename": "A.yaml", "app_id": "app-a"}, {"filename": "B.yaml", "app_id": "app-b"}]}`
+			if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			syncer := NewSyncer(Config{
+				DifyBaseURL:  server.URL,
+				DifyEmail:    "test@example.com",
+				DifyPassword: "testpassword",
+				DSLDirectory: dslDir,
+				AppMapFile:   appMapPath,
+				FailFast:     tt.failFast,
+			})
+
+			stats, err := syncer.SyncAll()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			// Stats up to the failure are returned either way
+			if stats == nil {
+				t.Fatal("Expected stats to be returned")
+			}
+			if len(stats.Results) != tt.expectedResults {
+				t.Fatalf("Expected %d results, got %d", tt.expectedResults, len(stats.Results))
+			}
+			if stats.Results[0].AppID != "app-a" || stats.Results[0].Success {
+				t.Errorf("Expected the first result to be the failed app-a, got %+v", stats.Results[0])
+			}
+			if err := stats.Err(); err == nil || !strings.Contains(err.Error(), "A.yaml (app_id: app-a)") {
+				t.Errorf("Expected aggregated error to mention A.yaml, got %v", err)
+			}
+
+			// Only the default run goes on to download app-b
+			data, err := os.ReadFile(filepath.Join(dslDir, "B.yaml"))
+			if err != nil {
+				t.Fatalf("Failed to read DSL file: %v", err)
+			}
+			if downloaded := string(data) == "name: B"; downloaded != tt.expectB {
+				t.Errorf("Expected B.yaml downloaded to be %v, got data %q", tt.expectB, data)
+			}
+		})
+	}
+}
+
+func TestSyncAllExportForbidden(t *testing.T) {
+	// Both apps can be seen, but app-a may not be exported
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps":
+			w.Write([]byte(`{"data": [{"id": "app-a", "name": "A"}, {"id": "app-b", "name": "B"}]}`))
+		case "/console/api/apps/app-a":
+			w.Write([]byte(`{"data": {"id": "app-a", "name": "A", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		case "/console/api/apps/app-b":
+			w.Write([]byte(`{"data": {"id": "app-b", "name": "B", "updated_at": "2023-01-01T12:00:00Z"}}`))
+		case "/console/api/apps/app-a/export":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code": "forbidden", "message": "no permission"}`))
+		case "/console/api/apps/app-b/export":
+			w.Write([]byte(`{"data": "name: B"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	for _, skipForbidden := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip_forbidden=%v", skipForbidden), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			dslDir := filepath.Join(tmpDir, "dsl")
+			if err := os.Mkdir(dslDir, 0755); err != nil {
+				t.Fatalf("Failed to create DSL directory: %v", err)
+			}
+			oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			for _, name := range []string{"A.yaml", "B.yaml"} {
+				path := filepath.Join(dslDir, name)
+				if err := os.WriteFile(path, []byte("name: old"), 0644); err != nil {
+					t.Fatalf("Failed to write DSL file: %v", err)
 				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": "` + time.Now().Format(time.RFC3339) + `"
-						}
-					}`))
-					return
+				if err := os.Chtimes(path, oldTime, oldTime); err != nil {
+					t.Fatalf("Failed to set file time: %v", err)
 				}
-				// For DSL export
-				if strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
-					return
+			}
+
+			appMapPath := filepath.Join(tmpDir, "app_map.json")
+			content := `{"apps": [{"filename": "A.yaml", "app_id": "app-a"}, {"filename": "B.yaml", "app_id": "app-b"}]}`
+			if err := os.WriteFile(appMapPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+
+			var logs bytes.Buffer
+			syncer := NewSyncer(Config{
+				DifyBaseURL:   server.URL,
+				DifyEmail:     "test@example.com",
+				DifyPassword:  "testpassword",
+				DSLDirectory:  dslDir,
+				AppMapFile:    appMapPath,
+				SkipForbidden: skipForbidden,
+				LogOutput:     &logs,
+			})
+
+			var stats *SyncStats
+			var err error
+			output := captureStdout(t, func() {
+				stats, err = syncer.SyncAll()
+			})
+			if err != nil {
+				t.Fatalf("SyncAll failed: %v", err)
+			}
+
+			// The warning goes to the log output, not stdout
+			warning := "Warning: No export permission for app A.yaml"
+			if !strings.Contains(logs.String(), warning) || strings.Contains(output, warning) {
+				t.Errorf("Expected the warning in the log output only, got log %q and stdout %q", logs.String(), output)
+			}
+			if len(stats.Results) != 2 {
+				t.Fatalf("Expected 2 results, got %d", len(stats.Results))
+			}
+
+			result := stats.Results[0]
+			if skipForbidden {
+				if result.Action != ActionSkip || !result.Success || result.Error != nil {
+					t.Errorf("Expected app-a to be skipped, got %+v", result)
 				}
-			},
-			expectedAction: ActionDownload,
-			expectedError:  false,
-			dryRun:         true,
-		},
-		{
-			name: "dsl_error",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
+				if stats.Skipped != 1 || stats.Downloads != 1 {
+					t.Errorf("Expected 1 skipped and 1 download, got %+v", stats)
 				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": "` + time.Now().Format(time.RFC3339) + `"
-						}
-					}`))
-					return
+				if err := stats.Err(); err != nil {
+					t.Errorf("Expected no failed apps, got %v", err)
 				}
-				// For DSL export, return error
-				if strings.Contains(r.URL.Path, "/export") {
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write([]byte(`{"error": "Internal server error"}`))
-					return
+			} else {
+				if result.Success || !errors.Is(result.Error, ErrNoExportPermission) {
+					t.Errorf("Expected app-a to fail with ErrNoExportPermission, got %+v", result)
 				}
-			},
-			expectedAction: ActionDownload,
-			expectedError:  true,
-		},
-		{
-			name: "remote_older",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
+				if !api.IsForbidden(result.Error) {
+					t.Errorf("Expected the 403 API error to be kept, got %v", result.Error)
 				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": "` + time.Now().Add(-48*time.Hour).Format(time.RFC3339) + `"
-						}
-					}`))
-					return
+				if stats.Skipped != 0 {
+					t.Errorf("Expected nothing skipped, got %d", stats.Skipped)
 				}
-			},
-			expectedAction: ActionNone,
-			expectedError:  false,
-		},
-		{
-			name: "integer_timestamp",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
+				// The failed export is an error, not a download, so the sync exits non-zero
+				if stats.Errors != 1 || stats.Downloads != 1 {
+					t.Errorf("Expected 1 error and 1 download, got %+v", stats)
 				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					// Use current timestamp (newer than file)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": ` + fmt.Sprintf("%d", time.Now().Unix()) + `
-						}
-					}`))
-					return
+				if err := stats.Err(); err == nil {
+					t.Error("Expected the failed app to be reported")
 				}
-				// For DSL export
-				if strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
-					return
+			}
+
+			// The forbidden app is left alone and the other app is still downloaded
+			if data, _ := os.ReadFile(filepath.Join(dslDir, "A.yaml")); string(data) != "name: old" {
+				t.Errorf("Expected A.yaml to be untouched, got %q", data)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dslDir, "B.yaml")); string(data) != "name: B" {
+				t.Errorf("Expected B.yaml to be downloaded, got %q", data)
+			}
+		})
+	}
+}
+
+func TestSyncAllCreatesMissingDSLDirectory(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry_run=%v", dryRun), func(t *testing.T) {
+			syncer, server, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+			defer server.Close()
+			defer cleanup()
+
+			// The app map exists but the DSL directory has not been created yet
+			if err := os.RemoveAll(dslDir); err != nil {
+				t.Fatalf("Failed to remove DSL directory: %v", err)
+			}
+			appMapData := `{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`
+			if err := os.WriteFile(appMapPath, []byte(appMapData), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
+			}
+			syncer.(*DefaultSyncer).config.DryRun = dryRun
+
+			stats, err := syncer.SyncAll()
+			if err != nil {
+				t.Fatalf("SyncAll failed: %v", err)
+			}
+			if stats.Errors != 0 {
+				t.Errorf("Expected no errors, got %d: %+v", stats.Errors, stats.Results)
+			}
+			if stats.Downloads != 1 {
+				t.Errorf("Expected 1 download, got %d", stats.Downloads)
+			}
+
+			_, statErr := os.Stat(filepath.Join(dslDir, "Test_App.yaml"))
+			if dryRun {
+				if _, err := os.Stat(dslDir); !os.IsNotExist(err) {
+					t.Error("Expected DSL directory not to be created in dry run mode")
 				}
-			},
-			expectedAction: ActionDownload,
-			expectedError:  false,
-		},
+			} else if statErr != nil {
+				t.Errorf("Expected DSL file to be downloaded into the new directory: %v", statErr)
+			}
+		})
+	}
+}
+
+func TestLoadAppMapDuplicates(t *testing.T) {
+	tests := []struct {
+		name          string
+		appMap        string
+		errorContains string
+		expectedApps  []AppMapping
+	}{
 		{
-			name: "float_timestamp",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
-				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					// Use current timestamp (newer than file)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": ` + fmt.Sprintf("%f", float64(time.Now().Unix())) + `
-						}
-					}`))
-					return
-				}
-				// For DSL export
-				if strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{"data": "name: Updated App 1\nversion: 1.1.0"}`))
-					return
-				}
+			name: "duplicate app IDs",
+			appMap: `{"apps": [
+				{"filename": "first.yaml", "app_id": "app-id-1"},
+				{"filename": "second.yaml", "app_id": "app-id-1"},
+				{"filename": "other.yaml", "app_id": "app-id-2"}
+			]}`,
+			errorContains: "app_id app-id-1 is mapped to both first.yaml and second.yaml",
+			expectedApps: []AppMapping{
+				{Filename: "first.yaml", AppID: "app-id-1"},
+				{Filename: "other.yaml", AppID: "app-id-2"},
 			},
-			expectedAction: ActionDownload,
-			expectedError:  false,
 		},
 		{
-			name: "unknown_type_timestamp",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/console/api/login" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte(`{
-						"status": "success",
-						"data": {
-							"access_token": "test-token"
-						}
-					}`))
-					return
-				}
-				// For app check, return success
-				if strings.Contains(r.URL.Path, "app-id-1") && !strings.Contains(r.URL.Path, "/export") {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					// Use an object as timestamp (should use default timestamp)
-					w.Write([]byte(`{
-						"data": {
-							"id": "app-id-1",
-							"name": "App 1",
-							"updated_at": {"some": "object"}
-						}
-					}`))
-					return
-				}
+			name: "duplicate filenames",
+			appMap: `{"apps": [
+				{"filename": "shared.yaml", "app_id": "app-id-1"},
+				{"filename": "shared.yaml", "app_id": "app-id-2"}
+			]}`,
+			errorContains: "filename shared.yaml is used by both app-id-1 and app-id-2",
+			expectedApps: []AppMapping{
+				{Filename: "shared.yaml", AppID: "app-id-1"},
 			},
-			expectedAction: ActionNone,
-			expectedError:  false,
 		},
 	}
 
-	// Run test cases
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			// Setup handler
-			serverHandler = tc.handler
-
-			// Create a test file for each test case
-			testFile := filepath.Join(dslDir, app1.Filename)
-			if tc.name != "file_not_found" {
-				if err := os.WriteFile(testFile, []byte("name: App 1\nversion: 1.0.0"), 0644); err != nil {
-					t.Fatalf("Failed to create test file: %v", err)
-				}
-				if err := os.Chtimes(testFile, fileTime, fileTime); err != nil {
-					t.Fatalf("Failed to set file modification time: %v", err)
-				}
-			} else {
-				// For file_not_found case, remove the file if it exists
-				os.Remove(testFile)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := os.MkdirTemp("", "difync-test-")
+			if err != nil {
+				t.Fatalf("Failed to create temp directory: %v", err)
 			}
+			defer os.RemoveAll(tmpDir)
 
-			// Create syncer with test configuration
-			config := Config{
-				DifyBaseURL:  server.URL,
-				DifyEmail:    "test@example.com",
-				DifyPassword: "testpassword",
-				DSLDirectory: dslDir,
-				DryRun:       tc.dryRun,
-				Verbose:      tc.verbose,
+			appMapPath := filepath.Join(tmpDir, "app_map.json")
+			if err := os.WriteFile(appMapPath, []byte(tt.appMap), 0644); err != nil {
+				t.Fatalf("Failed to write app map file: %v", err)
 			}
-			syncer := NewSyncer(config)
-
-			// Call SyncApp
-			result := syncer.SyncApp(app1)
 
-			// Check results
-			if result.Action != tc.expectedAction {
-				t.Errorf("Expected action %s, got %s", tc.expectedAction, result.Action)
+			// Without --dedupe the duplicates are reported as an error
+			syncer := &DefaultSyncer{config: Config{AppMapFile: appMapPath}}
+			_, err = syncer.LoadAppMap()
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorContains, err)
 			}
-			if (result.Error != nil) != tc.expectedError {
-				t.Errorf("Expected error: %v, got error: %v", tc.expectedError, result.Error)
+
+			// With --dedupe the first entry wins
+			syncer.config.Dedupe = true
+			appMap, err := syncer.LoadAppMap()
+			if err != nil {
+				t.Fatalf("Expected no error with dedupe, got %v", err)
+			}
+			if !reflect.DeepEqual(appMap.Apps, tt.expectedApps) {
+				t.Errorf("Expected apps %+v, got %+v", tt.expectedApps, appMap.Apps)
 			}
 		})
 	}
 }
 
-// TestSyncAllWithRenamedApps tests that files are renamed when remote app names change
-func TestSyncAllWithRenamedApps(t *testing.T) {
-	// Create a temporary directory for testing
-	tmpDir, err := os.MkdirTemp("", "difync-test-")
-	if err != nil {
-		t.Fatalf("Failed to create temp directory: %v", err)
+func TestSyncAllSkipUnchangedContent(t *testing.T) {
+	tests := []struct {
+		name              string
+		skipUnchanged     bool
+		expectedDownloads int
+		expectedNoAction  int
+		expectTouched     bool
+	}{
+		{
+			name:              "downloads when the remote is newer",
+			expectedDownloads: 1,
+			expectTouched:     true,
+		},
+		{
+			name:             "skips identical content",
+			skipUnchanged:    true,
+			expectedNoAction: 1,
+		},
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Create a test DSL directory
-	dslDir := filepath.Join(tmpDir, "dsl")
-	err = os.Mkdir(dslDir, 0755)
-	if err != nil {
-		t.Fatalf("Failed to create DSL directory: %v", err)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+			defer cleanup()
 
-	// Create test DSL files with original names
-	dslContent := "name: Original App Name\nversion: 1.0.0"
-	oldFilename := "Original_App_Name.yaml"
-	oldFilePath := filepath.Join(dslDir, oldFilename)
-	err = os.WriteFile(oldFilePath, []byte(dslContent), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write DSL file: %v", err)
-	}
+			// The local file already has the remote content but is older than the remote app
+			renamedPath := filepath.Join(dslDir, "Test_App.yaml")
+			if err := os.Rename(dslPath, renamedPath); err != nil {
+				t.Fatalf("Failed to rename DSL file: %v", err)
+			}
+			if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id"}]}`), 0644); err != nil {
+				t.Fatalf("Failed to write app map: %v", err)
+			}
+			oldTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			if err := os.Chtimes(renamedPath, oldTime, oldTime); err != nil {
+				t.Fatalf("Failed to set file time: %v", err)
+			}
 
-	// Create test DSL file with Japanese name
-	jpContent := "name: 日本語アプリ\nversion: 1.0.0"
-	jpOldFilename := "日本語アプリ.yaml"
-	jpOldFilePath := filepath.Join(dslDir, jpOldFilename)
-	err = os.WriteFile(jpOldFilePath, []byte(jpContent), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write Japanese DSL file: %v", err)
-	}
+			syncer.(*DefaultSyncer).config.SkipUnchanged = tt.skipUnchanged
 
-	// Create an app map file with the original filenames
-	appMapPath := filepath.Join(tmpDir, "app_map.json")
-	appMap := AppMap{
-		Apps: []AppMapping{
-			{
-				Filename: oldFilename,
-				AppID:    "app-id-1",
-			},
-			{
-				Filename: jpOldFilename,
-				AppID:    "app-id-2",
-			},
-		},
-	}
+			stats, err := syncer.SyncAll()
+			if err != nil {
+				t.Fatalf("Failed to sync all: %v", err)
+			}
+			if stats.Downloads != tt.expectedDownloads || stats.NoAction != tt.expectedNoAction {
+				t.Errorf("Expected %d downloads and %d in sync, got %d and %d",
+					tt.expectedDownloads, tt.expectedNoAction, stats.Downloads, stats.NoAction)
+			}
 
-	appMapData, err := json.Marshal(appMap)
-	if err != nil {
-		t.Fatalf("Failed to marshal app map: %v", err)
+			info, err := os.Stat(renamedPath)
+			if err != nil {
+				t.Fatalf("Failed to stat DSL file: %v", err)
+			}
+			if touched := !info.ModTime().Equal(oldTime); touched != tt.expectTouched {
+				t.Errorf("Expected file modified to be %v, got mtime %v", tt.expectTouched, info.ModTime())
+			}
+		})
 	}
+}
 
-	err = os.WriteFile(appMapPath, appMapData, 0644)
-	if err != nil {
-		t.Fatalf("Failed to write app map file: %v", err)
+func TestSyncAllTouchOnSync(t *testing.T) {
+	remoteTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		touchOnSync   bool
+		skipUnchanged bool
+		localTime     time.Time
+		readOnly      bool
+		expectedTime  time.Time
+	}{
+		{
+			name:         "in sync within the clock skew tolerance",
+			touchOnSync:  true,
+			localTime:    remoteTime.Add(-time.Second),
+			expectedTime: remoteTime,
+		},
+		{
+			name:          "identical content of a newer remote",
+			touchOnSync:   true,
+			skipUnchanged: true,
+			localTime:     time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedTime:  remoteTime,
+		},
+		{
+			name:         "disabled",
+			localTime:    remoteTime.Add(-time.Second),
+			expectedTime: remoteTime.Add(-time.Second),
+		},
+		{
+			name:         "local file newer than the remote",
+			touchOnSync:  true,
+			localTime:    remoteTime.Add(time.Hour),
+			expectedTime: remoteTime.Add(time.Hour),
+		},
+		{
+			name:         "read-only app with remote changes",
+			touchOnSync:  true,
+			readOnly:     true,
+			localTime:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedTime: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
 	}
 
-	// Create a mock server that returns changed app names
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/console/api/login" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"status": "success",
-				"data": {
-					"access_token": "test-token"
-				}
-			}`))
-			return
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+			defer cleanup()
 
-		if r.URL.Path == "/console/api/apps" {
-			// Return the app list with changed names
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": [
-					{
-						"id": "app-id-1",
-						"name": "Changed App Name",
-						"updated_at": 1672531200
-					},
-					{
-						"id": "app-id-2",
-						"name": "変更された日本語アプリ",
-						"updated_at": 1672617600
-					}
-				]
-			}`))
-			return
-		}
+			// The local file has the remote content and name
+			renamedPath := filepath.Join(dslDir, "Test_App.yaml")
+			if err := os.WriteFile(renamedPath, []byte("name: Test App\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("Failed to write DSL file: %v", err)
+			}
+			if err := os.Remove(dslPath); err != nil {
+				t.Fatalf("Failed to remove DSL file: %v", err)
+			}
+			appMap := fmt.Sprintf(`{"apps": [{"filename": "Test_App.yaml", "app_id": "test-app-id", "read_only": %v}]}`, tt.readOnly)
+			if err := os.WriteFile(appMapPath, []byte(appMap), 0644); err != nil {
+				t.Fatalf("Failed to write app map: %v", err)
+			}
+			if err := os.Chtimes(renamedPath, tt.localTime, tt.localTime); err != nil {
+				t.Fatalf("Failed to set file time: %v", err)
+			}
 
-		if r.URL.Path == "/console/api/apps/app-id-1" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": {
-					"id": "app-id-1",
-					"name": "Changed App Name",
-					"updated_at": 1672531200
-				}
-			}`))
-			return
-		}
+			defaultSyncer := syncer.(*DefaultSyncer)
+			defaultSyncer.config.TouchOnSync = tt.touchOnSync
+			defaultSyncer.config.SkipUnchanged = tt.skipUnchanged
 
-		if r.URL.Path == "/console/api/apps/app-id-2" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": {
-					"id": "app-id-2",
-					"name": "変更された日本語アプリ",
-					"updated_at": 1672617600
-				}
-			}`))
-			return
-		}
+			stats, err := syncer.SyncAll()
+			if err != nil {
+				t.Fatalf("Failed to sync all: %v", err)
+			}
+			if stats.NoAction != 1 {
+				t.Errorf("Expected the app to be in sync, got %+v", stats.Results)
+			}
 
-		if r.URL.Path == "/console/api/apps/app-id-1/export" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": "name: Changed App Name\nversion: 1.0.0"
-			}`))
-			return
-		}
+			info, err := os.Stat(renamedPath)
+			if err != nil {
+				t.Fatalf("Failed to stat DSL file: %v", err)
+			}
+			if !info.ModTime().Equal(tt.expectedTime) {
+				t.Errorf("Expected mtime %v, got %v", tt.expectedTime, info.ModTime())
+			}
+		})
+	}
+}
 
-		if r.URL.Path == "/console/api/apps/app-id-2/export" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{
-				"data": "name: 変更された日本語アプリ\nversion: 1.0.0"
-			}`))
-			return
-		}
+func TestSyncAllKeepsAppMapOrder(t *testing.T) {
+	syncer, _, dslDir, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
 
-		// Handle checks for app existence
-		if r.URL.Path == "/console/api/apps/app-id-1/check" || r.URL.Path == "/console/api/apps/app-id-2/check" {
-			// All apps exist in this test
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"exists": true}`))
-			return
+	// Entries out of filename order, with the renamed app in the middle
+	for _, name := range []string{"zeta.yaml", "alpha.yaml"} {
+		if err := os.WriteFile(filepath.Join(dslDir, name), []byte("name: other"), 0644); err != nil {
+			t.Fatalf("Failed to write DSL file: %v", err)
 		}
-
-		w.WriteHeader(http.StatusNotFound)
-	}))
-	defer server.Close()
-
-	// Create syncer with test configuration
-	config := Config{
-		DifyBaseURL:  server.URL,
-		DifyEmail:    "test@example.com",
-		DifyPassword: "testpassword",
-		DSLDirectory: dslDir,
-		AppMapFile:   appMapPath,
-		Verbose:      true, // For better debugging in tests
 	}
-	syncer := NewSyncer(config)
+	appMap := `{"apps": [
+		{"filename": "zeta.yaml", "app_id": "zeta-app-id"},
+		{"filename": "test.yaml", "app_id": "test-app-id"},
+		{"filename": "alpha.yaml", "app_id": "alpha-app-id"}
+	]}`
+	if err := os.WriteFile(appMapPath, []byte(appMap), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
+	}
 
-	// Run SyncAll
 	stats, err := syncer.SyncAll()
 	if err != nil {
 		t.Fatalf("Failed to sync all: %v", err)
 	}
+	if stats.Renamed != 1 {
+		t.Fatalf("Expected 1 rename, got %d", stats.Renamed)
+	}
 
-	// Check that files were renamed
-	expectedNewFilename := "Changed_App_Name.yaml"
-	expectedJpNewFilename := "変更された日本語アプリ.yaml"
-
-	// Check that the old files no longer exist
-	if _, err := os.Stat(oldFilePath); !os.IsNotExist(err) {
-		t.Errorf("Expected old file %s to be renamed/removed", oldFilename)
+	rewritten, err := syncer.LoadAppMap()
+	if err != nil {
+		t.Fatalf("Failed to load app map: %v", err)
 	}
-	if _, err := os.Stat(jpOldFilePath); !os.IsNotExist(err) {
-		t.Errorf("Expected old file %s to be renamed/removed", jpOldFilename)
+	var filenames []string
+	for _, app := range rewritten.Apps {
+		filenames = append(filenames, app.Filename)
 	}
+	expected := []string{"zeta.yaml", "Test_App.yaml", "alpha.yaml"}
+	if !reflect.DeepEqual(filenames, expected) {
+		t.Errorf("Expected app map order %v, got %v", expected, filenames)
+	}
+}
 
-	// Check that the new files exist
-	newFilePath := filepath.Join(dslDir, expectedNewFilename)
-	if _, err := os.Stat(newFilePath); os.IsNotExist(err) {
-		t.Errorf("Expected new file %s to exist", expectedNewFilename)
+func TestSyncAllNestedFilenames(t *testing.T) {
+	syncer, _, dslDir, dslPath, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	// Move the DSL file into a subdirectory under a stale name
+	nestedPath := filepath.Join(dslDir, "team-a", "test.yaml")
+	if err := os.MkdirAll(filepath.Dir(nestedPath), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
 	}
-	jpNewFilePath := filepath.Join(dslDir, expectedJpNewFilename)
-	if _, err := os.Stat(jpNewFilePath); os.IsNotExist(err) {
-		t.Errorf("Expected new file %s to exist", expectedJpNewFilename)
+	if err := os.Rename(dslPath, nestedPath); err != nil {
+		t.Fatalf("Failed to move DSL file: %v", err)
+	}
+	if err := os.WriteFile(appMapPath, []byte(`{"apps": [{"filename": "team-a/test.yaml", "app_id": "test-app-id"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write app map: %v", err)
 	}
 
-	// Check the app map has been updated
-	updatedAppMap, err := syncer.LoadAppMap()
+	stats, err := syncer.SyncAll()
 	if err != nil {
-		t.Fatalf("Failed to load updated app map: %v", err)
+		t.Fatalf("Failed to sync all: %v", err)
+	}
+	if stats.Renamed != 1 {
+		t.Fatalf("Expected 1 rename, got %d", stats.Renamed)
 	}
 
-	// Check app map contains the new filenames
-	foundUpdatedEn := false
-	foundUpdatedJp := false
-	for _, app := range updatedAppMap.Apps {
-		if app.AppID == "app-id-1" {
-			if app.Filename != expectedNewFilename {
-				t.Errorf("Expected app id app-id-1 to have filename %s, got %s",
-					expectedNewFilename, app.Filename)
-			}
-			foundUpdatedEn = true
-		}
-		if app.AppID == "app-id-2" {
-			if app.Filename != expectedJpNewFilename {
-				t.Errorf("Expected app id app-id-2 to have filename %s, got %s",
-					expectedJpNewFilename, app.Filename)
-			}
-			foundUpdatedJp = true
-		}
+	// The renamed file stays in its subdirectory
+	if _, err := os.Stat(filepath.Join(dslDir, "team-a", "Test_App.yaml")); err != nil {
+		t.Errorf("Expected renamed file in subdirectory: %v", err)
+	}
+	appMap, err := syncer.LoadAppMap()
+	if err != nil {
+		t.Fatalf("Failed to load app map: %v", err)
+	}
+	if len(appMap.Apps) != 1 || appMap.Apps[0].Filename != "team-a/Test_App.yaml" {
+		t.Errorf("Expected app map filename team-a/Test_App.yaml, got %+v", appMap.Apps)
 	}
 
-	if !foundUpdatedEn {
-		t.Errorf("English app mapping not found in updated app map")
+	// Downloading into a subdirectory that does not exist yet creates it
+	localPath := filepath.Join(dslDir, "team-b", "bot.yaml")
+	result := syncer.(*DefaultSyncer).downloadFromRemote(AppMapping{Filename: "team-b/bot.yaml", AppID: "test-app-id"}, localPath)
+	if !result.Success {
+		t.Fatalf("Expected download to succeed, got %v", result.Error)
 	}
-	if !foundUpdatedJp {
-		t.Errorf("Japanese app mapping not found in updated app map")
+	if data, err := os.ReadFile(localPath); err != nil || string(data) != "name: Test App\nversion: 1.0.0" {
+		t.Errorf("Unexpected nested file content %q: %v", data, err)
 	}
+}
 
-	// Check statistics
-	if stats.Total != 2 {
-		t.Errorf("Expected Total to be 2, got %d", stats.Total)
+func TestLoadAppMapRejectsEscapingFilenames(t *testing.T) {
+	syncer, _, _, _, appMapPath, cleanup := setupTestSyncerAndServer(t)
+	defer cleanup()
+
+	for _, filename := range []string{"../outside.yaml", "team-a/../../outside.yaml", "/etc/outside.yaml"} {
+		appMap := `{"apps": [{"filename": "` + filename + `", "app_id": "test-app-id"}]}`
+		if err := os.WriteFile(appMapPath, []byte(appMap), 0644); err != nil {
+			t.Fatalf("Failed to write app map: %v", err)
+		}
+		if _, err := syncer.LoadAppMap(); err == nil {
+			t.Errorf("Expected filename %q to be rejected", filename)
+		}
 	}
 }
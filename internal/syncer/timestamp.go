@@ -0,0 +1,45 @@
+package syncer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// timestampLayouts are the string formats accepted for remote timestamps, in order of preference
+var timestampLayouts = []string{
+	time.RFC3339Nano, // also accepts RFC3339 without fractional seconds
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// parseUpdatedAt converts an updated_at value from the Dify API into a time.
+// Strings are parsed with timestampLayouts and numbers are treated as UNIX seconds.
+// It returns false for nil, empty or unrecognized values.
+func parseUpdatedAt(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return time.Unix(i, 0), true
+		}
+		return time.Time{}, false
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,117 @@
+package syncer
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseUpdatedAtStringFormats(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+	}{
+		{
+			name:     "RFC3339",
+			value:    "2023-01-01T12:00:00Z",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "fractional seconds",
+			value:    "2023-01-01T12:00:00.123456Z",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 123456000, time.UTC),
+		},
+		{
+			name:     "fractional seconds with offset",
+			value:    "2023-01-01T12:00:00.123456+09:00",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 123456000, jst),
+		},
+		{
+			name:     "offset without fractional seconds",
+			value:    "2023-01-01T12:00:00+09:00",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, jst),
+		},
+		{
+			name:     "space separated with offset",
+			value:    "2023-01-01 12:00:00.5+09:00",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 500000000, jst),
+		},
+		{
+			name:     "space separated",
+			value:    "2023-01-01 12:00:00",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "without zone",
+			value:    "2023-01-01T12:00:00.123",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 123000000, time.UTC),
+		},
+		{
+			name:     "slash separated",
+			value:    "2023/01/01 12:00:00",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC1123",
+			value:    "Sun, 01 Jan 2023 12:00:00 UTC",
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseUpdatedAt(tt.value)
+			if !ok {
+				t.Fatalf("Expected %q to be parsed", tt.value)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseUpdatedAt(t *testing.T) {
+	expected := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	unix := expected.Unix()
+
+	tests := []struct {
+		name       string
+		value      interface{}
+		expectedOK bool
+	}{
+		{name: "nil", value: nil, expectedOK: false},
+		{name: "empty string", value: "", expectedOK: false},
+		{name: "unparseable string", value: "yesterday", expectedOK: false},
+		{name: "RFC3339 string", value: "2023-01-01T00:00:00Z", expectedOK: true},
+		{name: "unix int", value: int(unix), expectedOK: true},
+		{name: "unix int64", value: unix, expectedOK: true},
+		{name: "unix float", value: float64(unix), expectedOK: true},
+		{name: "json.Number", value: json.Number(strconv.FormatInt(unix, 10)), expectedOK: true},
+		{name: "fractional json.Number", value: json.Number("1672531200.5"), expectedOK: false},
+		{name: "garbage object", value: map[string]interface{}{"seconds": unix}, expectedOK: false},
+		{name: "bool", value: true, expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseUpdatedAt(tt.value)
+			if ok != tt.expectedOK {
+				t.Fatalf("Expected ok to be %v, got %v", tt.expectedOK, ok)
+			}
+			if !ok {
+				if !got.IsZero() {
+					t.Errorf("Expected zero time when not ok, got %v", got)
+				}
+				return
+			}
+			if !got.Equal(expected) {
+				t.Errorf("Expected %v, got %v", expected, got)
+			}
+		})
+	}
+}
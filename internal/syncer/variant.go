@@ -0,0 +1,68 @@
+package syncer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pepabo/difync/internal/api"
+)
+
+// DSL variants downloaded by the syncer
+const (
+	// VariantDraft downloads the draft workflow as edited in the Dify console
+	VariantDraft = "draft"
+	// VariantPublished downloads the last published version of the workflow
+	VariantPublished = "published"
+)
+
+// remoteUpdatedAt returns when the configured variant of an app last changed in Dify,
+// given the app's info. It returns false if Dify reports no valid timestamp.
+func (s *DefaultSyncer) remoteUpdatedAt(app AppMapping, appInfo *api.AppInfo) (time.Time, bool, error) {
+	if s.config.Variant == VariantPublished {
+		appPublish, err := s.client.GetAppPublish(app.AppID)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to get publish info: %w", err)
+		}
+
+		publishTime, ok := parseUpdatedAt(appPublish.UpdatedAt)
+		if !ok && s.config.Verbose {
+			fmt.Printf("No valid publish timestamp found for %s (%v), skipping sync\n", app.Filename, appPublish.UpdatedAt)
+		}
+		return publishTime, ok, nil
+	}
+
+	// Convert interface{} updated_at to time.Time
+	remoteModTime, ok := parseUpdatedAt(appInfo.UpdatedAt)
+	if !ok {
+		if s.config.Verbose {
+			fmt.Printf("No valid remote timestamp found for %s (%v), skipping sync\n", app.Filename, appInfo.UpdatedAt)
+		}
+		return time.Time{}, false, nil
+	}
+
+	// Publishing counts as a change of the app as well. Without a valid publish
+	// timestamp, only UpdatedAt counts.
+	appPublish, err := s.client.GetAppPublish(app.AppID)
+	if err == nil {
+		if publishTime, ok := parseUpdatedAt(appPublish.UpdatedAt); ok && publishTime.After(remoteModTime) {
+			return publishTime, true, nil
+		}
+	}
+	return remoteModTime, true, nil
+}
+
+// exportDSL downloads the configured variant of an app's DSL
+func (s *DefaultSyncer) exportDSL(appID string) ([]byte, error) {
+	if s.config.Variant != VariantPublished {
+		return s.client.GetDSL(appID)
+	}
+
+	appPublish, err := s.client.GetAppPublish(appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get publish info: %w", err)
+	}
+	if appPublish.ID == "" {
+		return nil, fmt.Errorf("app %s has no published version", appID)
+	}
+	return s.client.GetPublishedDSL(appID, appPublish.ID)
+}
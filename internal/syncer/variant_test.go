@@ -0,0 +1,79 @@
+package syncer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncAppPublishedVariant(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// The draft was edited after the last publish
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/console/api/login":
+			w.Write([]byte(`{"status": "success", "data": {"access_token": "test-token"}}`))
+		case "/console/api/apps/test-app-id":
+			w.Write([]byte(`{"id": "test-app-id", "name": "Test App", "updated_at": "2023-03-01T12:00:00Z"}`))
+		case "/console/api/apps/test-app-id/workflows/publish":
+			w.Write([]byte(`{"id": "workflow-id-1", "version": "v1", "updated_at": "2023-01-01T12:00:00Z"}`))
+		case "/console/api/apps/test-app-id/export":
+			if r.URL.Query().Get("workflow_id") == "workflow-id-1" {
+				w.Write([]byte(`{"data": "name: Published"}`))
+			} else {
+				w.Write([]byte(`{"data": "name: Draft"}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	syncer := NewSyncer(Config{
+		DifyBaseURL:  server.URL,
+		DifyEmail:    "test@example.com",
+		DifyPassword: "testpassword",
+		DSLDirectory: tmpDir,
+		Variant:      VariantPublished,
+	})
+
+	// A file older than the publish is replaced by the published DSL
+	localPath := filepath.Join(tmpDir, "test.yaml")
+	if err := os.WriteFile(localPath, []byte("name: Old"), 0644); err != nil {
+		t.Fatalf("Failed to write DSL file: %v", err)
+	}
+	published := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(localPath, published.Add(-time.Hour), published.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+
+	app := AppMapping{Filename: "test.yaml", AppID: "test-app-id"}
+	result := syncer.SyncApp(app)
+	if result.Action != ActionDownload || !result.Success {
+		t.Fatalf("Expected a successful download, got %s (%v)", result.Action, result.Error)
+	}
+	if !result.RemoteUpdatedAt.Equal(published) {
+		t.Errorf("Expected the publish time %v, got %v", published, result.RemoteUpdatedAt)
+	}
+
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatalf("Failed to read DSL file: %v", err)
+	}
+	if string(content) != "name: Published" {
+		t.Errorf("Expected the published DSL, got %q", content)
+	}
+
+	// A file newer than the publish is in sync, even though the draft changed later
+	if err := os.Chtimes(localPath, published.Add(time.Hour), published.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+	if result := syncer.SyncApp(app); result.Action != ActionNone || !result.Success {
+		t.Errorf("Expected no action, got %s (%v)", result.Action, result.Error)
+	}
+}
@@ -0,0 +1,40 @@
+package syncer
+
+import "sync"
+
+// defaultConcurrency is used when Config.Concurrency is not set
+const defaultConcurrency = 1
+
+// concurrency returns how many apps may be downloaded at once
+func (s *DefaultSyncer) concurrency() int {
+	if s.config.Concurrency > 0 {
+		return s.config.Concurrency
+	}
+	return defaultConcurrency
+}
+
+// forEachConcurrently calls fn for every index below n, running at most limit calls at once.
+// It returns when all calls have finished.
+func forEachConcurrently(n, limit int, fn func(i int)) {
+	if limit <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}